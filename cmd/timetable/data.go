@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sergrom/timetable/internal/config"
+	"github.com/sergrom/timetable/internal/repository"
+)
+
+const dataUsage = `Использование: timetable data <команда> [флаги]
+
+Команды:
+  validate  проверить все таблицы и связи между ними
+  export    выгрузить таблицы в csv
+  import    загрузить таблицу из csv
+  migrate   перенести таблицы из xlsx в базу или обратно
+
+Таблицы: stadiums, divisions, coaches, teams, wishes, games, referees, season
+Хранилище по умолчанию - server.storage из настроек. Базу, открытую сервером, команды не откроют:
+сначала остановите сервер.`
+
+// runData работа с каталогом данных без веб-сервера
+func runData(cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		fmt.Println(dataUsage)
+		return errors.New("не указана команда")
+	}
+
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet("data "+cmd, flag.ContinueOnError)
+	dir := fs.String("dir", cfg.Server.DataDir, "каталог данных")
+	kind := fs.String("storage", cfg.Server.Storage, "хранилище: xlsx или db")
+
+	switch cmd {
+	case "validate":
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		return dataValidate(*kind, *dir)
+
+	case "export":
+		table := fs.String("table", "", "таблица, пусто - все таблицы")
+		out := fs.String("out", "", "csv-файл для одной таблицы или каталог для всех")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *out == "" {
+			return errors.New("нужно указать --out")
+		}
+		return dataExport(*kind, *dir, *table, *out)
+
+	case "import":
+		table := fs.String("table", "", "таблица")
+		in := fs.String("in", "", "csv-файл")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *table == "" || *in == "" {
+			return errors.New("нужно указать --table и --in")
+		}
+		return dataImport(*kind, *dir, *table, *in)
+
+	case "migrate":
+		to := fs.String("to", repository.StorageDB, "куда перенести: db или xlsx")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		return dataMigrate(*dir, *to)
+	}
+
+	fmt.Println(dataUsage)
+	return fmt.Errorf("неизвестная команда %s", cmd)
+}
+
+func dataValidate(kind, dir string) error {
+	st, err := repository.OpenStorage(kind, dir)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	issues := repository.Validate(st)
+	for _, i := range issues {
+		fmt.Println(i)
+	}
+	if len(issues) > 0 {
+		return fmt.Errorf("найдено ошибок: %d", len(issues))
+	}
+	fmt.Println("ошибок не найдено")
+	return nil
+}
+
+func dataExport(kind, dir, table, out string) error {
+	st, err := repository.OpenStorage(kind, dir)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	if table != "" {
+		t, err := repository.GetTable(table)
+		if err != nil {
+			return err
+		}
+		return exportTable(st, t, out)
+	}
+
+	if err := os.MkdirAll(out, 0o755); err != nil {
+		return err
+	}
+	for _, t := range repository.Tables {
+		err := exportTable(st, t, filepath.Join(out, t.Name+".csv"))
+		if errors.Is(err, os.ErrNotExist) && !t.Required {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func exportTable(st repository.Storage, t repository.Table, path string) error {
+	rows, err := st.ReadTable(t)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(rows); err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d строк -> %s\n", t.Name, len(rows), path)
+	return f.Close()
+}
+
+func dataImport(kind, dir, table, in string) error {
+	t, err := repository.GetTable(table)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(in)
+	if err != nil {
+		return err
+	}
+	// Excel сохраняет csv в UTF-8 с BOM
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	rows, err := r.ReadAll()
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		for j := range rows[i] {
+			rows[i][j] = strings.TrimSpace(rows[i][j])
+		}
+	}
+
+	// таблицу с ошибками в строках не загружаем, чтобы сервер не потерял эти строки молча
+	if issues := repository.ValidateTable(t, rows); len(issues) > 0 {
+		for _, i := range issues {
+			fmt.Println(i)
+		}
+		return fmt.Errorf("таблица не загружена, ошибок: %d", len(issues))
+	}
+
+	st, err := repository.OpenStorage(kind, dir)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	if err := st.WriteTable(t, rows); err != nil {
+		return err
+	}
+	fmt.Printf("%s: загружено %d строк\n", t.Name, len(rows))
+
+	// ссылки на другие таблицы не мешают загрузке, но о них стоит знать
+	for _, i := range repository.Validate(st) {
+		fmt.Println("предупреждение:", i)
+	}
+	return nil
+}
+
+func dataMigrate(dir, to string) error {
+	from := repository.StorageXlsx
+	switch to {
+	case repository.StorageDB:
+	case repository.StorageXlsx:
+		from = repository.StorageDB
+	default:
+		return fmt.Errorf("неизвестное хранилище %s", to)
+	}
+
+	src, err := repository.OpenStorage(from, dir)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := repository.OpenStorage(to, dir)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	for _, t := range repository.Tables {
+		rows, err := src.ReadTable(t)
+		if errors.Is(err, os.ErrNotExist) && !t.Required {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+		if err := dst.WriteTable(t, rows); err != nil {
+			return fmt.Errorf("%s: %w", t.Name, err)
+		}
+		fmt.Printf("%s: %d строк, %s -> %s\n", t.Name, len(rows), from, to)
+	}
+	fmt.Printf("чтобы сервер работал с перенесенными таблицами, укажите server.storage: %s (или TIMETABLE_STORAGE=%s)\n", to, to)
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sergrom/timetable/internal/config"
+	"github.com/sergrom/timetable/internal/repository"
+)
+
+func TestDataMigrateRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Default()
+	cfg.Server.DataDir = dir // каталог данных из настроек, без --dir
+	tables := map[string][][]string{
+		"stadiums":  {{"ID", "Название"}, {"1", "Лужники"}, {"2", "Стадион №2"}},
+		"divisions": {{"ID", "Название", "Формат"}, {"1", "2012", "7"}},
+		"coaches":   {{"ID", "Имя"}, {"1", "Иванов"}},
+		"teams":     {{"ID", "Название", "Дивизион"}, {"1", "Спартак 007", "1"}, {"2", "007", "1"}},
+		"wishes":    {{"ID", "Команда"}},
+		"games":     {{"ID", "Тур"}, {"1", "Тур 1"}},
+		"referees":  {{"ID", "Имя"}, {"1", "Петров"}},
+		// таблицы season нет, она необязательная
+	}
+
+	xlsx := repository.NewXlsxStorage(dir)
+	for name, rows := range tables {
+		tbl, err := repository.GetTable(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := xlsx.WriteTable(tbl, rows); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := runData(cfg, []string{"migrate", "--to", "db"}); err != nil {
+		t.Fatal(err)
+	}
+	// xlsx-файлы убираем, чтобы обратный перенос брал строки только из базы
+	for _, tbl := range repository.Tables {
+		if err := os.Remove(filepath.Join(dir, tbl.File)); err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+	}
+	if err := runData(cfg, []string{"migrate", "--to", "xlsx"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tbl := range repository.Tables {
+		rows, err := xlsx.ReadTable(tbl)
+		want, ok := tables[tbl.Name]
+		if !ok {
+			if !os.IsNotExist(err) {
+				t.Errorf("%s: table must stay missing, got %v, %v", tbl.Name, rows, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tbl.Name, err)
+		}
+		if !reflect.DeepEqual(rows, want) {
+			t.Errorf("%s: rows %q, want %q", tbl.Name, rows, want)
+		}
+	}
+}
+
+func TestDataMigrateErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := runData(config.Default(), []string{"migrate", "--dir", dir, "--to", "csv"}); err == nil {
+		t.Error("unknown storage: expected error")
+	}
+	// в пустом каталоге нет обязательных таблиц
+	if err := runData(config.Default(), []string{"migrate", "--dir", dir, "--to", "db"}); err == nil {
+		t.Error("missing required table: expected error")
+	}
+}
@@ -1,20 +1,70 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
 	"time"
 
 	ttAPI "github.com/sergrom/timetable/internal/api"
+	"github.com/sergrom/timetable/internal/config"
 
 	"github.com/gin-gonic/gin"
 )
 
-const (
-	APP_PORT = ":8899"
-)
+// ShutdownTimeout сколько ждать завершения запросов при выключении сервера
+const ShutdownTimeout = 10 * time.Second
 
 func main() {
+	configPath := flag.String("config", config.DefaultFile, "файл настроек (yaml)")
+	addr := flag.String("addr", "", "адрес сервера, например :8899")
+	dataDir := flag.String("data-dir", "", "каталог данных")
+	webDir := flag.String("web-dir", "", "каталог шаблонов и статики")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// флаги важнее файла и переменных окружения
+	if *addr != "" {
+		cfg.Server.Addr = *addr
+	}
+	if *dataDir != "" {
+		cfg.Server.DataDir = *dataDir
+	}
+	if *webDir != "" {
+		cfg.Server.WebDir = *webDir
+	}
+
+	if flag.NArg() > 0 {
+		switch flag.Arg(0) {
+		case "solve":
+			err = runSolve(cfg, flag.Args()[1:])
+		case "data":
+			err = runData(cfg, flag.Args()[1:])
+		case "user":
+			err = runUser(cfg, flag.Args()[1:])
+		default:
+			log.Fatalf("unknown command %s, expected solve, data or user\n", flag.Arg(0))
+		}
+		if err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+
+	if cfg.Auth.Enabled {
+		if err := ensureAdmin(cfg); err != nil {
+			log.Fatalln(err)
+		}
+	}
+
 	// router := gin.Default()
 	router := gin.New()
 	router.Use(
@@ -22,26 +72,48 @@ func main() {
 		gin.Recovery(),
 	)
   router.SetTrustedProxies(nil)
-	router.Static("/css", "web/css")
-	router.Static("/js", "web/js")
-	router.Static("/img", "web/img")
-	router.Static("/fonts", "web/fonts")
-	router.StaticFile("/favicon.ico", "web/favicon.ico")
-	router.LoadHTMLGlob("web/*.html")
+	web := cfg.Server.WebDir
+	router.Static("/css", filepath.Join(web, "css"))
+	router.Static("/js", filepath.Join(web, "js"))
+	router.Static("/img", filepath.Join(web, "img"))
+	router.Static("/fonts", filepath.Join(web, "fonts"))
+	router.StaticFile("/favicon.ico", filepath.Join(web, "favicon.ico"))
+	router.LoadHTMLGlob(filepath.Join(web, "*.html"))
 
-	for route, handler := range ttAPI.NewTimetableAPI().GetHandlers() {
-		router.Handle(handler.Method, route, handler.Fn)
+	api, err := ttAPI.NewTimetableAPI(cfg)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for route, handler := range api.GetHandlers() {
+		router.Handle(handler.Method, route, api.Authorize(handler), handler.Fn)
 	}
 
 	serv := &http.Server{
-		Addr:        APP_PORT,
+		Addr:        cfg.Server.Addr,
 		Handler:     router,
 		ReadTimeout: 3 * time.Second,
 	}
 
-	if err := serv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("listen: %s\n", err)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		if err := serv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("listen: %s\n", err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Println("Shutting down...")
+
+	// сначала перестаем принимать запросы, потом останавливаем поиск и сохраняем его точку продолжения
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), ShutdownTimeout)
+	defer cancel()
+	if err := serv.Shutdown(shutdownCtx); err != nil {
+		log.Println("shutdown:", err)
 	}
+	api.Shutdown()
 
 	log.Println("Program exited. Bye.")
 }
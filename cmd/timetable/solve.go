@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sergrom/timetable/internal/config"
+	"github.com/sergrom/timetable/internal/services/export"
+	"github.com/sergrom/timetable/internal/services/problem"
+	"github.com/sergrom/timetable/internal/services/runs"
+	"github.com/sergrom/timetable/internal/services/searcher"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	DefaultSolveTime = 60 * time.Second
+	ProgressInterval = 5 * time.Second
+)
+
+// runSolve поиск по файлу задачи без веб-сервера. Если в файле есть точка продолжения, поиск продолжается с нее.
+// Пример: timetable solve --problem tour.json --time 60s --out schedule.xlsx --checkpoint tour-next.json
+func runSolve(cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("solve", flag.ContinueOnError)
+	problemPath := fs.String("problem", "", "файл задачи (json), выгруженный на главной странице")
+	dur := fs.Duration("time", DefaultSolveTime, "сколько искать")
+	out := fs.String("out", "", "куда записать решения: .xlsx, .json или .csv")
+	top := fs.Int("top", 1, "сколько лучших решений записать")
+	cpPath := fs.String("checkpoint", "", "куда записать задачу с точкой продолжения, чтобы продолжить поиск позже")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *problemPath == "" || *out == "" {
+		fs.Usage()
+		return errors.New("нужно указать --problem и --out")
+	}
+	if *top < 1 {
+		return errors.New("--top должен быть больше 0")
+	}
+
+	ext := strings.ToLower(filepath.Ext(*out))
+	if ext != ".xlsx" && ext != ".json" && ext != ".csv" {
+		return fmt.Errorf("неизвестный формат %s, нужен .xlsx, .json или .csv", ext)
+	}
+
+	pf, err := os.Open(*problemPath)
+	if err != nil {
+		return err
+	}
+	p, err := problem.Read(pf)
+	pf.Close()
+	if err != nil {
+		return err
+	}
+	cond, err := p.Condition()
+	if err != nil {
+		return err
+	}
+
+	s := searcher.NewSearcher(cfg.Search)
+	if _, _, err := s.Resume(cond, p.Checkpoint, p.Solutions); err != nil {
+		return err
+	}
+	if p.Checkpoint != nil {
+		fmt.Printf("поиск продолжен, попыток до остановки: %d, решений: %d\n", p.Checkpoint.Attempts, len(p.Solutions))
+	}
+
+	// по Ctrl+C поиск останавливается, найденные решения записываются
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	started := time.Now()
+	deadline := time.NewTimer(*dur)
+	defer deadline.Stop()
+	ticker := time.NewTicker(ProgressInterval)
+	defer ticker.Stop()
+
+loop:
+	for {
+		select {
+		case <-s.Done():
+			break loop
+		case <-deadline.C:
+			break loop
+		case <-interrupt:
+			break loop
+		case <-ticker.C:
+			printProgress(s, started)
+		}
+	}
+	s.Stop()
+	printProgress(s, started)
+
+	sols, att := s.GetSolutions()
+	if *cpPath != "" {
+		if err := writeCheckpoint(*cpPath, p, s.Checkpoint(), sols); err != nil {
+			return err
+		}
+	}
+	if len(sols) == 0 {
+		return fmt.Errorf("решений не найдено за %d попыток", att)
+	}
+	if len(sols) > *top {
+		sols = sols[:*top]
+	}
+
+	if err := writeSolutions(*out, ext, cond, sols, att); err != nil {
+		return err
+	}
+	fmt.Printf("записано решений: %d в %s\n", len(sols), *out)
+	return nil
+}
+
+// writeCheckpoint записать задачу с точкой продолжения и лучшими решениями
+func writeCheckpoint(path string, p problem.Problem, cp *searcher.Checkpoint, sols []searcher.Solution) error {
+	if cp == nil {
+		fmt.Println("перебор завершен, продолжать нечего")
+		return nil
+	}
+	if len(sols) > runs.TopSolutions {
+		sols = sols[:runs.TopSolutions]
+	}
+	p.Checkpoint, p.Solutions = cp, sols
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := problem.Write(f, p); err != nil {
+		return err
+	}
+	fmt.Printf("точка продолжения записана в %s\n", path)
+	return f.Close()
+}
+
+func printProgress(s *searcher.Searcher, started time.Time) {
+	sols, att := s.GetSolutions()
+	best := "-"
+	if len(sols) > 0 {
+		best = fmt.Sprint(sols[0].Sum)
+	}
+	fmt.Printf("%s попыток: %d, решений: %d, лучший штраф: %s\n",
+		time.Since(started).Round(time.Second), att, len(sols), best)
+}
+
+func writeSolutions(path, ext string, cond *searcher.Condition, sols []searcher.Solution, att int) error {
+	names := export.NamesFromCondition(cond)
+
+	switch ext {
+	case ".json":
+		data, err := json.MarshalIndent(map[string]interface{}{
+			"tour_name": cond.TourName,
+			"attempts":  att,
+			"teams":     cond.TeamsPrettyMap,
+			"referees":  cond.RefereesPrettyMap,
+			"solutions": sols,
+		}, "", "  ")
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0o644)
+
+	case ".csv":
+		// решения идут подряд, каждое со своей строкой заголовка
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		for i := range sols {
+			if err := export.CSV(f, &sols[i], names); err != nil {
+				return err
+			}
+		}
+		return f.Close()
+	}
+
+	// xlsx: каждое решение на своем листе
+	f := excelize.NewFile()
+	defer f.Close()
+	for i := range sols {
+		sheet := fmt.Sprintf("Решение %d", i+1)
+		if _, err := f.NewSheet(sheet); err != nil {
+			return err
+		}
+		if err := export.Xlsx(f, sheet, &sols[i], names); err != nil {
+			return err
+		}
+	}
+	if err := f.DeleteSheet("Sheet1"); err != nil {
+		return err
+	}
+	f.SetActiveSheet(0)
+	return f.SaveAs(path)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sergrom/timetable/internal/config"
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/services/problem"
+	"github.com/sergrom/timetable/internal/services/searcher"
+)
+
+func writeProblem(t *testing.T, path string, p problem.Problem) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := problem.Write(f, p); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readProblem(t *testing.T, path string) problem.Problem {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	p, err := problem.Read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestSolveResumesFromCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	p := problem.Problem{
+		Version:   problem.Version,
+		TourName:  "Тур 1",
+		Divisions: []ds.Division{{ID: 1, Name: "2012", Format: 6}},
+		Coaches:   []ds.Coach{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}, {ID: 6}},
+		Teams: []ds.Team{
+			{ID: 1, Name: "Альфа", DivisionID: 1, CoachID: 1},
+			{ID: 2, Name: "Бета", DivisionID: 1, CoachID: 2},
+			{ID: 3, Name: "Гамма", DivisionID: 1, CoachID: 3},
+			{ID: 4, Name: "Дельта", DivisionID: 1, CoachID: 4},
+			{ID: 5, Name: "Эпсилон", DivisionID: 1, CoachID: 5},
+			{ID: 6, Name: "Дзета", DivisionID: 1, CoachID: 6},
+		},
+		Params: searcher.DefaultParams(),
+	}
+	// поиск не успевает закончиться за время запуска, и остается точка продолжения
+	for id := 1; id <= 3; id++ {
+		f, err := ds.NewField(id, 6, time.Hour, 0, "09:00", "12:00", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Fields = append(p.Fields, f)
+	}
+	writeProblem(t, filepath.Join(dir, "tour.json"), p)
+
+	cfg := config.Default()
+	cfg.Search.RotateInterval = time.Hour
+	solve := func(in, next string) problem.Problem {
+		t.Helper()
+		args := []string{
+			"--problem", filepath.Join(dir, in), "--time", "100ms",
+			"--out", filepath.Join(dir, "out.json"), "--checkpoint", filepath.Join(dir, next),
+		}
+		if err := runSolve(cfg, args); err != nil {
+			t.Fatal(err)
+		}
+		return readProblem(t, filepath.Join(dir, next))
+	}
+
+	first := solve("tour.json", "next1.json")
+	if first.Checkpoint == nil || first.Checkpoint.Attempts == 0 || len(first.Solutions) == 0 {
+		t.Fatalf("first run: checkpoint %+v, solutions %d", first.Checkpoint, len(first.Solutions))
+	}
+
+	// второй запуск по файлу с точкой продолжения идет дальше, а не начинает заново
+	second := solve("next1.json", "next2.json")
+	if second.Checkpoint == nil || second.Checkpoint.Attempts <= first.Checkpoint.Attempts {
+		t.Fatalf("second run attempts %+v, first run %d", second.Checkpoint, first.Checkpoint.Attempts)
+	}
+	if len(second.Solutions) < len(first.Solutions) {
+		t.Errorf("second run has %d solutions, first had %d", len(second.Solutions), len(first.Solutions))
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/sergrom/timetable/internal/config"
+	"github.com/sergrom/timetable/internal/services/auth"
+)
+
+const userUsage = `Использование: timetable user <команда> [флаги]
+
+Команды:
+  list    пользователи и их роли
+  add     добавить пользователя или поменять ему роль и пароль
+  passwd  сменить пароль
+  del     удалить пользователя
+
+Роли: viewer - смотрит расписания, planner - запускает поиск и публикует, admin - правит справочники.
+Пароль, не заданный флагом --password, читается из стандартного ввода.`
+
+// runUser пользователи сервера
+func runUser(cfg config.Config, args []string) error {
+	if len(args) == 0 {
+		fmt.Println(userUsage)
+		return errors.New("не указана команда")
+	}
+
+	cmd, args := args[0], args[1:]
+	fs := flag.NewFlagSet("user "+cmd, flag.ContinueOnError)
+	name := fs.String("name", "", "имя пользователя")
+	store := auth.NewStore(cfg.UsersPath())
+
+	switch cmd {
+	case "list":
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		users, err := store.Users()
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			fmt.Printf("%s\t%s\n", u.Name, u.Role)
+		}
+		return nil
+
+	case "add", "passwd":
+		roleName := fs.String("role", "", "роль: viewer, planner или admin")
+		password := fs.String("password", "", "пароль")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *name == "" {
+			return errors.New("нужно указать --name")
+		}
+
+		var role auth.Role
+		u, exists := store.Get(*name)
+		switch {
+		case *roleName != "":
+			r, err := auth.ParseRole(*roleName)
+			if err != nil {
+				return err
+			}
+			role = r
+		case exists:
+			role = u.Role
+		case cmd == "passwd":
+			return fmt.Errorf("нет пользователя %s", *name)
+		default:
+			return errors.New("нужно указать --role")
+		}
+
+		pass := *password
+		if pass == "" && (cmd == "passwd" || !exists) {
+			p, err := readPassword()
+			if err != nil {
+				return err
+			}
+			pass = p
+		}
+		if err := store.Save(*name, pass, role); err != nil {
+			return err
+		}
+		fmt.Printf("%s: %s, сохранено в %s\n", *name, role, cfg.UsersPath())
+		return nil
+
+	case "del":
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *name == "" {
+			return errors.New("нужно указать --name")
+		}
+		return store.Delete(*name)
+	}
+
+	fmt.Println(userUsage)
+	return fmt.Errorf("неизвестная команда %s", cmd)
+}
+
+// ensureAdmin завести первого администратора, если пользователей еще нет, чтобы сервер
+// после обновления запускался сам. Имя и пароль берутся из TIMETABLE_ADMIN_USER и
+// TIMETABLE_ADMIN_PASSWORD, без пароля создается случайный и один раз печатается в лог
+func ensureAdmin(cfg config.Config) error {
+	store := auth.NewStore(cfg.UsersPath())
+	users, err := store.Users()
+	if err != nil || len(users) > 0 {
+		return err
+	}
+
+	name := os.Getenv("TIMETABLE_ADMIN_USER")
+	if name == "" {
+		name = "admin"
+	}
+	pass := os.Getenv("TIMETABLE_ADMIN_PASSWORD")
+	generated := pass == ""
+	if generated {
+		b := make([]byte, 12)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		pass = base64.RawURLEncoding.EncodeToString(b)
+	}
+	if err := store.Save(name, pass, auth.RoleAdmin); err != nil {
+		return err
+	}
+
+	if generated {
+		log.Printf("в %s не было пользователей, создан администратор %s с паролем %s - "+
+			"смените его командой timetable user passwd --name %s\n", cfg.UsersPath(), name, pass, name)
+	} else {
+		log.Printf("в %s не было пользователей, создан администратор %s\n", cfg.UsersPath(), name)
+	}
+	return nil
+}
+
+func readPassword() (string, error) {
+	fmt.Print("Пароль: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", errors.New("пароль не введен")
+	}
+	pass := strings.TrimRight(line, "\r\n")
+	if pass == "" {
+		return "", errors.New("пароль не должен быть пустым")
+	}
+	return pass, nil
+}
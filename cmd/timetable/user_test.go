@@ -0,0 +1,54 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sergrom/timetable/internal/config"
+	"github.com/sergrom/timetable/internal/services/auth"
+)
+
+func TestEnsureAdmin(t *testing.T) {
+	tests := []struct {
+		name, user, password string
+		wantName             string
+	}{
+		{name: "from env", user: "boss", password: "s3cret-pass", wantName: "boss"},
+		{name: "generated password", wantName: "admin"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("TIMETABLE_ADMIN_USER", tc.user)
+			t.Setenv("TIMETABLE_ADMIN_PASSWORD", tc.password)
+			cfg := config.Default()
+			cfg.Server.DataDir = t.TempDir()
+
+			if err := ensureAdmin(cfg); err != nil {
+				t.Fatal(err)
+			}
+			store := auth.NewStore(filepath.Join(cfg.Server.DataDir, auth.UsersFile))
+			users, err := store.Users()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(users) != 1 || users[0].Name != tc.wantName || users[0].Role != auth.RoleAdmin {
+				t.Fatalf("users %+v", users)
+			}
+			if tc.password != "" {
+				if _, err := store.Authenticate(tc.user, tc.password); err != nil {
+					t.Errorf("login with the env password: %v", err)
+				}
+			}
+
+			// пользователи уже есть - никого не добавляем
+			t.Setenv("TIMETABLE_ADMIN_USER", "second")
+			if err := ensureAdmin(cfg); err != nil {
+				t.Fatal(err)
+			}
+			if users, _ := store.Users(); len(users) != 1 {
+				t.Errorf("users %+v", users)
+			}
+		})
+	}
+}
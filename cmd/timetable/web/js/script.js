@@ -1,18 +1,107 @@
 var TourName = '';
 var Solutions = [];
 var Teams = {};
+var Referees = {};
 var DayStart = "";
 var DayEnd = "";
+var VenueTmpl = null;
+var RunID = ''; // открытый сохраненный запуск, пусто - текущий поиск
 
 $( document ).ready(function() {
+    // сессия истекла - на вход, не хватает прав - сообщаем, если запрос сам не обрабатывает ошибки
+    $(document).ajaxError(function(event, xhr, settings) {
+        if (xhr.status == 401) {
+            window.location.href = '/login?next='+encodeURIComponent(window.location.pathname+window.location.search);
+        } else if (xhr.status == 403 && !settings.error) {
+            alert(xhr.responseJSON && xhr.responseJSON.error ? xhr.responseJSON.error : 'Недостаточно прав');
+        }
+    });
+
+    VenueTmpl = $('#Venues .venue').first().clone();
     $('.select2').select2();
     $('#WishTable').find('.w-from, .w-to').inputmask({alias: "datetime",inputFormat: "HH:MM"});
 
-    drawFields();
-    $('#StadID').on('change', drawFields);
+    $('#Venues .venue').each(function(){
+        drawFields($(this));
+    });
+    $(document).on('change', '.v-stad', function(){
+        drawFields($(this).closest('.venue'));
+    });
+
+    $('#AddVenue').on('click', function(){
+        var $venue = VenueTmpl.clone();
+        $('#Venues').append($venue);
+        $venue.find('.select2').select2();
+        drawFields($venue);
+    });
+
+    $(document).on('click', '.x-venue-btn', function(){
+        if ($('#Venues .venue').length > 1) {
+            $(this).closest('.venue').remove();
+            recountFieldIdx();
+        }
+    });
+
+    $('#SeasonTour').on('change', function(){
+        var $opt = $(this).find('option:selected');
+        if (!$opt.val()) {
+            return;
+        }
+        $('#TourName').val($opt.val());
+        if ($opt.data('date')) {
+            $('#Venues .v-date').val($opt.data('date'));
+        }
+        var teams = (""+$opt.data('teams')).split(',');
+        $('#TeamsSelects select').each(function(){
+            var $sel = $(this);
+            var vals = [];
+            $sel.find('option').each(function(){
+                if (teams.indexOf($(this).val()) >= 0) {
+                    vals.push($(this).val());
+                }
+            });
+            $sel.val(vals).trigger('change');
+        });
+    });
+
+    $('#SeasonPlan').on('click', function(){
+        var divIds = [];
+        $('#SeasonDivs option:selected').each(function(){
+            divIds.push(parseInt($(this).val()));
+        });
+        var dates = [];
+        $('#SeasonDates').val().split(',').forEach(function(d){
+            d = d.trim();
+            if (d) {
+                dates.push(d);
+            }
+        });
+
+        $.ajax({
+            type: 'POST',
+            url: '/season-plan',
+            data: JSON.stringify({division_ids: divIds, dates: dates}),
+            dataType: "json",
+            success: function(data) {
+                if (data.result) {
+                    if (data.skipped) {
+                        alert('Пары команд одного тренера не ставятся в план, пропущено: '+data.skipped);
+                    }
+                    location.reload();
+                    return
+                }
+                alert(data.error);
+            }
+        });
+    });
 
     if ( window.location.pathname == '/' ){
-        checkStat(true);
+        var runId = new URLSearchParams(window.location.search).get('run');
+        if (runId) {
+            openRun(runId);
+        } else {
+            checkStat(true);
+        }
     }
     
     $('#GO').on('click', function(){
@@ -33,21 +122,32 @@ $( document ).ready(function() {
         }
 
         var tourName = $('#TourName').val();
-        var stadID = parseInt($('#StadID').val());
-        
-        var fields = [];
-        $('#FieldsTable tbody tr').each(function(index){
-            var $tr = $(this);
-            var format = parseInt($tr.find('.f-format').val());
-            if (format < 3 || format > 7) {
-                alert('Формат поля должен быть от 3 до 7');
-                return;
-            }
-            fields.push({
-                format: format,
-                from: $tr.find('.f-from').val(),
-                to: $tr.find('.f-to').val(),
-                dur: parseInt($tr.find('.f-dur').val()),
+
+        var venues = [];
+        $('#Venues .venue').each(function(){
+            var $venue = $(this);
+            var fields = [];
+            $venue.find('.v-fields tbody tr').each(function(index){
+                var $tr = $(this);
+                var format = parseInt($tr.find('.f-format').val());
+                if (format < 3 || format > 7) {
+                    alert('Формат поля должен быть от 3 до 7');
+                    return;
+                }
+                fields.push({
+                    format: format,
+                    from: $tr.find('.f-from').val(),
+                    to: $tr.find('.f-to').val(),
+                    dur: parseInt($tr.find('.f-dur').val()),
+                    buffer: parseInt($tr.find('.f-buffer').val()) || 0,
+                    breaks: $tr.find('.f-breaks').val(),
+                });
+            });
+            venues.push({
+                stadium_id: parseInt($venue.find('.v-stad').val()),
+                date: $venue.find('.v-date').val(),
+                fields: fields,
+                merges: $venue.find('.v-merges').val(),
             });
         });
 
@@ -57,34 +157,54 @@ $( document ).ready(function() {
             teams.push(parseInt(teamId));
         });
 
+        // без списка судей на странице поиск берет всех судей
+        var refereeIds = undefined;
+        if ($('#RefereesSelect').length) {
+            refereeIds = [];
+            $('#RefereesSelect option:selected').each(function(){
+                refereeIds.push(parseInt($(this).val()));
+            });
+        }
+
         var wishes = [];
         $('#WishTable tbody tr').each(function(index){
             var $tr = $(this);
             wishes.push({
                 team_id: parseInt($tr.data('team-id')),
+                kind: parseInt($tr.data('kind')) || 0,
                 from: $tr.find('input.w-from').val(),
-                to: $tr.find('input.w-to').val()
+                to: $tr.find('input.w-to').val(),
+                weight: parseInt($tr.data('weight')) || 0,
+                value: parseInt($tr.data('value')) || 0
             });
         });
 
         var games = [];
         $('#GamesTable tbody tr').each(function(index){
             var $tr = $(this);
-            if ($tr.find('input[type="checkbox"]:checked').length == 0) {
-                games.push({
-                    team_id_1: parseInt($tr.data('team-id-1')),
-                    team_id_2: parseInt($tr.data('team-id-2')),
-                });
-            }
+            games.push({
+                tour: ""+$tr.data('tour'),
+                team_id_1: parseInt($tr.data('team-id-1')),
+                team_id_2: parseInt($tr.data('team-id-2')),
+                can_rematch: $tr.find('input[type="checkbox"]:checked').length,
+            });
         });
 
         var data = {
             tour_name: tourName,
-            stadium_id: stadID,
-            fields: fields,
+            season_tour: $('#SeasonTour').val() || '',
+            venues: venues,
+            travel_min: parseInt($('#TravelMin').val()) || 0,
             teams: teams,
+            referee_ids: refereeIds,
             wishes: wishes,
-            games: games
+            games: games,
+            rematch_tours: parseInt($('#RematchTours').val()) || 0,
+            rematch_weight: parseInt($('#RematchWeight').val()) || 0,
+            rating_weight: parseInt($('#RatingWeight').val()) || 0,
+            rating_max_diff: parseInt($('#RatingMaxDiff').val()) || 0,
+            cross_div_weight: parseInt($('#CrossDivWeight').val()) || 0,
+            rating_source: $('#RatingSource').val() || ''
         }
 
         $('#GO').attr('disabled', true);
@@ -95,19 +215,89 @@ $( document ).ready(function() {
             data: JSON.stringify(data),
             dataType: "json",
             success: function(data) {
-                $('#SolCnt').text(""+data.solutions.length);
-                $('#AttCnt').text(data.attempts);
-                TourName = data.tour_name;
-                Teams = data.teams;
-                DayStart = data.day_start.substring(11, 16);
-                DayEnd = data.day_end.substring(11, 16);
-                loadSolutions(data.solutions);
-                $('#GO').removeAttr('disabled');
-                $('#GO').text('Стоп');
-                $('#Results').css('visibility', 'visible');
+                searchStarted(data);
             },
             error: function(err){
                 console.log('error', err);
+                $('#GO').removeAttr('disabled');
+                if (err.responseJSON && err.responseJSON.error) {
+                    alert(err.responseJSON.error);
+                }
+            }
+        });
+    });
+
+    $('#ProblemDownload').on('click', function(){
+        window.open('/problem-download'+(RunID ? '?run='+encodeURIComponent(RunID) : ''), '_blank');
+    });
+
+    $('#ProblemUpload').on('click', function(){
+        $('#ProblemFile').val('').click();
+    });
+
+    $('#ProblemFile').on('change', function(){
+        var file = this.files[0];
+        if (!file) {
+            return;
+        }
+        var reader = new FileReader();
+        reader.onload = function() {
+            $('#GO').attr('disabled', true);
+            $.ajax({
+                url: '/problem-start',
+                type: "POST",
+                data: reader.result,
+                contentType: "application/json",
+                dataType: "json",
+                success: function(data) {
+                    searchStarted(data);
+                },
+                error: function(err){
+                    $('#GO').removeAttr('disabled');
+                    alert(err.responseJSON && err.responseJSON.error ? err.responseJSON.error : 'Не удалось запустить поиск');
+                }
+            });
+        };
+        reader.readAsText(file);
+    });
+
+    $('#SearchResume').on('click', function(){
+        var $btn = $(this);
+        $btn.attr('disabled', true);
+        $.ajax({
+            url: '/search-resume',
+            type: "POST",
+            dataType: "json",
+            success: function(data) {
+                $btn.hide().removeAttr('disabled');
+                $('#ResumeOffer').remove();
+                searchStarted(data);
+            },
+            error: function(err){
+                $btn.removeAttr('disabled');
+                alert(err.responseJSON && err.responseJSON.error ? err.responseJSON.error : 'Не удалось продолжить поиск');
+            }
+        });
+    });
+
+    $('.resume-run').on('click', function(){
+        var $btn = $(this);
+        $btn.attr('disabled', true);
+        $.ajax({
+            url: '/run-resume?id='+encodeURIComponent($btn.data('run')),
+            type: "POST",
+            dataType: "json",
+            success: function(data) {
+                if (!$('#GO').length) {
+                    window.location.href = '/';
+                    return;
+                }
+                $('#ResumeOffer').remove();
+                searchStarted(data);
+            },
+            error: function(err){
+                $btn.removeAttr('disabled');
+                alert(err.responseJSON && err.responseJSON.error ? err.responseJSON.error : 'Не удалось продолжить поиск');
             }
         });
     });
@@ -130,10 +320,8 @@ $( document ).ready(function() {
     });
 
     $(document).on('click','.x-field-btn',function(){
-        var $tr = $(this).closest('tr');
-        var $tbody = $tr.closest('tbody');
-        $tr.remove();
-        recountFieldIdx($tbody);
+        $(this).closest('tr').remove();
+        recountFieldIdx();
     });
     
     $(document).on('click','.solution-item',function(){
@@ -141,7 +329,8 @@ $( document ).ready(function() {
         var $area = $('#SolutionDetailsArea');
         $area.html('');
 
-        $area.append('<h4>'+TourName+', Решение №'+(solutionId+1)+'<a href="/download-solution?hash='+Solutions[solutionId].hash+'" target="blank" class="btn btn-sm btn-primary pull-right"><i class="fa fa-file-excel-o" aria-hidden="true"></i> Скачать</a>'+'<h4>');
+        $area.append('<h4>'+TourName+', Решение №'+(solutionId+1)+'<a href="/download-solution?hash='+Solutions[solutionId].hash+(RunID ? '&run='+RunID : '')+'" target="blank" class="btn btn-sm btn-primary pull-right"><i class="fa fa-file-excel-o" aria-hidden="true"></i> Скачать</a>'+
+            '<button type="button" data-hash="'+Solutions[solutionId].hash+'" class="publish-btn btn btn-sm btn-success pull-right" style="margin-right:5px" title="Записать игры решения в предыдущие игры, повторная публикация заменяет игры тура"><i class="fa fa-check" aria-hidden="true"></i> Опубликовать</button>'+'<h4>');
 
         // add tables with bars
 
@@ -170,11 +359,80 @@ $( document ).ready(function() {
         );
 
         for (var field in Solutions[solutionId].games) {
-            var $el = fieldTable(field, Solutions[solutionId].games[field], Teams)
+            var venue = (Solutions[solutionId].venues || {})[field];
+            var $el = fieldTable(venue ? venue+': '+field : field, Solutions[solutionId].games[field], Teams)
             $area.append($el);
         }
     });
 
+    $(document).on('click', '.publish-btn', function(){
+        var $btn = $(this);
+        if (!confirm('Записать игры решения в предыдущие игры тура '+TourName+'?')) {
+            return;
+        }
+        $btn.attr('disabled', true);
+        $.ajax({
+            type: 'POST',
+            url: '/publish-solution?hash='+$btn.data('hash')+(RunID ? '&run='+RunID : ''),
+            dataType: "json",
+            success: function(data) {
+                $btn.removeAttr('disabled');
+                if (data.result) {
+                    alert('Тур '+data.tour+': записано игр - '+data.games);
+                    return
+                }
+                alert(data.error);
+            }
+        });
+    });
+
+    $('#UnpublishTour').on('click', function(){
+        var tour = $('#UnpublishTourName').val();
+        if (!confirm('Удалить все игры тура '+tour+'?')) {
+            return;
+        }
+        $.ajax({
+            type: 'POST',
+            url: '/unpublish-tour?tour='+encodeURIComponent(tour),
+            dataType: "json",
+            success: function(data) {
+                if (data.result) {
+                    location.reload();
+                    return
+                }
+                alert(data.error);
+            }
+        });
+    });
+
+    $('.save-result-btn').on('click', function(){
+        var $tr = $(this).closest('tr');
+        var data = {
+            id: ""+$tr.data('id'),
+            score1: $tr.find('.r-score1').val(),
+            score2: $tr.find('.r-score2').val(),
+            status: $tr.find('.r-status').val(),
+            date: $tr.find('.r-date').val()
+        };
+        if (data.status == '0' && data.score1 !== '' && data.score2 !== '') {
+            data.status = '1';
+            $tr.find('.r-status').val('1');
+        }
+        $.ajax({
+            type: 'POST',
+            url: '/save-result?id='+data.id,
+            data: JSON.stringify(data),
+            dataType: "json",
+            success: function(data) {
+                if (data.result) {
+                    $tr.addClass('table-success');
+                    return
+                }
+                alert(data.error);
+            }
+        });
+    });
+
     $('.del-btn').on('click', function(){
         var tag = $(this).data('tag');
         var id = $(this).data('id');
@@ -193,6 +451,27 @@ $( document ).ready(function() {
         });
     });
 
+    $('.restore-revision').on('click', function(){
+        var $btn = $(this);
+        var id = $btn.data('id');
+        if (!confirm(id ? 'Вернуть запись '+id+' к этой версии?' : 'Вернуть всю таблицу к этой версии?')) {
+            return;
+        }
+
+        $.ajax({
+            type: 'POST',
+            url: '/history-restore?table='+encodeURIComponent($btn.data('table'))+'&rev='+encodeURIComponent($btn.data('rev'))+'&id='+encodeURIComponent(id),
+            dataType: "json",
+            success: function(data) {
+                if (data.result) {
+                    location.reload();
+                    return
+                }
+                alert(data.error);
+            }
+        });
+    });
+
     $('#ModalForm').modal({show:false});
 
     $('.edit-btn').on('click', function(){
@@ -236,9 +515,10 @@ $( document ).ready(function() {
         showForm($btn, tag, -1)
     });
 
-    $('#AddField').on('click', function(){
-        var fOpts = getFieldOptions();
-        $('#FieldsTable tbody').append(getFieldTr(fOpts.format, fOpts.from, fOpts.to, fOpts.dur));
+    $(document).on('click', '.v-add-field', function(){
+        var $venue = $(this).closest('.venue');
+        var fOpts = getFieldOptions($venue);
+        $venue.find('.v-fields tbody').append(getFieldTr(fOpts.format, fOpts.from, fOpts.to, fOpts.dur, fOpts.buffer, fOpts.breaks));
         recountFieldIdx();
     });
 
@@ -278,6 +558,9 @@ $( document ).ready(function() {
 
     recountTeamsSelected();
     $('#TeamsSelects select').on("change", recountTeamsSelected);
+    $('#RefereesSelect select').on("change", function(){
+        $('#RefereesCnt').html(optsSelectedCnt($('#RefereesSelect')));
+    }).trigger('change');
 });
 
 function recountTeamsSelected() {
@@ -338,16 +621,20 @@ function checkStat(firstCall) {
             if (data.status == "process") {
                 $('#SolCnt').text(""+data.solutions_cnt);
                 $('#AttCnt').text(data.attempts);
+                showRefRejected(data.rejected);
                 $('#GO').text('Стоп');
+                $('#SearchResume').hide();
                 $('#Results').css('visibility', 'visible');
                 if (data.tour_name) {
                     TourName = data.tour_name;
                     Teams = data.teams;
+                    Referees = data.referees || {};
                     DayStart = data.day_start.substring(11, 16);
                     DayEnd = data.day_end.substring(11, 16);
                 }
             } else {
                 $('#GO').text('Пуск').removeAttr('disabled');
+                $('#SearchResume').toggle(!!data.resumable);
                 $('#Results').css('visibility', 'hidden');
             }
         }
@@ -358,6 +645,54 @@ function checkStat(firstCall) {
     });
 }
 
+function searchStarted(data) {
+    $('#SolCnt').text(""+data.solutions.length);
+    $('#AttCnt').text(data.attempts);
+    showRefRejected(data.rejected);
+    RunID = '';
+    TourName = data.tour_name;
+    Teams = data.teams;
+    Referees = data.referees || {};
+    DayStart = data.day_start.substring(11, 16);
+    DayEnd = data.day_end.substring(11, 16);
+    $('#LoadSolutions').show();
+    loadSolutions(data.solutions);
+    $('#GO').removeAttr('disabled');
+    $('#GO').text('Стоп');
+    $('#Results').css('visibility', 'visible');
+}
+
+// showRefRejected сколько решений отброшено, потому что судей не хватило
+function showRefRejected(cnt) {
+    $('#RefRejected span').text(cnt || 0);
+    $('#RefRejected').toggle(cnt > 0);
+}
+
+function openRun(runId) {
+    $.ajax({
+        url: '/run?id='+encodeURIComponent(runId),
+        type: 'GET',
+        dataType: 'json',
+        success: function(data) {
+            RunID = data.run_id;
+            TourName = data.tour_name;
+            Teams = data.teams;
+            Referees = data.referees || {};
+            DayStart = data.day_start.substring(11, 16);
+            DayEnd = data.day_end.substring(11, 16);
+            $('#SolCnt').text(""+data.solutions.length);
+            $('#AttCnt').text(data.attempts);
+            showRefRejected(0);
+            $('#LoadSolutions').hide();
+            loadSolutions(data.solutions);
+            $('#Results').css('visibility', 'visible');
+        },
+        error: function(err){
+            alert('Запуск не найден');
+        }
+    });
+}
+
 function loadSolutions(solutions) {
     var $ul = $('#SolutionsList ul');
     $ul.find('li').remove();
@@ -373,13 +708,16 @@ function loadSolutions(solutions) {
     }
 }
 
-function getFieldOptions() {
-    var $opt = $('#StadID').find(':selected');
+function getFieldOptions($venue) {
+    var $opt = $venue.find('.v-stad').find(':selected');
     var fields = $opt.data('fields');
     var format = $opt.data('format');
     var from = $opt.data('from');
     var to = $opt.data('to');
     var dur = $opt.data('game-dur');
+    var merges = $opt.data('merges');
+    var breaks = $opt.data('breaks');
+    var buffer = $opt.data('buffer');
 
     return {
         fields: fields,
@@ -387,10 +725,13 @@ function getFieldOptions() {
         from: from,
         to: to,
         dur: dur,
+        merges: merges,
+        breaks: breaks,
+        buffer: buffer,
     }
 }
 
-function getFieldTr(format, from, to, dur) {
+function getFieldTr(format, from, to, dur, buffer, breaks) {
     return $(
         '<tr>'+
             '<td><input type="text" class="form-control form-control-sm" value="0" disabled></td>'+
@@ -398,38 +739,45 @@ function getFieldTr(format, from, to, dur) {
             '<td><input type="text" class="form-control form-control-sm f-from" value="'+from+'" disabled></td>'+
             '<td><input type="text" class="form-control form-control-sm f-to" value="'+to+'"></td>'+
             '<td><input type="text" class="form-control form-control-sm f-dur" value="'+dur+'" disabled></td>'+
+            '<td><input type="text" class="form-control form-control-sm f-buffer" value="'+buffer+'"></td>'+
+            '<td><input type="text" class="form-control form-control-sm f-breaks" value="'+breaks+'" placeholder="13:00-13:30"></td>'+
             '<td><button type="button" class="btn btn-sm btn-warning x-field-btn">✕</button></td>'+
         '</tr>'
     )
 }
 
+// recountFieldIdx поля нумеруются подряд по всем стадионам и дням тура
 function recountFieldIdx() {
-    var $tbody = $('#FieldsTable tbody')
-    $tbody.find('tr').each(function(index){
+    $('#Venues .v-fields tbody tr').each(function(index){
         $(this).find('td:first-child input').val(index+1);
     });
 }
 
-function drawFields() {
-    var fOpts = getFieldOptions();
-    $('#FieldsTable tbody tr').remove();
+function drawFields($venue) {
+    var fOpts = getFieldOptions($venue);
+    var $table = $venue.find('.v-fields');
+    $table.find('tbody tr').remove();
     for (var i=0;i<fOpts.fields; i++) {
-        $('#FieldsTable tbody').append(getFieldTr(fOpts.format, fOpts.from, fOpts.to, fOpts.dur));
+        $table.find('tbody').append(getFieldTr(fOpts.format, fOpts.from, fOpts.to, fOpts.dur, fOpts.buffer, fOpts.breaks));
     }
     recountFieldIdx();
-    $('#FieldsTable').find('.f-from, .f-to').inputmask({alias: "datetime",inputFormat: "HH:MM"});
-    $('#FieldsTable').find('.f-format, .f-dur').inputmask({ regex: "^[0-9]{1,3}$" });
+    $venue.find('.v-merges').val(fOpts.merges);
+    $table.find('.f-from, .f-to').inputmask({alias: "datetime",inputFormat: "HH:MM"});
+    $table.find('.f-format, .f-dur, .f-buffer').inputmask({ regex: "^[0-9]{1,3}$" });
 }
 
 function fieldTable(fieldName, games, teams) {
     var gamesHtml = '';
 
+    var withReferees = Object.keys(Referees).length > 0;
+
     for (var i=0; i<games.length; i++) {
         gamesHtml +=
         '<tr>'+
             '<td>'+teams[games[i].team_id_1]+'</td>'+
             '<td>'+teams[games[i].team_id_2]+'</td>'+
             '<td>'+games[i].start.substring(11, 16)+'-'+games[i].end.substring(11, 16)+'</td>'+
+            (withReferees ? '<td>'+(Referees[games[i].referee_id] || '')+'</td>' : '')+
         '</tr>'
     }
 
@@ -440,6 +788,7 @@ function fieldTable(fieldName, games, teams) {
                     '<th scope="col" style="width:40%">Команда1</th>'+
                     '<th scope="col">Команда2</th>'+
                     '<th scope="col" style="width:100px">Время</th>'+
+                    (withReferees ? '<th scope="col">Судья</th>' : '')+
                 '</tr>'+
             '</thead>'+
             '<tbody>'+
@@ -477,6 +826,20 @@ var formTemplates = {
             <label for="gameDur">Продолжительность игры (мин.)</label>
             <input name="game_dur" type="text" class="form-control form-control-sm" id="gameDur">
         </div>
+        <div class="form-group">
+            <label for="stadMerges">Объединения полей</label>
+            <input name="merges" type="text" class="form-control form-control-sm" id="stadMerges" placeholder="1+2:7; 3+4:7">
+            <small class="form-text text-muted">номера полей через "+" и формат объединенного поля, объединения через ";"</small>
+        </div>
+        <div class="form-group">
+            <label for="stadBuffer">Пересменка между играми (мин.)</label>
+            <input name="buffer" type="text" class="form-control form-control-sm" id="stadBuffer">
+        </div>
+        <div class="form-group">
+            <label for="stadBreaks">Перерывы</label>
+            <input name="breaks" type="text" class="form-control form-control-sm" id="stadBreaks" placeholder="13:00-13:30">
+            <small class="form-text text-muted">время, когда на полях не играют, через ";"</small>
+        </div>
     `,
     division: `
         <div class="form-group">
@@ -489,6 +852,10 @@ var formTemplates = {
             <label for="fieldsFormat">Формат</label>
             <input name="format" type="text" class="form-control form-control-sm" id="fieldsFormat">
         </div>
+        <div class="form-group">
+            <label for="playsWith">Играет с дивизионами (того же формата)</label>
+            <select name="plays_with" multiple="multiple" class="form-control form-control-sm select2" id="playsWith" style="width:100%"></select>
+        </div>
     `,
     coach: `
         <div class="form-group">
@@ -497,6 +864,46 @@ var formTemplates = {
             <label for="stadName">Имя</label>
             <input name="name" type="text" class="form-control form-control-sm" id="stadName">
         </div>
+        <div class="form-group">
+            <label for="timeFrom">Приезжает (пусто - с начала дня)</label>
+            <input name="time_from" type="text" class="form-control form-control-sm" id="timeFrom">
+        </div>
+        <div class="form-group">
+            <label for="timeTo">Уезжает (пусто - до конца дня)</label>
+            <input name="time_to" type="text" class="form-control form-control-sm" id="timeTo">
+        </div>
+        <div class="form-group">
+            <label for="maxConsecutive">Игр подряд, максимум (пусто - без ограничения)</label>
+            <input name="max_consecutive" type="text" class="form-control form-control-sm" id="maxConsecutive">
+        </div>
+        <div class="form-group">
+            <label for="breakDur">Перерыв после серии игр (мин.)</label>
+            <input name="break_dur" type="text" class="form-control form-control-sm" id="breakDur">
+        </div>
+    `,
+    referee: `
+        <div class="form-group">
+            <input type="hidden" name="id" id="stadId">
+            <input type="hidden" name="tag" id="stadTag">
+            <label for="stadName">Имя</label>
+            <input name="name" type="text" class="form-control form-control-sm" id="stadName">
+        </div>
+        <div class="form-group">
+            <label for="refFormats">Форматы через запятую (пусто - любые)</label>
+            <input name="formats" type="text" class="form-control form-control-sm" id="refFormats">
+        </div>
+        <div class="form-group">
+            <label for="timeFrom">Судит с</label>
+            <input name="time_from" type="text" class="form-control form-control-sm" id="timeFrom">
+        </div>
+        <div class="form-group">
+            <label for="timeTo">Судит по</label>
+            <input name="time_to" type="text" class="form-control form-control-sm" id="timeTo">
+        </div>
+        <div class="form-group">
+            <label for="refMaxGames">Игр в день, максимум (пусто - без ограничения)</label>
+            <input name="max_games" type="text" class="form-control form-control-sm" id="refMaxGames">
+        </div>
     `,
     team: `
         <div class="form-group">
@@ -513,6 +920,10 @@ var formTemplates = {
             <label for="coachId">Тренер</label>
             <select name="coach_id" class="form-control form-control-sm select2" id="coachId" style="width:100%"></select>
         </div>
+        <div class="form-group">
+            <label for="teamRating">Рейтинг (например 1500, пусто - не задан)</label>
+            <input name="rating" type="text" class="form-control form-control-sm" id="teamRating">
+        </div>
     `,
     wish: `
         <div class="form-group">
@@ -521,6 +932,27 @@ var formTemplates = {
             <label for="teamId">Название</label>
             <select name="team_id" class="form-control form-control-sm select2" id="teamId" style="width:100%"></select>
         </div>
+        <div class="form-group">
+            <label for="wishKind">Тип</label>
+            <select name="kind" class="form-control form-control-sm" id="wishKind">
+                <option value="0">Играть только в этот промежуток</option>
+                <option value="1">Не может играть в этот промежуток</option>
+                <option value="2">Предпочитает играть ближе к этому времени</option>
+                <option value="3">Играть только на поле</option>
+                <option value="4">Не играть на поле</option>
+                <option value="5">Играть только на полях формата</option>
+                <option value="6">Обязательно сыграть с соперником</option>
+                <option value="7">Не играть с соперником</option>
+            </select>
+        </div>
+        <div class="form-group wish-value">
+            <label for="wishValue">Номер поля или формат</label>
+            <input name="value" type="text" class="form-control form-control-sm" id="wishValue">
+        </div>
+        <div class="form-group wish-opponent">
+            <label for="opponentId">Соперник</label>
+            <select name="opponent_id" class="form-control form-control-sm select2" id="opponentId" style="width:100%"></select>
+        </div>
         <div class="form-group">
             <label for="timeFrom">С</label>
             <input name="time_from" type="text" class="form-control form-control-sm" id="timeFrom">
@@ -529,6 +961,10 @@ var formTemplates = {
             <label for="timeTo">По</label>
             <input name="time_to" type="text" class="form-control form-control-sm" id="timeTo">
         </div>
+        <div class="form-group">
+            <label for="wishWeight">Вес (только для "предпочитает")</label>
+            <input name="weight" type="text" class="form-control form-control-sm" id="wishWeight">
+        </div>
     `,
     games: `
         <div class="form-group">
@@ -567,21 +1003,33 @@ function showForm($btn, tag, id) {
                 $html.find('#fromTime').val($tds.eq(4).html());
                 $html.find('#toTime').val($tds.eq(5).html());
                 $html.find('#gameDur').val(parseInt($tds.eq(6).html()));
+                $html.find('#stadMerges').val($tds.eq(7).text());
+                $html.find('#stadBreaks').val($tds.eq(8).text());
+                $html.find('#stadBuffer').val($tds.eq(9).text());
             }
             $html.find('#fieldsCount, #fieldsFormat').inputmask("9");
             $html.find('#gameDur').inputmask({ regex: "^[0-9]{1,3}$" });
+            $html.find('#stadBuffer').inputmask({ regex: "^[0-9]{1,2}$" });
             $html.find('#fromTime, #toTime').inputmask({alias: "datetime",inputFormat: "HH:MM"});
             break;
         case 'division':
             $html = $(formTemplates.division);
             $html.find('#stadId').val(id);
             $html.find('#stadTag').val(tag);
+            var $playsWithEl = $html.find('#playsWith');
+            for (k in Divisions) {
+                if (k != id) {
+                    $playsWithEl.append('<option value="'+k+'">'+Divisions[k]+'</option>');
+                }
+            }
             if (id != -1) {
                 var $tds = $btn.closest('tr').find('td');
                 $html.find('#stadName').val($tds.eq(1).html());
                 $html.find('#fieldsFormat').val($tds.eq(2).html());
+                $playsWithEl.val((""+$btn.data('plays-with')).split(','));
             }
             $html.find('#fieldsFormat').inputmask("9");
+            $html.find('.select2').select2();
             break;
         case 'coach':
             $html = $(formTemplates.coach);
@@ -590,7 +1038,29 @@ function showForm($btn, tag, id) {
             if (id != -1) {
                 var $tds = $btn.closest('tr').find('td');
                 $html.find('#stadName').val($tds.eq(1).html());
+                $html.find('#timeFrom').val($tds.eq(2).html());
+                $html.find('#timeTo').val($tds.eq(3).html());
+                $html.find('#maxConsecutive').val($tds.eq(4).html());
+                $html.find('#breakDur').val($tds.eq(5).html());
+            }
+            $html.find('#timeFrom, #timeTo').inputmask({alias: "datetime",inputFormat: "HH:MM"});
+            $html.find('#maxConsecutive').inputmask({ regex: "^[0-9]{0,2}$" });
+            $html.find('#breakDur').inputmask({ regex: "^[0-9]{0,3}$" });
+            break;
+        case 'referee':
+            $html = $(formTemplates.referee);
+            $html.find('#stadId').val(id);
+            $html.find('#stadTag').val(tag);
+            if (id != -1) {
+                var $tds = $btn.closest('tr').find('td');
+                $html.find('#stadName').val($tds.eq(1).html());
+                $html.find('#refFormats').val($tds.eq(2).html());
+                $html.find('#timeFrom').val($tds.eq(3).html());
+                $html.find('#timeTo').val($tds.eq(4).html());
+                $html.find('#refMaxGames').val($tds.eq(5).html());
             }
+            $html.find('#timeFrom, #timeTo').inputmask({alias: "datetime",inputFormat: "HH:MM"});
+            $html.find('#refMaxGames').inputmask({ regex: "^[0-9]{0,2}$" });
             break;
         case 'team':
             $html = $(formTemplates.team);
@@ -607,9 +1077,11 @@ function showForm($btn, tag, id) {
             if (id != -1) {
                 var $tds = $btn.closest('tr').find('td');
                 $html.find('#stadName').val($tds.eq(1).html());
+                $html.find('#teamRating').val($tds.eq(4).html());
                 $divEl.val($btn.data('div-id'));
                 $coachEl.val($btn.data('coach-id'));
             }
+            $html.find('#teamRating').inputmask({ regex: "^[0-9]{0,4}$" });
             $html.find('.select2').select2();
             break;
         case 'wish':
@@ -617,15 +1089,33 @@ function showForm($btn, tag, id) {
             $html.find('#stadId').val(id);
             $html.find('#stadTag').val(tag);
             var $teamIdEl = $html.find('#teamId');
+            var $opponentEl = $html.find('#opponentId');
             for (k in Teams) {
                 $teamIdEl.append('<option value="'+k+'">'+Teams[k]+'</option>');
+                $opponentEl.append('<option value="'+k+'">'+Teams[k]+'</option>');
             }
+            var $kindEl = $html.find('#wishKind');
             if (id != -1) {
                 var $tds = $btn.closest('tr').find('td');
                 $html.find('#timeFrom').val($tds.eq(1).html());
                 $html.find('#timeTo').val($tds.eq(2).html());
+                $kindEl.val($btn.data('kind'));
+                $html.find('#wishWeight').val($tds.eq(5).html());
+                $html.find('#wishValue').val($btn.data('value'));
+                $opponentEl.val($btn.data('value'));
                 $teamIdEl.val($btn.data('team-id'));
             }
+            var toggleWishKind = function() {
+                var kind = parseInt($kindEl.val());
+                $html.find('#timeFrom, #timeTo').closest('.form-group').toggle(kind <= 2);
+                $html.find('#wishWeight').closest('.form-group').toggle(kind == 2);
+                $html.find('.wish-value').toggle(kind >= 3 && kind <= 5);
+                $html.find('.wish-opponent').toggle(kind >= 6);
+            };
+            $kindEl.on('change', toggleWishKind);
+            toggleWishKind();
+            $html.find('#wishValue').inputmask({ regex: "^[0-9]{0,2}$" });
+            $html.find('#wishWeight').inputmask({ regex: "^[0-9]{0,3}$" });
             $html.find('#timeFrom').inputmask({alias: "datetime",inputFormat: "HH:MM"});
             $html.find('#timeTo').inputmask({alias: "datetime",inputFormat: "HH:MM"});
             $html.find('.select2').select2();
@@ -4,7 +4,12 @@ go 1.19
 
 require (
 	github.com/gin-gonic/gin v1.9.0
+	github.com/jung-kurt/gofpdf v1.16.2
 	github.com/xuri/excelize/v2 v2.7.1
+	go.etcd.io/bbolt v1.3.7
+	golang.org/x/crypto v0.8.0
+	golang.org/x/image v0.5.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -30,10 +35,8 @@ require (
 	github.com/xuri/efp v0.0.0-20220603152613-6918739fd470 // indirect
 	github.com/xuri/nfp v0.0.0-20220409054826-5e722a1d9e22 // indirect
 	golang.org/x/arch v0.0.0-20210923205945-b76863e36670 // indirect
-	golang.org/x/crypto v0.8.0 // indirect
 	golang.org/x/net v0.9.0 // indirect
 	golang.org/x/sys v0.7.0 // indirect
 	golang.org/x/text v0.9.0 // indirect
 	google.golang.org/protobuf v1.28.1 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
@@ -5,26 +5,62 @@ import (
 	"html/template"
 	"log"
 	"net/http"
+	"path/filepath"
 	"strconv"
+	"sync"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sergrom/timetable/internal/api/req"
+	"github.com/sergrom/timetable/internal/config"
 	"github.com/sergrom/timetable/internal/repository"
+	"github.com/sergrom/timetable/internal/services/audit"
+	"github.com/sergrom/timetable/internal/services/auth"
+	"github.com/sergrom/timetable/internal/services/revisions"
+	"github.com/sergrom/timetable/internal/services/runs"
 	"github.com/sergrom/timetable/internal/services/searcher"
 )
 
 // TimetableAPI ...
 type TimetableAPI struct {
-	repo     *repository.Repo
-	searcher *searcher.Searcher
+	repo         *repository.Repo
+	searcher     *searcher.Searcher
+	runs         *runs.Store
+	audit        *audit.Log
+	revisions    *revisions.Store
+	maxSolutions int
+	tracking     sync.WaitGroup // сохранение запусков, которые еще идут
+
+	runLock sync.Mutex
+	current currentRun // запуск, который сохраняет trackRun
+
+	users     *auth.Store // nil - вход выключен, всем доступно все
+	sessions  *auth.Sessions
+	basicAuth bool
 }
 
 // NewTimetableAPI ...
-func NewTimetableAPI() *TimetableAPI {
-	return &TimetableAPI{
-		repo:     repository.NewRepo(),
-		searcher: searcher.NewSearcher(),
+func NewTimetableAPI(cfg config.Config) (*TimetableAPI, error) {
+	repo, err := repository.OpenRepo(cfg.Server.Storage, cfg.Server.DataDir)
+	if err != nil {
+		return nil, err
+	}
+	tt := &TimetableAPI{
+		repo:         repo,
+		searcher:     searcher.NewSearcher(cfg.Search),
+		runs:         runs.NewStore(filepath.Join(cfg.Server.DataDir, runs.Dir)),
+		audit:        audit.NewLog(filepath.Join(cfg.Server.DataDir, audit.File)),
+		revisions:    revisions.NewStore(filepath.Join(cfg.Server.DataDir, revisions.Dir)),
+		maxSolutions: cfg.Server.MaxSolutions,
 	}
+	if cfg.Auth.Enabled {
+		tt.users = auth.NewStore(cfg.UsersPath())
+		tt.sessions = auth.NewSessions(cfg.Auth.SessionTTL)
+		tt.basicAuth = cfg.Auth.Basic
+	}
+	if err := tt.runs.MarkInterrupted(); err != nil {
+		log.Println(err)
+	}
+	return tt, nil
 }
 
 // GetHandlers ...
@@ -33,22 +69,32 @@ func (tt *TimetableAPI) GetHandlers() map[string]Handler {
 		"/": {
 			Method: http.MethodGet,
 			Fn:     tt.index,
+			Role:   auth.RoleViewer,
 		},
 		"/teams": {
 			Method: http.MethodGet,
 			Fn:     tt.teams,
+			Role:   auth.RoleViewer,
 		},
 		"/coaches": {
 			Method: http.MethodGet,
 			Fn:     tt.coaches,
+			Role:   auth.RoleViewer,
 		},
 		"/stadiums": {
 			Method: http.MethodGet,
 			Fn:     tt.stadiums,
+			Role:   auth.RoleViewer,
 		},
 		"/divisions": {
 			Method: http.MethodGet,
 			Fn:     tt.divisions,
+			Role:   auth.RoleViewer,
+		},
+		"/referees": {
+			Method: http.MethodGet,
+			Fn:     tt.referees,
+			Role:   auth.RoleViewer,
 		},
 		// "/stadiums-download": {
 		// 	Method: http.MethodGet,
@@ -69,38 +115,157 @@ func (tt *TimetableAPI) GetHandlers() map[string]Handler {
 		"/wishes": {
 			Method: http.MethodGet,
 			Fn:     tt.wishes,
+			Role:   auth.RoleViewer,
 		},
 		"/games": {
 			Method: http.MethodGet,
 			Fn:     tt.games,
+			Role:   auth.RoleViewer,
+		},
+		"/results": {
+			Method: http.MethodGet,
+			Fn:     tt.results,
+			Role:   auth.RoleViewer,
+		},
+		"/standings": {
+			Method: http.MethodGet,
+			Fn:     tt.standingsPage,
+			Role:   auth.RoleViewer,
+		},
+		"/standings-download": {
+			Method: http.MethodGet,
+			Fn:     tt.standingsDownload,
+			Role:   auth.RoleViewer,
+		},
+		"/standings-pdf": {
+			Method: http.MethodGet,
+			Fn:     tt.standingsPDF,
+			Role:   auth.RoleViewer,
+		},
+		"/season": {
+			Method: http.MethodGet,
+			Fn:     tt.season,
+			Role:   auth.RoleViewer,
+		},
+		"/season-plan": {
+			Method: http.MethodPost,
+			Fn:     tt.seasonPlan,
+			Role:   auth.RolePlanner,
 		},
 		"/status": {
 			Method: http.MethodGet,
 			Fn:     tt.status,
+			Role:   auth.RoleViewer,
 		},
 		"/search-start": {
 			Method: http.MethodPost,
 			Fn:     tt.searchStart,
+			Role:   auth.RolePlanner,
 		},
 		"/search-stop": {
 			Method: http.MethodPost,
 			Fn:     tt.searchStop,
+			Role:   auth.RolePlanner,
+		},
+		"/search-resume": {
+			Method: http.MethodPost,
+			Fn:     tt.searchResume,
+			Role:   auth.RolePlanner,
 		},
 		"/get-solutions": {
 			Method: http.MethodGet,
 			Fn:     tt.getSolutions,
+			Role:   auth.RoleViewer,
+		},
+		"/runs": {
+			Method: http.MethodGet,
+			Fn:     tt.runsPage,
+			Role:   auth.RoleViewer,
+		},
+		"/run": {
+			Method: http.MethodGet,
+			Fn:     tt.getRun,
+			Role:   auth.RoleViewer,
+		},
+		"/run-resume": {
+			Method: http.MethodPost,
+			Fn:     tt.runResume,
+			Role:   auth.RolePlanner,
+		},
+		"/problem-download": {
+			Method: http.MethodGet,
+			Fn:     tt.problemDownload,
+			Role:   auth.RoleViewer,
+		},
+		"/problem-start": {
+			Method: http.MethodPost,
+			Fn:     tt.problemStart,
+			Role:   auth.RolePlanner,
 		},
 		"/download-solution": {
 			Method: http.MethodGet,
 			Fn:     tt.downloadSolution,
+			Role:   auth.RoleViewer,
+		},
+		"/publish-solution": {
+			Method: http.MethodPost,
+			Fn:     tt.publishSolution,
+			Role:   auth.RolePlanner,
+		},
+		"/unpublish-tour": {
+			Method: http.MethodPost,
+			Fn:     tt.unpublishTour,
+			Role:   auth.RolePlanner,
 		},
 		"/del-entity": {
 			Method: http.MethodPost,
 			Fn:     tt.delEntity,
+			Role:   auth.RoleAdmin,
 		},
 		"/save-entity": {
 			Method: http.MethodPost,
 			Fn:     tt.saveEntity,
+			Role:   auth.RoleAdmin,
+		},
+		"/save-result": {
+			Method: http.MethodPost,
+			Fn:     tt.saveResultHandler,
+			Role:   auth.RolePlanner,
+		},
+		"/audit": {
+			Method: http.MethodGet,
+			Fn:     tt.auditPage,
+			Role:   auth.RoleAdmin,
+		},
+		"/audit-download": {
+			Method: http.MethodGet,
+			Fn:     tt.auditDownload,
+			Role:   auth.RoleAdmin,
+		},
+		"/history": {
+			Method: http.MethodGet,
+			Fn:     tt.historyPage,
+			Role:   auth.RoleViewer,
+		},
+		"/history-restore": {
+			Method: http.MethodPost,
+			Fn:     tt.historyRestore,
+			Role:   auth.RoleAdmin,
+		},
+		"/login": {
+			Method: http.MethodGet,
+			Fn:     tt.loginPage,
+			Public: true,
+		},
+		"/auth-login": {
+			Method: http.MethodPost,
+			Fn:     tt.authLogin,
+			Public: true,
+		},
+		"/auth-logout": {
+			Method: http.MethodPost,
+			Fn:     tt.authLogout,
+			Public: true,
 		},
 	}
 }
@@ -145,6 +310,7 @@ func (tt *TimetableAPI) delEntity(c *gin.Context) {
 	q := c.Request.URL.Query()
 	id, _ := strconv.Atoi(q.Get("id"))
 	tag := q.Get("tag")
+	before := tt.tableSnapshot(entityTables[tag])
 
 	var err error
 	switch tag {
@@ -160,6 +326,8 @@ func (tt *TimetableAPI) delEntity(c *gin.Context) {
 		err = tt.delWish(id)
 	case "game":
 		err = tt.delGame(id)
+	case "referee":
+		err = tt.delReferee(id)
 	}
 
 	if err != nil {
@@ -169,6 +337,7 @@ func (tt *TimetableAPI) delEntity(c *gin.Context) {
 		})
 		return
 	}
+	tt.tableChanged(c, entityTables[tag], before, "")
 
 	c.JSON(http.StatusOK, gin.H{
 		"result": true,
@@ -179,6 +348,7 @@ func (tt *TimetableAPI) saveEntity(c *gin.Context) {
 	q := c.Request.URL.Query()
 	//id := q.Get("id")
 	tag := q.Get("tag")
+	before := tt.tableSnapshot(entityTables[tag])
 	var err error
 
 	switch tag {
@@ -224,6 +394,13 @@ func (tt *TimetableAPI) saveEntity(c *gin.Context) {
 			break
 		}
 		err = tt.saveGame(msg)
+	case "referee":
+		var msg req.SaveRefereeRequest
+		if err = c.BindJSON(&msg); err != nil {
+			log.Println(err)
+			break
+		}
+		err = tt.saveReferee(msg)
 	}
 
 	if err != nil {
@@ -233,6 +410,7 @@ func (tt *TimetableAPI) saveEntity(c *gin.Context) {
 		})
 		return
 	}
+	tt.tableChanged(c, entityTables[tag], before, "")
 
 	c.JSON(http.StatusOK, gin.H{
 		"result": true,
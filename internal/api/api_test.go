@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sergrom/timetable/internal/config"
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/services/problem"
+	"github.com/sergrom/timetable/internal/services/searcher"
+)
+
+// testServer сервер на временном каталоге данных с маршрутами и шаблонами, как в cmd/timetable
+type testServer struct {
+	t      *testing.T
+	api    *TimetableAPI
+	router *gin.Engine
+	dir    string
+}
+
+func newTestServer(t *testing.T, cfg config.Config) *testServer {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	ts := &testServer{t: t, dir: cfg.Server.DataDir}
+	api, err := NewTimetableAPI(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { api.repo.Close() })
+	ts.api = api
+	ts.router = gin.New()
+	ts.router.LoadHTMLGlob("../../cmd/timetable/web/*.html")
+	for route, h := range ts.api.GetHandlers() {
+		ts.router.Handle(h.Method, route, ts.api.Authorize(h), h.Fn)
+	}
+	return ts
+}
+
+// testConfig настройки с каталогом данных во временном каталоге теста
+func testConfig(t *testing.T) config.Config {
+	cfg := config.Default()
+	cfg.Server.DataDir = t.TempDir()
+	return cfg
+}
+
+// do выполнить запрос; заголовки и cookie задаются через prepare
+func (ts *testServer) do(method, target string, body string, prepare ...func(r *http.Request)) *httptest.ResponseRecorder {
+	ts.t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	for _, p := range prepare {
+		p(r)
+	}
+	w := httptest.NewRecorder()
+	ts.router.ServeHTTP(w, r)
+	return w
+}
+
+// form тело формы и ее заголовок
+func form(values url.Values) (string, func(r *http.Request)) {
+	return values.Encode(), func(r *http.Request) {
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+}
+
+func withCookie(c *http.Cookie) func(r *http.Request) {
+	return func(r *http.Request) { r.AddCookie(c) }
+}
+
+func ajax(r *http.Request) {
+	r.Header.Set("X-Requested-With", "XMLHttpRequest")
+}
+
+// smallProblem задача на шесть команд и три поля, поиск по ней идет, пока его не остановят
+func smallProblem(t *testing.T) problem.Problem {
+	t.Helper()
+	p := problem.Problem{
+		Version:   problem.Version,
+		TourName:  "Тур 1",
+		Divisions: []ds.Division{{ID: 1, Name: "2012", Format: 6}},
+		Params:    searcher.DefaultParams(),
+	}
+	for id := 1; id <= 3; id++ {
+		f, err := ds.NewField(id, 6, time.Hour, 0, "09:00", "12:00", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.Fields = append(p.Fields, f)
+	}
+	for id := 1; id <= 6; id++ {
+		p.Coaches = append(p.Coaches, ds.Coach{ID: id})
+		p.Teams = append(p.Teams, ds.Team{ID: id, Name: fmt.Sprint("Команда ", id), DivisionID: 1, CoachID: id})
+	}
+	return p
+}
+
+// problemBody файл задачи, как его загружает страница
+func problemBody(t *testing.T, p problem.Problem) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := problem.Write(&buf, p); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
@@ -0,0 +1,260 @@
+package api
+
+import (
+	"encoding/csv"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sergrom/timetable/internal/repository"
+	"github.com/sergrom/timetable/internal/services/audit"
+	"github.com/sergrom/timetable/internal/services/auth"
+)
+
+// AuditPageLimit сколько записей журнала показывать на странице, остальные - в CSV
+const AuditPageLimit = 500
+
+// entityTables таблица справочника, которую меняет сохранение или удаление сущности
+var entityTables = map[string]string{
+	"stadium":  "stadiums",
+	"division": "divisions",
+	"coach":    "coaches",
+	"team":     "teams",
+	"wish":     "wishes",
+	"game":     "games",
+	"referee":  "referees",
+}
+
+var (
+	auditTmpl, _ = template.New(`auditTemplate`).Parse(`
+	<form method="get" action="/audit" class="form-inline mb-3">
+		<select name="user" class="form-control form-control-sm mr-2">
+			<option value="">Все пользователи</option>
+			{{ range .users }}{{ if . }}<option value="{{ . }}"{{ if eq . $.filter.user }} selected{{ end }}>{{ . }}</option>{{ end }}{{ end }}
+		</select>
+		<select name="action" class="form-control form-control-sm mr-2">
+			<option value="">Все действия</option>
+			{{ range .actions }}<option value="{{ index . 0 }}"{{ if eq (index . 0) $.filter.action }} selected{{ end }}>{{ index . 1 }}</option>{{ end }}
+		</select>
+		<select name="entity" class="form-control form-control-sm mr-2">
+			<option value="">Все таблицы</option>
+			{{ range .entities }}<option value="{{ . }}"{{ if eq . $.filter.entity }} selected{{ end }}>{{ . }}</option>{{ end }}
+		</select>
+		<input name="id" class="form-control form-control-sm mr-2" type="text" placeholder="ID" value="{{ .filter.id }}" style="width:80px">
+		<input name="from" class="form-control form-control-sm mr-2" type="date" value="{{ .filter.from }}" title="С даты">
+		<input name="to" class="form-control form-control-sm mr-2" type="date" value="{{ .filter.to }}" title="По дату включительно">
+		<input name="q" class="form-control form-control-sm mr-2" type="text" placeholder="Значение" value="{{ .filter.q }}">
+		<button type="submit" class="btn btn-sm btn-info mr-2">Показать</button>
+		<a href="/audit-download?{{ .query }}" target="_blank" class="btn btn-sm btn-secondary" title="Все найденные записи, по строке на изменившееся поле"><i class="fa fa-download" aria-hidden="true"></i> CSV</a>
+	</form>
+	{{ if .more }}<div class="alert alert-info">Показаны последние {{ .limit }} из {{ .total }} записей, все записи есть в CSV</div>{{ end }}
+	<table class="table table-sm">
+	<thead>
+	  <tr>
+		<th scope="col">Время</th>
+		<th scope="col">Пользователь</th>
+		<th scope="col">IP</th>
+		<th scope="col">Действие</th>
+		<th scope="col">Таблица</th>
+		<th scope="col">ID</th>
+		<th scope="col">Изменения</th>
+	  </tr>
+	</thead>
+	<tbody>
+	  {{ range .entries }}
+	  <tr>
+		<td>{{ .Time }}</td>
+		<td>{{ .User }}</td>
+		<td>{{ .IP }}</td>
+		<td>{{ .Action }}</td>
+		<td>{{ .Entity }}</td>
+		<td>{{ if eq .Entity "search" }}{{ .EntityID }}{{ else }}<a href="/history?table={{ .Entity }}&id={{ .EntityID }}">{{ .EntityID }}</a>{{ end }}</td>
+		<td>{{ range .Changes }}<div><b>{{ .Column }}</b>: {{ if .Before }}<del>{{ .Before }}</del> → {{ end }}{{ .After }}</div>{{ end }}</td>
+	  </tr>
+	  {{ end }}
+	</tbody>
+  </table>
+`)
+)
+
+// auditRow запись журнала для страницы
+type auditRow struct {
+	Time, User, IP, Action, Entity, EntityID string
+	Changes                                  []audit.Change
+}
+
+// auditPage журнал изменений справочников и запусков поиска
+func (tt *TimetableAPI) auditPage(c *gin.Context) {
+	errs := make([]string, 0)
+
+	filter, err := auditFilter(c)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	entries, err := tt.audit.Read(filter)
+	if err != nil {
+		log.Println(err.Error())
+		errs = append(errs, err.Error())
+	}
+	users, err := tt.audit.Users()
+	if err != nil {
+		log.Println(err.Error())
+	}
+
+	total := len(entries)
+	if total > AuditPageLimit {
+		entries = entries[:AuditPageLimit]
+	}
+	rows := make([]auditRow, 0, len(entries))
+	for _, e := range entries {
+		rows = append(rows, auditRow{
+			Time:     e.Time.Format("02.01.2006 15:04:05"),
+			User:     e.User,
+			IP:       e.IP,
+			Action:   audit.ActionLabels[e.Action],
+			Entity:   e.Entity,
+			EntityID: e.EntityID,
+			Changes:  e.Changes(),
+		})
+	}
+
+	actions := make([][]string, 0, len(audit.ActionLabels))
+	for _, a := range []string{audit.ActionCreate, audit.ActionUpdate, audit.ActionDelete,
+		audit.ActionSearchStart, audit.ActionSearchStop, audit.ActionSearchResume} {
+		actions = append(actions, []string{a, audit.ActionLabels[a]})
+	}
+	entities := make([]string, 0, len(repository.Tables)+1)
+	for _, t := range repository.Tables {
+		entities = append(entities, t.Name)
+	}
+	entities = append(entities, audit.EntitySearch)
+
+	q := c.Request.URL.Query()
+	body := tt.renderTemplate(auditTmpl, map[string]interface{}{
+		"users":    users,
+		"actions":  actions,
+		"entities": entities,
+		"filter": map[string]string{
+			"user":   q.Get("user"),
+			"action": q.Get("action"),
+			"entity": q.Get("entity"),
+			"id":     q.Get("id"),
+			"from":   q.Get("from"),
+			"to":     q.Get("to"),
+			"q":      q.Get("q"),
+		},
+		"query":   c.Request.URL.RawQuery,
+		"entries": rows,
+		"more":    total > len(rows),
+		"limit":   AuditPageLimit,
+		"total":   total,
+	})
+
+	c.HTML(http.StatusOK, "tmpl.html", gin.H{
+		"title":    "Конструктор турниров",
+		"subtitle": "Журнал изменений",
+		"errors":   errs,
+		"body":     template.HTML(body),
+		"page":     "audit",
+	})
+}
+
+// auditDownload записи журнала по фильтру в CSV, по строке на изменившееся поле
+func (tt *TimetableAPI) auditDownload(c *gin.Context) {
+	filter, err := auditFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	entries, err := tt.audit.Read(filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename=Журнал.csv")
+	cw := csv.NewWriter(c.Writer)
+	_ = cw.Write([]string{"Время", "Пользователь", "IP", "Действие", "Таблица", "ID", "Поле", "Было", "Стало"})
+	for _, e := range entries {
+		head := []string{e.Time.Format("2006-01-02 15:04:05"), e.User, e.IP, audit.ActionLabels[e.Action], e.Entity, e.EntityID}
+		changes := e.Changes()
+		if len(changes) == 0 {
+			_ = cw.Write(append(head, "", "", ""))
+		}
+		for _, ch := range changes {
+			_ = cw.Write(append(head, ch.Column, ch.Before, ch.After))
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		log.Println(err)
+	}
+}
+
+// auditFilter фильтр из параметров запроса, даты в формате 2006-01-02, конечная включительно
+func auditFilter(c *gin.Context) (audit.Filter, error) {
+	q := c.Request.URL.Query()
+	filter := audit.Filter{
+		User:     q.Get("user"),
+		Action:   q.Get("action"),
+		Entity:   q.Get("entity"),
+		EntityID: strings.TrimSpace(q.Get("id")),
+		Query:    strings.TrimSpace(q.Get("q")),
+	}
+
+	if from := q.Get("from"); from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, time.Local)
+		if err != nil {
+			return filter, err
+		}
+		filter.From = t
+	}
+	if to := q.Get("to"); to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, time.Local)
+		if err != nil {
+			return filter, err
+		}
+		filter.To = t.AddDate(0, 0, 1)
+	}
+	return filter, nil
+}
+
+// auditSearch записать в журнал запуск, остановку или продолжение поиска
+func (tt *TimetableAPI) auditSearch(c *gin.Context, action, runID, tourName string, attempts int) {
+	e := audit.Entry{
+		Action:   action,
+		Entity:   audit.EntitySearch,
+		EntityID: runID,
+		Columns:  []string{"Тур"},
+		After:    []string{tourName},
+	}
+	if action == audit.ActionSearchStop {
+		e.Columns = append(e.Columns, "Попыток")
+		e.After = append(e.After, strconv.Itoa(attempts))
+	}
+	tt.auditRecord(c, e)
+}
+
+// auditRecord дописать записи в журнал от имени пользователя запроса
+func (tt *TimetableAPI) auditRecord(c *gin.Context, entries ...audit.Entry) {
+	user, now := requestUser(c), time.Now()
+	for i := range entries {
+		entries[i].Time, entries[i].User, entries[i].IP = now, user, c.ClientIP()
+	}
+	if err := tt.audit.Append(entries...); err != nil {
+		log.Println(err)
+	}
+}
+
+// requestUser имя вошедшего пользователя, пустое, если вход выключен
+func requestUser(c *gin.Context) string {
+	if u, ok := c.Get(userKey); ok {
+		return u.(auth.User).Name
+	}
+	return ""
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sergrom/timetable/internal/repository"
+	"github.com/sergrom/timetable/internal/services/audit"
+)
+
+// seed записать таблицы справочников в каталог данных сервера
+func (ts *testServer) seed(tables map[string][][]string) {
+	ts.t.Helper()
+	for name, rows := range tables {
+		tbl, err := repository.GetTable(name)
+		if err != nil {
+			ts.t.Fatal(err)
+		}
+		if err := ts.api.repo.WriteTable(tbl, rows); err != nil {
+			ts.t.Fatal(err)
+		}
+	}
+}
+
+// postJSON запрос скрипта страницы, ответ {"result": ..., "error": ...}
+func (ts *testServer) postJSON(target, body string, prepare ...func(r *http.Request)) (bool, string) {
+	ts.t.Helper()
+	w := ts.do(http.MethodPost, target, body, append(prepare, ajax)...)
+	var resp struct {
+		Result bool   `json:"result"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		ts.t.Fatalf("%s: code %d, body %s", target, w.Code, w.Body)
+	}
+	return resp.Result, resp.Error
+}
+
+func TestAuditEntityEdits(t *testing.T) {
+	ts := authServer(t)
+	ts.seed(map[string][][]string{
+		"divisions": {{"ID", "Дивизион", "Формат", "Играет с (ID дивизионов)"}, {"1", "2012", "6"}},
+		"teams":     {{"ID", "Команда", "Тренер", "Дивизион"}, {"1", "Спартак", "1", "1"}},
+	})
+	admin := withCookie(ts.login("admin", "admin-secret"))
+
+	steps := []struct {
+		target, body string
+		wantOk       bool
+	}{
+		{target: "/save-entity?tag=division", body: `{"id":"1","name":"2012 А","format":"6"}`, wantOk: true},
+		{target: "/save-entity?tag=division", body: `{"id":"-1","name":"2013","format":"7"}`, wantOk: true},
+		{target: "/save-entity?tag=division", body: `{"id":"1","name":"2012","format":"9"}`},
+		{target: "/del-entity?tag=division&id=1"}, // в дивизионе есть команда
+		{target: "/del-entity?tag=division&id=2", wantOk: true},
+	}
+	for _, st := range steps {
+		if ok, msg := ts.postJSON(st.target, st.body, admin); ok != st.wantOk {
+			t.Fatalf("%s %s: result %v (%s), want %v", st.target, st.body, ok, msg, st.wantOk)
+		}
+	}
+
+	// в журнале только успешные изменения, от последнего к первому
+	entries, err := ts.api.audit.Read(audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type change struct {
+		action, id string
+		changes    []audit.Change
+	}
+	want := []change{
+		{action: audit.ActionDelete, id: "2", changes: []audit.Change{{Column: "ID", Before: "2"}, {Column: "Дивизион", Before: "2013"}, {Column: "Формат", Before: "7"}}},
+		{action: audit.ActionCreate, id: "2", changes: []audit.Change{{Column: "ID", After: "2"}, {Column: "Дивизион", After: "2013"}, {Column: "Формат", After: "7"}}},
+		{action: audit.ActionUpdate, id: "1", changes: []audit.Change{{Column: "Дивизион", Before: "2012", After: "2012 А"}}},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("entries %+v, want %d", entries, len(want))
+	}
+	for i, e := range entries {
+		got := change{action: e.Action, id: e.EntityID, changes: e.Changes()}
+		if !reflect.DeepEqual(got, want[i]) || e.Entity != "divisions" || e.User != "admin" {
+			t.Errorf("entry %d: %+v by %s, want %+v", i, got, e.User, want[i])
+		}
+	}
+
+	// выгрузка: строка на каждое изменившееся поле
+	w := ts.do(http.MethodGet, "/audit-download?action=update", "", admin)
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1][1] != "admin" || rows[1][6] != "Дивизион" || rows[1][8] != "2012 А" {
+		t.Errorf("csv %q", rows)
+	}
+}
+
+func TestSaveEntityStorage(t *testing.T) {
+	for _, kind := range []string{repository.StorageXlsx, repository.StorageDB} {
+		t.Run(kind, func(t *testing.T) {
+			cfg := testConfig(t)
+			cfg.Server.Storage = kind
+			cfg.Auth.Enabled = false
+			ts := newTestServer(t, cfg)
+			ts.seed(map[string][][]string{"divisions": {{"ID", "Дивизион", "Формат"}, {"1", "2012", "6"}}})
+
+			if ok, msg := ts.postJSON("/save-entity?tag=division", `{"id":"-1","name":"2013","format":"7"}`); !ok {
+				t.Fatal(msg)
+			}
+			divs, err := ts.api.repo.GetDivisions()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(divs) != 2 || divs[1].Name != "2013" || divs[1].Format != 7 {
+				t.Errorf("divisions %+v", divs)
+			}
+			// база не оставляет рядом xlsx-файлов
+			_, err = os.Stat(filepath.Join(cfg.Server.DataDir, repository.DivisionsFile))
+			if exists := err == nil; exists != (kind == repository.StorageXlsx) {
+				t.Errorf("xlsx file exists: %v", exists)
+			}
+			if entries, _ := ts.api.audit.Read(audit.Filter{}); len(entries) != 1 || entries[0].Action != audit.ActionCreate {
+				t.Errorf("audit %+v", entries)
+			}
+		})
+	}
+}
+
+func TestAuditSearch(t *testing.T) {
+	ts := authServer(t)
+	planner := withCookie(ts.login("planner", "planner-secret"))
+
+	// остановка без идущего поиска в журнал не пишется
+	ts.do(http.MethodPost, "/search-stop", "", ajax, planner)
+	if entries, _ := ts.api.audit.Read(audit.Filter{}); len(entries) != 0 {
+		t.Fatalf("entries %+v", entries)
+	}
+
+	if w := ts.do(http.MethodPost, "/problem-start", problemBody(t, smallProblem(t)), ajax, planner); w.Code != http.StatusOK {
+		t.Fatalf("problem start: code %d, %s", w.Code, w.Body)
+	}
+	ts.do(http.MethodPost, "/search-stop", "", ajax, planner)
+	ts.api.tracking.Wait()
+
+	list, err := ts.api.runs.List()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("runs %+v, err %v", list, err)
+	}
+	entries, err := ts.api.audit.Read(audit.Filter{Entity: audit.EntitySearch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("entries %+v", entries)
+	}
+	for i, action := range []string{audit.ActionSearchStop, audit.ActionSearchStart} {
+		e := entries[i]
+		if e.Action != action || e.EntityID != list[0].ID || e.User != "planner" || e.After[0] != "Тур 1" {
+			t.Errorf("entry %d: %+v, want %s of run %s", i, e, action, list[0].ID)
+		}
+	}
+}
+
+func TestAuditStopResumedRun(t *testing.T) {
+	ts := authServer(t)
+	planner := withCookie(ts.login("planner", "planner-secret"))
+
+	// два остановленных запуска, затем продолжается первый: он уже не последний в списке
+	for i := 0; i < 2; i++ {
+		if w := ts.do(http.MethodPost, "/problem-start", problemBody(t, smallProblem(t)), ajax, planner); w.Code != http.StatusOK {
+			t.Fatalf("problem start: code %d, %s", w.Code, w.Body)
+		}
+		ts.do(http.MethodPost, "/search-stop", "", ajax, planner)
+		ts.api.tracking.Wait()
+	}
+	list, err := ts.api.runs.List()
+	if err != nil || len(list) != 2 {
+		t.Fatalf("runs %+v, err %v", list, err)
+	}
+	first := list[1].ID
+
+	if w := ts.do(http.MethodPost, "/run-resume?id="+first, "", ajax, planner); w.Code != http.StatusOK {
+		t.Fatalf("resume: code %d, %s", w.Code, w.Body)
+	}
+	ts.do(http.MethodPost, "/search-stop", "", ajax, planner)
+	ts.api.tracking.Wait()
+
+	entries, err := ts.api.audit.Read(audit.Filter{Entity: audit.EntitySearch})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) < 2 {
+		t.Fatalf("entries %+v", entries)
+	}
+	for i, action := range []string{audit.ActionSearchStop, audit.ActionSearchResume} {
+		if e := entries[i]; e.Action != action || e.EntityID != first {
+			t.Errorf("entry %d: %s of run %s, want %s of run %s", i, e.Action, e.EntityID, action, first)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sergrom/timetable/internal/services/auth"
+)
+
+const (
+	SessionCookie = "tt_session"
+	userKey       = "user" // вошедший пользователь в контексте запроса
+)
+
+var (
+	loginTmpl, _ = template.New(`loginTemplate`).Parse(`
+	<div class="row">
+		<div class="col-4">
+			{{ if .error }}<div class="alert alert-danger">{{ .error }}</div>{{ end }}
+			<form method="post" action="/auth-login">
+				<input type="hidden" name="next" value="{{ .next }}">
+				<div class="form-group">
+					<label>Пользователь</label>
+					<input name="name" class="form-control form-control-sm" type="text" value="{{ .name }}" autofocus>
+				</div>
+				<div class="form-group">
+					<label>Пароль</label>
+					<input name="password" class="form-control form-control-sm" type="password">
+				</div>
+				<button type="submit" class="btn btn-sm btn-success">Войти</button>
+			</form>
+		</div>
+	</div>
+`)
+)
+
+// Authorize middleware: пускает к обработчику вошедшего пользователя с достаточной ролью
+func (tt *TimetableAPI) Authorize(h Handler) gin.HandlerFunc {
+	need := h.Role
+	if need == 0 {
+		need = auth.RoleAdmin
+	}
+
+	return func(c *gin.Context) {
+		if h.Public || tt.users == nil {
+			c.Next()
+			return
+		}
+
+		user, ok := tt.currentUser(c)
+		if !ok {
+			tt.unauthorized(c)
+			return
+		}
+		c.Set(userKey, user)
+
+		if user.Role < need {
+			msg := "Недостаточно прав: нужна роль " + need.String()
+			if wantsHTML(c) {
+				c.HTML(http.StatusForbidden, "tmpl.html", gin.H{
+					"title":    "Конструктор турниров",
+					"subtitle": msg,
+					"page":     "",
+				})
+			} else {
+				c.JSON(http.StatusForbidden, gin.H{"result": false, "error": msg})
+			}
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// currentUser пользователь по cookie сессии или по HTTP basic.
+// Пользователь перечитывается из файла, чтобы удаление и смена роли действовали сразу
+func (tt *TimetableAPI) currentUser(c *gin.Context) (auth.User, bool) {
+	if token, err := c.Cookie(SessionCookie); err == nil {
+		if name, ok := tt.sessions.Get(token); ok {
+			return tt.users.Get(name)
+		}
+	}
+	if name, password, ok := c.Request.BasicAuth(); ok && tt.basicAuth {
+		user, err := tt.users.Authenticate(name, password)
+		return user, err == nil
+	}
+	return auth.User{}, false
+}
+
+func (tt *TimetableAPI) unauthorized(c *gin.Context) {
+	if wantsHTML(c) {
+		c.Redirect(http.StatusSeeOther, "/login?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+		c.Abort()
+		return
+	}
+	// окно basic в браузере на ajax-запросы не показываем
+	if tt.basicAuth && c.GetHeader("X-Requested-With") == "" {
+		c.Header("WWW-Authenticate", `Basic realm="timetable", charset="UTF-8"`)
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"result": false, "error": "Нужно войти"})
+	c.Abort()
+}
+
+// wantsHTML страницу открывают в браузере, а не запрашивают скриптом
+func wantsHTML(c *gin.Context) bool {
+	return c.Request.Method == http.MethodGet && strings.Contains(c.GetHeader("Accept"), "text/html")
+}
+
+// loginPage ...
+func (tt *TimetableAPI) loginPage(c *gin.Context) {
+	tt.renderLogin(c, http.StatusOK, c.Query("next"), "", "")
+}
+
+func (tt *TimetableAPI) renderLogin(c *gin.Context, code int, next, name, errMsg string) {
+	body := tt.renderTemplate(loginTmpl, map[string]interface{}{
+		"next":  next,
+		"name":  name,
+		"error": errMsg,
+	})
+
+	c.HTML(code, "tmpl.html", gin.H{
+		"title":    "Конструктор турниров",
+		"subtitle": "Вход",
+		"body":     template.HTML(body),
+		"page":     "login",
+	})
+}
+
+// authLogin проверить пароль из формы входа и открыть сессию
+func (tt *TimetableAPI) authLogin(c *gin.Context) {
+	next := c.PostForm("next")
+	// переходим только внутри сайта
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") {
+		next = "/"
+	}
+	if tt.users == nil {
+		c.Redirect(http.StatusSeeOther, next)
+		return
+	}
+
+	name := c.PostForm("name")
+	user, err := tt.users.Authenticate(name, c.PostForm("password"))
+	if err != nil {
+		tt.renderLogin(c, http.StatusUnauthorized, next, name, err.Error())
+		return
+	}
+	token, err := tt.sessions.Create(user.Name)
+	if err != nil {
+		tt.renderLogin(c, http.StatusInternalServerError, next, name, err.Error())
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(SessionCookie, token, int(tt.sessions.TTL().Seconds()), "/", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusSeeOther, next)
+}
+
+// authLogout закрыть сессию
+func (tt *TimetableAPI) authLogout(c *gin.Context) {
+	if token, err := c.Cookie(SessionCookie); err == nil && tt.sessions != nil {
+		tt.sessions.Delete(token)
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(SessionCookie, "", -1, "/", "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusSeeOther, "/login")
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/sergrom/timetable/internal/services/auth"
+)
+
+// login войти через форму и вернуть cookie сессии
+func (ts *testServer) login(name, password string) *http.Cookie {
+	ts.t.Helper()
+	body, h := form(url.Values{"name": {name}, "password": {password}, "next": {"/"}})
+	w := ts.do(http.MethodPost, "/auth-login", body, h)
+	if w.Code != http.StatusSeeOther {
+		ts.t.Fatalf("login %s: code %d", name, w.Code)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == SessionCookie {
+			return c
+		}
+	}
+	ts.t.Fatalf("login %s: no session cookie", name)
+	return nil
+}
+
+func authServer(t *testing.T) *testServer {
+	cfg := testConfig(t)
+	cfg.Auth.Basic = true
+	users := auth.NewStore(filepath.Join(cfg.Server.DataDir, auth.UsersFile))
+	for name, role := range map[string]auth.Role{"viewer": auth.RoleViewer, "planner": auth.RolePlanner, "admin": auth.RoleAdmin} {
+		if err := users.Save(name, name+"-secret", role); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return newTestServer(t, cfg)
+}
+
+func TestAuthorizeRoles(t *testing.T) {
+	ts := authServer(t)
+	cookies := map[string]*http.Cookie{
+		"viewer":  ts.login("viewer", "viewer-secret"),
+		"planner": ts.login("planner", "planner-secret"),
+		"admin":   ts.login("admin", "admin-secret"),
+	}
+
+	tests := []struct {
+		method, route string
+		user          string
+		wantCode      int
+	}{
+		{method: http.MethodGet, route: "/status", wantCode: http.StatusUnauthorized},
+		{method: http.MethodGet, route: "/status", user: "viewer", wantCode: http.StatusOK},
+		{method: http.MethodPost, route: "/search-stop", user: "viewer", wantCode: http.StatusForbidden},
+		{method: http.MethodPost, route: "/search-stop", user: "planner", wantCode: http.StatusOK},
+		{method: http.MethodPost, route: "/del-entity", user: "planner", wantCode: http.StatusForbidden},
+		{method: http.MethodPost, route: "/search-stop", user: "admin", wantCode: http.StatusOK},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.route+" as "+tc.user, func(t *testing.T) {
+			prepare := []func(r *http.Request){ajax}
+			if tc.user != "" {
+				prepare = append(prepare, withCookie(cookies[tc.user]))
+			}
+			if w := ts.do(tc.method, tc.route, "", prepare...); w.Code != tc.wantCode {
+				t.Errorf("code %d, want %d: %s", w.Code, tc.wantCode, w.Body)
+			}
+		})
+	}
+
+	// админ проходит проверку роли, дальше отвечает сам обработчик
+	w := ts.do(http.MethodPost, "/del-entity", "", ajax, withCookie(cookies["admin"]))
+	if w.Code == http.StatusUnauthorized || w.Code == http.StatusForbidden {
+		t.Errorf("admin: code %d", w.Code)
+	}
+}
+
+func TestLoginFlow(t *testing.T) {
+	ts := authServer(t)
+
+	// страница в браузере без входа уводит на форму входа и обратно
+	w := ts.do(http.MethodGet, "/runs", "", func(r *http.Request) { r.Header.Set("Accept", "text/html") })
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/login?next=%2Fruns" {
+		t.Fatalf("code %d, location %q", w.Code, w.Header().Get("Location"))
+	}
+	if w := ts.do(http.MethodGet, "/login?next=/runs", ""); w.Code != http.StatusOK {
+		t.Fatalf("login page: code %d", w.Code)
+	}
+
+	body, h := form(url.Values{"name": {"viewer"}, "password": {"wrong"}, "next": {"/runs"}})
+	if w := ts.do(http.MethodPost, "/auth-login", body, h); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: code %d", w.Code)
+	}
+
+	for next, want := range map[string]string{"/runs": "/runs", "//evil.example": "/", "https://evil.example": "/"} {
+		body, h := form(url.Values{"name": {"viewer"}, "password": {"viewer-secret"}, "next": {next}})
+		w := ts.do(http.MethodPost, "/auth-login", body, h)
+		if w.Code != http.StatusSeeOther || w.Header().Get("Location") != want {
+			t.Errorf("next %q: code %d, location %q, want %q", next, w.Code, w.Header().Get("Location"), want)
+		}
+	}
+
+	// после выхода сессия больше не действует
+	cookie := ts.login("viewer", "viewer-secret")
+	if w := ts.do(http.MethodGet, "/status", "", ajax, withCookie(cookie)); w.Code != http.StatusOK {
+		t.Fatalf("status: code %d", w.Code)
+	}
+	ts.do(http.MethodPost, "/auth-logout", "", withCookie(cookie))
+	if w := ts.do(http.MethodGet, "/status", "", ajax, withCookie(cookie)); w.Code != http.StatusUnauthorized {
+		t.Errorf("after logout: code %d", w.Code)
+	}
+}
+
+func TestBasicAuth(t *testing.T) {
+	ts := authServer(t)
+	basic := func(name, password string) func(r *http.Request) {
+		return func(r *http.Request) { r.SetBasicAuth(name, password) }
+	}
+
+	if w := ts.do(http.MethodGet, "/status", ""); w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("script without credentials: code %d, challenge %q", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+	if w := ts.do(http.MethodGet, "/status", "", basic("viewer", "wrong")); w.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: code %d", w.Code)
+	}
+	if w := ts.do(http.MethodGet, "/status", "", basic("viewer", "viewer-secret")); w.Code != http.StatusOK {
+		t.Errorf("viewer: code %d", w.Code)
+	}
+	if w := ts.do(http.MethodPost, "/search-stop", "", basic("viewer", "viewer-secret")); w.Code != http.StatusForbidden {
+		t.Errorf("viewer stops search: code %d", w.Code)
+	}
+}
+
+func TestAuthDisabled(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Auth.Enabled = false
+	ts := newTestServer(t, cfg)
+
+	if w := ts.do(http.MethodPost, "/search-stop", "", ajax); w.Code != http.StatusOK {
+		t.Errorf("code %d", w.Code)
+	}
+}
@@ -6,12 +6,13 @@ import (
 	"html/template"
 	"log"
 	"net/http"
-	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sergrom/timetable/internal/api/req"
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/pkg"
 	"github.com/sergrom/timetable/internal/repository"
 	"github.com/xuri/excelize/v2"
 )
@@ -20,6 +21,7 @@ var (
 	coachesTmpl, _ = template.New(`coachesTemplate`).Parse(`
 	<div class="bttns-top-panel">
 		<div class="pull-right">
+			<a href="/history?table=coaches" class="btn btn-sm btn-secondary" title="Версии таблицы"><i class="fa fa-history" aria-hidden="true"></i> История</a>
 			<button id="AddEntity" data-tag="coach" data-id="-1" type="button" class="btn btn-sm btn-success"><i class="fa fa-plus" aria-hidden="true"></i> Добавить</button>
 		</div>
 		<div class="clearfix"></div>
@@ -29,17 +31,26 @@ var (
 	  <tr>
 		<th scope="col">#ID</th>
 		<th scope="col">Имя</th>
+		<th scope="col">Приезжает</th>
+		<th scope="col">Уезжает</th>
+		<th scope="col">Игр подряд</th>
+		<th scope="col">Перерыв (мин.)</th>
 		<th scope="col"></th>
 	  </tr>
 	</thead>
 	<tbody>
 	  {{range $key, $coach := .coaches }}
 	  <tr>
-		<td scope="row">{{ $coach.ID }}</td>
-		<td>{{ $coach.Name }}</td>
+		<td scope="row">{{ index $coach 0 }}</td>
+		<td>{{ index $coach 1 }}</td>
+		<td>{{ index $coach 2 }}</td>
+		<td>{{ index $coach 3 }}</td>
+		<td>{{ index $coach 4 }}</td>
+		<td>{{ index $coach 5 }}</td>
 		<td style="text-align:right">
-			<button data-tag="coach" data-id="{{ $coach.ID }}" type="button" class="edit-btn btn btn-sm btn-info"><i class="fa fa-pencil" aria-hidden="true"></i></button>
-			<button data-tag="coach" data-id="{{ $coach.ID }}" type="button" class="del-btn btn btn-sm btn-danger"><i class="fa fa-times" aria-hidden="true"></i></button>
+			<a href="/history?table=coaches&id={{ index $coach 0 }}" class="btn btn-sm btn-secondary" title="История записи"><i class="fa fa-history" aria-hidden="true"></i></a>
+			<button data-tag="coach" data-id="{{ index $coach 0 }}" type="button" class="edit-btn btn btn-sm btn-info"><i class="fa fa-pencil" aria-hidden="true"></i></button>
+			<button data-tag="coach" data-id="{{ index $coach 0 }}" type="button" class="del-btn btn btn-sm btn-danger"><i class="fa fa-times" aria-hidden="true"></i></button>
 		</td>
 	  </tr>
 	  {{end}}
@@ -58,8 +69,12 @@ func (tt *TimetableAPI) coaches(c *gin.Context) {
 		log.Println(err.Error())
 		errs = append(errs, err.Error())
 	} else {
+		coachesData := make([][]string, 0, len(coaches))
+		for _, c := range coaches {
+			coachesData = append(coachesData, coachRow(c))
+		}
 		body = tt.renderTemplate(coachesTmpl, map[string]interface{}{
-			"coaches": coaches,
+			"coaches": coachesData,
 		})
 	}
 
@@ -104,20 +119,24 @@ func (tt *TimetableAPI) delCoach(id int) error {
 		return err
 	}
 
-	f.SetCellStr("Sheet1", "A1", "ID")
-	f.SetCellStr("Sheet1", "B1", "Имя")
+	setCoachesHeader(f)
 
 	for i, c := range coaches {
 		if c.ID == id {
 			continue
 		}
+		row := coachRow(c)
 		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), c.ID)
 		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), c.Name)
+		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), row[2])
+		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), row[3])
+		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), row[4])
+		f.SetCellValue("Sheet1", fmt.Sprintf("F%d", i+2), row[5])
 	}
 
 	f.SetActiveSheet(index)
 
-	return f.SaveAs(filepath.Join(repository.DataDir, repository.CoachesFile))
+	return tt.repo.SaveFile(f, repository.CoachesFile)
 }
 
 func (tt *TimetableAPI) saveCoach(msg req.SaveCoachRequest) error {
@@ -154,8 +173,7 @@ func (tt *TimetableAPI) saveCoach(msg req.SaveCoachRequest) error {
 		return err
 	}
 
-	f.SetCellStr("Sheet1", "A1", "ID")
-	f.SetCellStr("Sheet1", "B1", "Имя")
+	setCoachesHeader(f)
 
 	maxID := 0
 	for i, coach := range coaches {
@@ -165,22 +183,35 @@ func (tt *TimetableAPI) saveCoach(msg req.SaveCoachRequest) error {
 		if coach.ID == coachID {
 			f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), msg.ID)
 			f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), msg.Name)
+			f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), msg.TimeFrom)
+			f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), msg.TimeTo)
+			f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), msg.MaxConsecutive)
+			f.SetCellValue("Sheet1", fmt.Sprintf("F%d", i+2), msg.BreakDur)
 			continue
 		}
 
+		row := coachRow(coach)
 		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), coach.ID)
 		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), coach.Name)
+		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), row[2])
+		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), row[3])
+		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), row[4])
+		f.SetCellValue("Sheet1", fmt.Sprintf("F%d", i+2), row[5])
 	}
 
 	if coachID == -1 {
 		idx := len(coaches) + 2
 		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", idx), maxID+1)
 		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", idx), msg.Name)
+		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", idx), msg.TimeFrom)
+		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", idx), msg.TimeTo)
+		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", idx), msg.MaxConsecutive)
+		f.SetCellValue("Sheet1", fmt.Sprintf("F%d", idx), msg.BreakDur)
 	}
 
 	f.SetActiveSheet(index)
 
-	return f.SaveAs(filepath.Join(repository.DataDir, repository.CoachesFile))
+	return tt.repo.SaveFile(f, repository.CoachesFile)
 }
 
 func (tt *TimetableAPI) validateCoach(msg req.SaveCoachRequest) error {
@@ -196,9 +227,65 @@ func (tt *TimetableAPI) validateCoach(msg req.SaveCoachRequest) error {
 		return errors.New("empty Name")
 	}
 
+	if msg.TimeFrom != "" && !pkg.ValidateTime(msg.TimeFrom) {
+		return errors.New("TimeFrom incorrect")
+	}
+	if msg.TimeTo != "" && !pkg.ValidateTime(msg.TimeTo) {
+		return errors.New("TimeTo incorrect")
+	}
+	if msg.TimeFrom != "" && msg.TimeTo != "" && msg.TimeFrom >= msg.TimeTo {
+		return errors.New("Время приезда должно быть раньше времени отъезда")
+	}
+
+	if msg.MaxConsecutive != "" {
+		maxConsecutive, err := strconv.Atoi(msg.MaxConsecutive)
+		if err != nil {
+			return err
+		}
+		if maxConsecutive < 0 {
+			return errors.New("MaxConsecutive incorrect")
+		}
+	}
+	if msg.BreakDur != "" {
+		breakDur, err := strconv.Atoi(msg.BreakDur)
+		if err != nil {
+			return err
+		}
+		if breakDur < 0 {
+			return errors.New("BreakDur incorrect")
+		}
+	}
+
 	return nil
 }
 
+func setCoachesHeader(f *excelize.File) {
+	f.SetCellStr("Sheet1", "A1", "ID")
+	f.SetCellStr("Sheet1", "B1", "Имя")
+	f.SetCellStr("Sheet1", "C1", "Приезжает")
+	f.SetCellStr("Sheet1", "D1", "Уезжает")
+	f.SetCellStr("Sheet1", "E1", "Игр подряд (макс.)")
+	f.SetCellStr("Sheet1", "F1", "Перерыв (мин.)")
+}
+
+// coachRow тренер в виде строки таблицы, пустые значения - без ограничений
+func coachRow(c ds.Coach) []string {
+	from, to, maxConsecutive, breakDur := "", "", "", ""
+	if !c.TimeFrom.IsZero() {
+		from = c.TimeFrom.Format("15:04")
+	}
+	if !c.TimeTo.IsZero() {
+		to = c.TimeTo.Format("15:04")
+	}
+	if c.MaxConsecutive > 0 {
+		maxConsecutive = strconv.Itoa(c.MaxConsecutive)
+	}
+	if c.BreakDur > 0 {
+		breakDur = strconv.Itoa(int(c.BreakDur.Minutes()))
+	}
+	return []string{strconv.Itoa(c.ID), c.Name, from, to, maxConsecutive, breakDur}
+}
+
 // func (tt *TimetableAPI) coachesDownload(c *gin.Context) {
 // 	coaches, err := tt.repo.GetCoaches()
 // 	if err != nil {
@@ -6,12 +6,12 @@ import (
 	"html/template"
 	"log"
 	"net/http"
-	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sergrom/timetable/internal/api/req"
+	"github.com/sergrom/timetable/internal/pkg"
 	"github.com/sergrom/timetable/internal/repository"
 	"github.com/xuri/excelize/v2"
 )
@@ -20,6 +20,7 @@ var (
 	divisionsTmpl, _ = template.New(`dividionsTemplate`).Parse(`
 	<div class="bttns-top-panel">
 		<div class="pull-right">
+			<a href="/history?table=divisions" class="btn btn-sm btn-secondary" title="Версии таблицы"><i class="fa fa-history" aria-hidden="true"></i> История</a>
 			<button id="AddEntity" data-tag="division" data-id="-1" type="button" class="btn btn-sm btn-success"><i class="fa fa-plus" aria-hidden="true"></i> Добавить</button>
 		</div>
 		<div class="clearfix"></div>
@@ -30,23 +31,33 @@ var (
 		<th scope="col">#ID</th>
 		<th scope="col">Название</th>
 		<th scope="col">Формат</th>
+		<th scope="col">Играет с</th>
 		<th scope="col"></th>
 	  </tr>
 	</thead>
 	<tbody>
 	  {{range $key, $div := .divisions }}
 	  <tr>
-		<td scope="row">{{ $div.ID }}</td>
-		<td>{{ $div.Name }}</td>
-		<td>{{ $div.Format }}</td>
+		<td scope="row">{{ index $div 0 }}</td>
+		<td>{{ index $div 1 }}</td>
+		<td>{{ index $div 2 }}</td>
+		<td>{{ index $div 3 }}</td>
 		<td style="text-align:right">
-			<button data-tag="division" data-id="{{ $div.ID }}" type="button" class="edit-btn btn btn-sm btn-info"><i class="fa fa-pencil" aria-hidden="true"></i></button>
-			<button data-tag="division" data-id="{{ $div.ID }}" type="button" class="del-btn btn btn-sm btn-danger"><i class="fa fa-times" aria-hidden="true"></i></button>
+			<a href="/history?table=divisions&id={{ index $div 0 }}" class="btn btn-sm btn-secondary" title="История записи"><i class="fa fa-history" aria-hidden="true"></i></a>
+			<button data-tag="division" data-id="{{ index $div 0 }}" data-plays-with="{{ index $div 4 }}" type="button" class="edit-btn btn btn-sm btn-info"><i class="fa fa-pencil" aria-hidden="true"></i></button>
+			<button data-tag="division" data-id="{{ index $div 0 }}" type="button" class="del-btn btn btn-sm btn-danger"><i class="fa fa-times" aria-hidden="true"></i></button>
 		</td>
 	  </tr>
 	  {{end}}
 	</tbody>
   </table>
+	<script>
+		var Divisions = {
+			{{range $key, $div := .divisions }}
+			{{index $div 0}}: "{{index $div 1}}",
+			{{end}}
+		};
+	</script>
 `)
 )
 
@@ -60,8 +71,22 @@ func (tt *TimetableAPI) divisions(c *gin.Context) {
 		log.Println(err.Error())
 		errs = append(errs, err.Error())
 	} else {
+		divNames := make(map[int]string, len(divs))
+		for _, d := range divs {
+			divNames[d.ID] = d.Name
+		}
+		divsData := make([][]string, 0, len(divs))
+		for _, d := range divs {
+			names := make([]string, 0, len(d.PlaysWith))
+			for _, id := range d.PlaysWith {
+				if name, ok := divNames[id]; ok {
+					names = append(names, name)
+				}
+			}
+			divsData = append(divsData, []string{strconv.Itoa(d.ID), d.Name, strconv.Itoa(d.Format), strings.Join(names, ", "), pkg.JoinInts(d.PlaysWith, ",")})
+		}
 		body = tt.renderTemplate(divisionsTmpl, map[string]interface{}{
-			"divisions": divs,
+			"divisions": divsData,
 		})
 	}
 
@@ -110,19 +135,27 @@ func (tt *TimetableAPI) delDivision(id int) error {
 	f.SetCellStr("Sheet1", "A1", "ID")
 	f.SetCellStr("Sheet1", "B1", "Дивизион")
 	f.SetCellStr("Sheet1", "C1", "Формат")
+	f.SetCellStr("Sheet1", "D1", "Играет с (ID дивизионов)")
 
 	for i, div := range divs {
 		if div.ID == id {
 			continue
 		}
+		playsWith := make([]int, 0, len(div.PlaysWith))
+		for _, dID := range div.PlaysWith {
+			if dID != id {
+				playsWith = append(playsWith, dID)
+			}
+		}
 		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), div.ID)
 		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), div.Name)
 		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), div.Format)
+		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), pkg.JoinInts(playsWith, ","))
 	}
 
 	f.SetActiveSheet(index)
 
-	return f.SaveAs(filepath.Join(repository.DataDir, repository.DivisionsFile))
+	return tt.repo.SaveFile(f, repository.DivisionsFile)
 }
 
 func (tt *TimetableAPI) saveDivision(msg req.SaveDivisionRequest) error {
@@ -162,6 +195,7 @@ func (tt *TimetableAPI) saveDivision(msg req.SaveDivisionRequest) error {
 	f.SetCellStr("Sheet1", "A1", "ID")
 	f.SetCellStr("Sheet1", "B1", "Дивизион")
 	f.SetCellStr("Sheet1", "C1", "Формат")
+	f.SetCellStr("Sheet1", "D1", "Играет с (ID дивизионов)")
 
 	maxID := 0
 	for i, div := range divs {
@@ -172,12 +206,14 @@ func (tt *TimetableAPI) saveDivision(msg req.SaveDivisionRequest) error {
 			f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), msg.ID)
 			f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), msg.Name)
 			f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), msg.Format)
+			f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), strings.Join(msg.PlaysWith, ","))
 			continue
 		}
 
 		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), div.ID)
 		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), div.Name)
 		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), div.Format)
+		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), pkg.JoinInts(div.PlaysWith, ","))
 	}
 
 	if divID == -1 {
@@ -185,11 +221,12 @@ func (tt *TimetableAPI) saveDivision(msg req.SaveDivisionRequest) error {
 		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", idx), maxID+1)
 		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", idx), msg.Name)
 		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", idx), msg.Format)
+		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", idx), strings.Join(msg.PlaysWith, ","))
 	}
 
 	f.SetActiveSheet(index)
 
-	return f.SaveAs(filepath.Join(repository.DataDir, repository.DivisionsFile))
+	return tt.repo.SaveFile(f, repository.DivisionsFile)
 }
 
 func (tt *TimetableAPI) validateDivision(msg req.SaveDivisionRequest) error {
@@ -213,6 +250,16 @@ func (tt *TimetableAPI) validateDivision(msg req.SaveDivisionRequest) error {
 		return errors.New("Формат должен быть от 3 до 7")
 	}
 
+	for _, dIDStr := range msg.PlaysWith {
+		dID, err := strconv.Atoi(dIDStr)
+		if err != nil {
+			return err
+		}
+		if dID == id {
+			return errors.New("Дивизион не может играть сам с собой")
+		}
+	}
+
 	return nil
 }
 
@@ -6,11 +6,11 @@ import (
 	"html/template"
 	"log"
 	"net/http"
-	"path/filepath"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sergrom/timetable/internal/api/req"
+	"github.com/sergrom/timetable/internal/ds"
 	"github.com/sergrom/timetable/internal/repository"
 	"github.com/xuri/excelize/v2"
 )
@@ -25,7 +25,18 @@ var (
 		};
 	</script>
 	<div class="bttns-top-panel">
+		{{if .tours }}
+		<div class="pull-left form-inline">
+			<select id="UnpublishTourName" class="form-control form-control-sm">
+				{{range $i, $tour := .tours }}
+				<option value="{{$tour}}">{{$tour}}</option>
+				{{end}}
+			</select>
+			<button id="UnpublishTour" type="button" class="btn btn-sm btn-warning" style="margin-left:5px" title="Удалить все игры тура, например опубликованного по ошибке">Удалить тур</button>
+		</div>
+		{{end}}
 		<div class="pull-right">
+			<a href="/history?table=games" class="btn btn-sm btn-secondary" title="Версии таблицы"><i class="fa fa-history" aria-hidden="true"></i> История</a>
 			<button id="AddEntity" data-tag="game" data-id="-1" type="button" class="btn btn-sm btn-success"><i class="fa fa-plus" aria-hidden="true"></i> Добавить</button>
 		</div>
 		<div class="clearfix"></div>
@@ -48,6 +59,7 @@ var (
 		<td>{{ index $game 3}}</td>
 		<td>{{ index $game 4}}</td>
 		<td style="text-align:right">
+			<a href="/history?table=games&id={{ index $game 0 }}" class="btn btn-sm btn-secondary" title="История записи"><i class="fa fa-history" aria-hidden="true"></i></a>
 			<button data-tag="game" data-id="{{ index $game 0 }}" data-team-id-1="{{ index $game 5}}" data-team-id-2="{{ index $game 6}}" type="button" class="edit-btn btn btn-sm btn-info"><i class="fa fa-pencil" aria-hidden="true"></i></button>
 			<button data-tag="game" data-id="{{ index $game 0 }}" type="button" class="del-btn btn btn-sm btn-danger"><i class="fa fa-times" aria-hidden="true"></i></button>
 		</td>
@@ -82,8 +94,14 @@ func (tt *TimetableAPI) games(c *gin.Context) {
 	}
 	fmt.Println(len(teamsMap), len(teamDivsMap))
 
+	tours := make([]string, 0)
+	tourSeen := make(map[string]bool)
 	gamesData := make([][]string, 0, len(games))
 	for _, g := range games {
+		if !tourSeen[g.Tour] {
+			tourSeen[g.Tour] = true
+			tours = append(tours, g.Tour)
+		}
 		team1, ok := teamsMap[g.TeamID1]
 		if !ok {
 			// todo err
@@ -100,6 +118,7 @@ func (tt *TimetableAPI) games(c *gin.Context) {
 
 	body = tt.renderTemplate(gamesTmpl, map[string]interface{}{
 		"games":    gamesData,
+		"tours":    tours,
 		"teamsMap": teamDivsMap,
 	})
 
@@ -131,26 +150,18 @@ func (tt *TimetableAPI) delGame(id int) error {
 		return err
 	}
 
-	f.SetCellStr("Sheet1", "A1", "ID")
-	f.SetCellStr("Sheet1", "B1", "тур (просто текст)")
-	f.SetCellStr("Sheet1", "C1", "id первой команды")
-	f.SetCellStr("Sheet1", "D1", "id второй команды")
-	f.SetCellStr("Sheet1", "E1", "возможна переиговка (0-нет, 1-да)")
+	setGamesHeader(f)
 
 	for i, game := range games {
 		if game.ID == id {
 			continue
 		}
-		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), game.ID)
-		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), game.Tour)
-		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), game.TeamID1)
-		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), game.TeamID2)
-		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), game.CanRematch)
+		setGameRow(f, i+2, game)
 	}
 
 	f.SetActiveSheet(index)
 
-	return f.SaveAs(filepath.Join(repository.DataDir, repository.GamesFile))
+	return tt.repo.SaveFile(f, repository.GamesFile)
 }
 
 func (tt *TimetableAPI) saveGame(msg req.SaveGameRequest) error {
@@ -185,43 +196,33 @@ func (tt *TimetableAPI) saveGame(msg req.SaveGameRequest) error {
 		return err
 	}
 
-	f.SetCellStr("Sheet1", "A1", "ID")
-	f.SetCellStr("Sheet1", "B1", "тур (просто текст)")
-	f.SetCellStr("Sheet1", "C1", "id первой команды")
-	f.SetCellStr("Sheet1", "D1", "id второй команды")
-	f.SetCellStr("Sheet1", "E1", "возможна переиговка (0-нет, 1-да)")
+	setGamesHeader(f)
 	maxID := 0
 	for i, game := range games {
 		if maxID < game.ID {
 			maxID = game.ID
 		}
 		if game.ID == gameID {
-			f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), msg.ID)
-			f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), msg.Tour)
-			f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), msg.TeamID1)
-			f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), msg.TeamID2)
-			f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), msg.CanRematch)
-			continue
+			// результат игры вносится на странице результатов и здесь не меняется
+			game.Tour = msg.Tour
+			game.TeamID1, _ = strconv.Atoi(msg.TeamID1)
+			game.TeamID2, _ = strconv.Atoi(msg.TeamID2)
+			game.CanRematch, _ = strconv.Atoi(msg.CanRematch)
 		}
-		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), game.ID)
-		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), game.Tour)
-		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), game.TeamID1)
-		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), game.TeamID2)
-		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), game.CanRematch)
+		setGameRow(f, i+2, game)
 	}
 
 	if gameID == -1 {
-		idx := len(games) + 2
-		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", idx), maxID+1)
-		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", idx), msg.Tour)
-		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", idx), msg.TeamID1)
-		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", idx), msg.TeamID2)
-		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", idx), msg.CanRematch)
+		game := ds.Game{ID: maxID + 1, Tour: msg.Tour}
+		game.TeamID1, _ = strconv.Atoi(msg.TeamID1)
+		game.TeamID2, _ = strconv.Atoi(msg.TeamID2)
+		game.CanRematch, _ = strconv.Atoi(msg.CanRematch)
+		setGameRow(f, len(games)+2, game)
 	}
 
 	f.SetActiveSheet(index)
 
-	return f.SaveAs(filepath.Join(repository.DataDir, repository.GamesFile))
+	return tt.repo.SaveFile(f, repository.GamesFile)
 }
 
 func (tt *TimetableAPI) validateGame(msg req.SaveGameRequest) error {
@@ -251,3 +252,29 @@ func (tt *TimetableAPI) validateGame(msg req.SaveGameRequest) error {
 
 	return nil
 }
+
+func setGamesHeader(f *excelize.File) {
+	f.SetCellStr("Sheet1", "A1", "ID")
+	f.SetCellStr("Sheet1", "B1", "тур (просто текст)")
+	f.SetCellStr("Sheet1", "C1", "id первой команды")
+	f.SetCellStr("Sheet1", "D1", "id второй команды")
+	f.SetCellStr("Sheet1", "E1", "возможна переиговка (0-нет, 1-да)")
+	f.SetCellStr("Sheet1", "F1", "голы первой команды")
+	f.SetCellStr("Sheet1", "G1", "голы второй команды")
+	f.SetCellStr("Sheet1", "H1", "статус (0-не сыграна, 1-сыграна, 2-тех. поражение, 3-отменена)")
+	f.SetCellStr("Sheet1", "I1", "дата")
+}
+
+func setGameRow(f *excelize.File, rowIdx int, game ds.Game) {
+	f.SetCellValue("Sheet1", fmt.Sprintf("A%d", rowIdx), game.ID)
+	f.SetCellValue("Sheet1", fmt.Sprintf("B%d", rowIdx), game.Tour)
+	f.SetCellValue("Sheet1", fmt.Sprintf("C%d", rowIdx), game.TeamID1)
+	f.SetCellValue("Sheet1", fmt.Sprintf("D%d", rowIdx), game.TeamID2)
+	f.SetCellValue("Sheet1", fmt.Sprintf("E%d", rowIdx), game.CanRematch)
+	if game.Status != ds.GameScheduled {
+		f.SetCellValue("Sheet1", fmt.Sprintf("F%d", rowIdx), game.Score1)
+		f.SetCellValue("Sheet1", fmt.Sprintf("G%d", rowIdx), game.Score2)
+		f.SetCellValue("Sheet1", fmt.Sprintf("H%d", rowIdx), int(game.Status))
+	}
+	f.SetCellStr("Sheet1", fmt.Sprintf("I%d", rowIdx), game.Date)
+}
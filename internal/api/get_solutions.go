@@ -8,8 +8,8 @@ import (
 
 func (tt *TimetableAPI) getSolutions(c *gin.Context) {
 	sol, att := tt.searcher.GetSolutions()
-	if len(sol) > 5000 {
-		sol = sol[:5000]
+	if len(sol) > tt.maxSolutions {
+		sol = sol[:tt.maxSolutions]
 	}
 
 	c.JSON(http.StatusOK, gin.H{
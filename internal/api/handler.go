@@ -1,9 +1,14 @@
 package api
 
-import "github.com/gin-gonic/gin"
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/sergrom/timetable/internal/services/auth"
+)
 
 // Handler ...
 type Handler struct {
 	Method string
 	Fn     func(*gin.Context)
+	Role   auth.Role // наименьшая роль, которой доступен обработчик, не задана - только администратору
+	Public bool      // доступен без входа
 }
@@ -0,0 +1,286 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sergrom/timetable/internal/repository"
+	"github.com/sergrom/timetable/internal/services/audit"
+	"github.com/sergrom/timetable/internal/services/revisions"
+)
+
+var (
+	historyTmpl, _ = template.New(`historyTemplate`).Parse(`
+	<form method="get" action="/history" class="form-inline mb-3">
+		<select name="table" class="form-control form-control-sm mr-2">
+			{{ range .tables }}<option value="{{ index . 0 }}"{{ if eq (index . 0) $.table }} selected{{ end }}>{{ index . 1 }}</option>{{ end }}
+		</select>
+		<input name="id" class="form-control form-control-sm mr-2" type="text" placeholder="ID записи" value="{{ .id }}" style="width:100px">
+		<button type="submit" class="btn btn-sm btn-info">Показать</button>
+	</form>
+	{{ if not .revisions }}<div class="alert alert-info">Версий еще нет: они появляются, когда таблицу правят на сайте, публикуют тур или составляют план сезона</div>{{ end }}
+	<table class="table table-sm">
+	<thead>
+	  <tr>
+		<th scope="col">Время</th>
+		<th scope="col">Пользователь</th>
+		<th scope="col">Изменения</th>
+		<th scope="col"></th>
+	  </tr>
+	</thead>
+	<tbody>
+	  {{ range .revisions }}
+	  <tr>
+		<td>{{ .Time }}</td>
+		<td>{{ .User }}</td>
+		<td>
+			{{ if .Note }}<div><i>{{ .Note }}</i></div>{{ end }}
+			{{ range .Entries }}
+			<div>{{ if .Action }}{{ .Action }} {{ end }}ID {{ .EntityID }}{{ range .Changes }}; <b>{{ .Column }}</b>: {{ if .Before }}<del>{{ .Before }}</del> → {{ end }}{{ .After }}{{ end }}</div>
+			{{ end }}
+		</td>
+		<td style="text-align:right; white-space:nowrap">
+			{{ if .Restorable }}
+			{{ if $.id }}<button type="button" class="btn btn-sm btn-warning restore-revision" data-table="{{ $.table }}" data-rev="{{ .ID }}" data-id="{{ $.id }}" title="Вернуть запись {{ $.id }} к этой версии, остальные записи не меняются"><i class="fa fa-undo" aria-hidden="true"></i> Запись</button>{{ end }}
+			<button type="button" class="btn btn-sm btn-danger restore-revision" data-table="{{ $.table }}" data-rev="{{ .ID }}" data-id="" title="Вернуть всю таблицу к этой версии"><i class="fa fa-undo" aria-hidden="true"></i> Таблицу</button>
+			{{ end }}
+		</td>
+	  </tr>
+	  {{ end }}
+	</tbody>
+  </table>
+`)
+)
+
+// historyRow версия таблицы для страницы истории
+type historyRow struct {
+	ID, Time, User, Note string
+	Entries              []historyEntry
+	Restorable           bool // последняя версия - это текущая таблица, возвращать к ней нечего
+}
+
+// historyEntry изменение одной записи в версии
+type historyEntry struct {
+	Action   string
+	EntityID string
+	Changes  []audit.Change
+}
+
+// historyPage версии таблицы справочника, а с id - только те, где менялась эта запись
+func (tt *TimetableAPI) historyPage(c *gin.Context) {
+	errs := make([]string, 0)
+	q := c.Request.URL.Query()
+	table, id := q.Get("table"), strings.TrimSpace(q.Get("id"))
+	if table == "" {
+		table = repository.Tables[0].Name
+	}
+
+	t, err := repository.GetTable(table)
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	var list []revisions.Revision
+	if err == nil {
+		if list, err = tt.revisions.List(table); err != nil {
+			log.Println(err.Error())
+			errs = append(errs, err.Error())
+		}
+	}
+
+	rows := make([]historyRow, 0, len(list))
+	for i, rev := range list {
+		// изменения - разница с предыдущей версией, у исходной версии ее нет
+		var prev [][]string
+		if i+1 < len(list) {
+			prev = list[i+1].Rows
+		} else if id == "" {
+			prev = rev.Rows
+		}
+
+		var entries []historyEntry
+		for _, e := range audit.Diff(table, prev, rev.Rows) {
+			if id != "" && e.EntityID != id {
+				continue
+			}
+			entry := historyEntry{Action: audit.ActionLabels[e.Action], EntityID: e.EntityID, Changes: e.Changes()}
+			if prev == nil {
+				entry.Action = "" // исходная версия записи, а не ее добавление
+			}
+			entries = append(entries, entry)
+		}
+		if id != "" && len(entries) == 0 {
+			continue
+		}
+		rows = append(rows, historyRow{
+			ID:         rev.ID,
+			Time:       rev.Time.Local().Format("02.01.2006 15:04:05"),
+			User:       rev.User,
+			Note:       rev.Note,
+			Entries:    entries,
+			Restorable: i > 0,
+		})
+	}
+
+	tables := make([][]string, 0, len(repository.Tables))
+	for _, t := range repository.Tables {
+		tables = append(tables, []string{t.Name, tableLabel(t)})
+	}
+
+	subtitle := "История"
+	if t.Name != "" {
+		subtitle += ": " + tableLabel(t)
+		if id != "" {
+			subtitle += ", запись " + id
+		}
+	}
+
+	body := tt.renderTemplate(historyTmpl, map[string]interface{}{
+		"tables":    tables,
+		"table":     table,
+		"id":        id,
+		"revisions": rows,
+	})
+
+	c.HTML(http.StatusOK, "tmpl.html", gin.H{
+		"title":    "Конструктор турниров",
+		"subtitle": subtitle,
+		"errors":   errs,
+		"body":     template.HTML(body),
+		"page":     "history",
+	})
+}
+
+// historyRestore вернуть таблицу или одну ее запись к сохраненной версии.
+// Возврат - такая же правка, как остальные: попадает в журнал и сохраняется новой версией
+func (tt *TimetableAPI) historyRestore(c *gin.Context) {
+	q := c.Request.URL.Query()
+	table, revID, id := q.Get("table"), q.Get("rev"), q.Get("id")
+
+	err := func() error {
+		t, err := repository.GetTable(table)
+		if err != nil {
+			return err
+		}
+		rev, err := tt.revisions.Get(table, revID)
+		if err != nil {
+			return err
+		}
+
+		before := tt.tableSnapshot(table)
+		rows := rev.Rows
+		note := "возврат к версии от " + rev.Time.Local().Format("02.01.2006 15:04:05")
+		if id != "" {
+			if rows, err = restoreRow(before, rev.Rows, id); err != nil {
+				return err
+			}
+			note = fmt.Sprintf("возврат записи %s к версии от %s", id, rev.Time.Local().Format("02.01.2006 15:04:05"))
+		}
+		if issues := repository.ValidateTable(t, rows); len(issues) > 0 {
+			return errors.New(issues[0].String())
+		}
+
+		if err := tt.repo.WriteTable(t, rows); err != nil {
+			return err
+		}
+		tt.tableChanged(c, table, before, note)
+		return nil
+	}()
+
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"result": false,
+			"error":  err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"result": true,
+	})
+}
+
+// restoreRow текущие строки, в которых запись id взята из старой версии:
+// заменяется, добавляется в конец или удаляется, если в старой версии ее не было
+func restoreRow(cur, old [][]string, id string) ([][]string, error) {
+	oldRow := findRow(old, id)
+	rows := make([][]string, 0, len(cur)+1)
+	found := false
+	for _, row := range cur {
+		if len(row) > 0 && row[0] == id {
+			found = true
+			if oldRow != nil {
+				rows = append(rows, oldRow)
+			}
+			continue
+		}
+		rows = append(rows, row)
+	}
+	switch {
+	case !found && oldRow == nil:
+		return nil, fmt.Errorf("записи %s нет ни в таблице, ни в версии", id)
+	case !found:
+		rows = append(rows, oldRow)
+	}
+	return rows, nil
+}
+
+func findRow(rows [][]string, id string) []string {
+	for _, row := range rows {
+		if len(row) > 0 && row[0] == id {
+			return row
+		}
+	}
+	return nil
+}
+
+// tableSnapshot строки таблицы до изменения, nil - таблицы еще нет
+func (tt *TimetableAPI) tableSnapshot(table string) [][]string {
+	t, err := repository.GetTable(table)
+	if err != nil {
+		return nil
+	}
+	rows, _ := tt.repo.ReadTable(t)
+	return rows
+}
+
+// tableChanged записать в журнал строки таблицы, изменившиеся со снимка before,
+// и сохранить новую версию таблицы
+func (tt *TimetableAPI) tableChanged(c *gin.Context, table string, before [][]string, note string) {
+	t, err := repository.GetTable(table)
+	if err != nil {
+		return
+	}
+	after, err := tt.repo.ReadTable(t)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	if before == nil && len(after) > 0 {
+		before = [][]string{after[0]} // таблицы не было: исходная версия - пустая таблица, к ней тоже можно вернуться
+	}
+	entries := audit.Diff(table, before, after)
+	if len(entries) == 0 {
+		return
+	}
+	tt.auditRecord(c, entries...)
+
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.EntityID)
+	}
+	rev := revisions.Revision{Table: table, Time: time.Now(), User: requestUser(c), Note: note, IDs: ids, Rows: after}
+	if err := tt.revisions.Record(rev, before); err != nil {
+		log.Println(err)
+	}
+}
+
+// tableLabel название таблицы для страниц - имя ее файла
+func tableLabel(t repository.Table) string {
+	return strings.TrimSuffix(t.File, ".xlsx")
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sergrom/timetable/internal/repository"
+	"github.com/sergrom/timetable/internal/services/audit"
+	"github.com/sergrom/timetable/internal/services/revisions"
+)
+
+func TestHistoryRestore(t *testing.T) {
+	header := []string{"ID", "Дивизион", "Формат", "Играет с (ID дивизионов)"}
+	ts := authServer(t)
+	ts.seed(map[string][][]string{
+		"divisions": {header, {"1", "2012", "6"}},
+		"teams":     {{"ID", "Команда", "Тренер", "Дивизион"}, {"1", "Спартак", "1", "1"}},
+	})
+	admin := withCookie(ts.login("admin", "admin-secret"))
+	divisions := func() [][]string {
+		t.Helper()
+		tbl, _ := repository.GetTable("divisions")
+		rows, err := ts.api.repo.ReadTable(tbl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	for _, body := range []string{`{"id":"1","name":"2012 А","format":"6"}`, `{"id":"-1","name":"2013","format":"7"}`} {
+		if ok, msg := ts.postJSON("/save-entity?tag=division", body, admin); !ok {
+			t.Fatal(msg)
+		}
+	}
+	edited := [][]string{header, {"1", "2012 А", "6"}, {"2", "2013", "7"}}
+	if got := divisions(); !reflect.DeepEqual(got, edited) {
+		t.Fatalf("divisions %q", got)
+	}
+
+	// исходная версия и версия после каждой правки
+	revs, err := ts.api.revisions.List("divisions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revs) != 3 {
+		t.Fatalf("revisions %+v", revs)
+	}
+	base := revs[2]
+	if base.Note == "" || !reflect.DeepEqual(base.Rows, [][]string{header, {"1", "2012", "6"}}) {
+		t.Fatalf("base revision %+v", base)
+	}
+
+	// испорченная версия не проходит проверку, таблица не меняется
+	bad := revisions.Revision{Table: "divisions", Time: time.Now(), Rows: [][]string{header, {"1", "2012", "шесть"}}}
+	if err := ts.api.revisions.Record(bad, nil); err != nil {
+		t.Fatal(err)
+	}
+	revs, _ = ts.api.revisions.List("divisions")
+	steps := []struct {
+		name   string
+		target string
+		wantOk bool
+		want   [][]string
+	}{
+		{name: "invalid revision", target: "/history-restore?table=divisions&rev=" + revs[0].ID, want: edited},
+		{name: "invalid row", target: "/history-restore?table=divisions&id=1&rev=" + revs[0].ID, want: edited},
+		{name: "unknown revision", target: "/history-restore?table=divisions&rev=nope", want: edited},
+		{name: "unknown row", target: "/history-restore?table=divisions&id=9&rev=" + base.ID, want: edited},
+		{
+			name:   "one row, others are kept",
+			target: "/history-restore?table=divisions&id=1&rev=" + base.ID,
+			wantOk: true,
+			want:   [][]string{header, {"1", "2012", "6"}, {"2", "2013", "7"}},
+		},
+		{
+			name:   "row created later is removed",
+			target: "/history-restore?table=divisions&id=2&rev=" + base.ID,
+			wantOk: true,
+			want:   [][]string{header, {"1", "2012", "6"}},
+		},
+	}
+	for _, st := range steps {
+		if ok, msg := ts.postJSON(st.target, "", admin); ok != st.wantOk {
+			t.Fatalf("%s: result %v (%s), want %v", st.name, ok, msg, st.wantOk)
+		}
+		if got := divisions(); !reflect.DeepEqual(got, st.want) {
+			t.Fatalf("%s: divisions %q, want %q", st.name, got, st.want)
+		}
+	}
+
+	// возврат - обычная правка: в журнале от имени администратора и новой версией с пометкой
+	entries, err := ts.api.audit.Read(audit.Filter{Entity: "divisions"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 || entries[0].Action != audit.ActionDelete || entries[1].Action != audit.ActionUpdate || entries[0].User != "admin" {
+		t.Errorf("audit %+v", entries)
+	}
+	revs, _ = ts.api.revisions.List("divisions")
+	if revs[0].Note == "" || !reflect.DeepEqual(revs[0].IDs, []string{"2"}) {
+		t.Errorf("last revision %+v", revs[0])
+	}
+
+	// смотреть историю может любой, возвращать - только администратор
+	viewer := withCookie(ts.login("viewer", "viewer-secret"))
+	if w := ts.do(http.MethodGet, "/history?table=divisions&id=1", "", viewer); w.Code != http.StatusOK {
+		t.Errorf("history page: code %d", w.Code)
+	}
+	if w := ts.do(http.MethodPost, "/history-restore?table=divisions&rev="+base.ID, "", ajax, viewer); w.Code != http.StatusForbidden {
+		t.Errorf("viewer restore: code %d", w.Code)
+	}
+}
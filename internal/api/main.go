@@ -9,11 +9,22 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/pkg"
+	"github.com/sergrom/timetable/internal/services/searcher"
 )
 
 var (
-	mainTmpl, _ = template.New(`mainTemplate`).Parse(`
+	mainTmpl, _ = template.New(`mainTemplate`).Funcs(template.FuncMap{
+		"mergesString": ds.FieldMergesString,
+		"breaksString": ds.BreaksString,
+	}).Parse(`
 		<div class="container">
+			{{with .resumeRun }}
+			<div id="ResumeOffer" class="alert alert-info">
+				Поиск «{{.TourName}}» от {{.Started.Format "02.01.2006 15:04"}} {{if .StopReason}}{{.StopReason}}{{else}}{{.StatusLabel}}{{end}}, найдено решений: {{.SolutionsCnt}}.
+				<button type="button" class="btn btn-sm btn-success resume-run" data-run="{{.ID}}"><i class="fa fa-play" aria-hidden="true"></i> Продолжить поиск</button>
+			</div>
+			{{end}}
 			<form>
 				<div class="row" style="padding-bottom:20px">
 					<div class="col-6">
@@ -21,29 +32,69 @@ var (
 							<label>Название тура</label>
 							<input id="TourName" class="form-control form-control-sm" type="text" value="Тур_1">
 						</div>
+						{{if .seasonTours }}
 						<div class="form-group">
-							<label>Стадион</label>
-							<select id="StadID" class="select2 form-control form-control-sm">
-								{{range $i, $stad := .stads }}
-								<option value="{{$stad.ID}}" data-fields="{{$stad.Fields}}" data-format="{{$stad.Format}}" data-from="{{$stad.TimeFrom.Format "15:04"}}" data-to="{{$stad.TimeTo.Format "15:04"}}" data-game-dur="{{$stad.GameDur.Minutes}}" {{ if eq $i 0}}selected{{end}}>{{$stad.Name}}</option>
+							<label title="Играются только пары из плана сезона, команды и дата берутся из плана">Тур сезона</label>
+							<select id="SeasonTour" class="form-control form-control-sm">
+								<option value="" selected>— пары подбираются —</option>
+								{{range $i, $tour := .seasonTours }}
+								<option value="{{$tour.Name}}" data-date="{{$tour.Date}}" data-teams="{{$tour.Teams}}">{{$tour.Name}}{{if $tour.Date}}, {{$tour.Date}}{{end}}</option>
 								{{end}}
 							</select>
 						</div>
-						<div class="form-group">
-							<table id="FieldsTable" class="table table-sm">
-								<thead class="thead-light">
-									<tr>
-										<th scope="col">Поле</th>
-										<th scope="col">Формат</th>
-										<th scope="col" colspan="2">Работает (с/по)</th>
-										<th scope="col">Игра (мин.)</th>
-										<th scope="col"></th>
-										<th scope="col"></th>
-									</tr>
-								</thead>
-								<tbody></tbody>
-							</table>
-							<button id="AddField" type="button" class="btn btn-sm btn-success">＋ добавить</button>
+						{{end}}
+						<div id="Venues">
+							<div class="venue">
+								<div class="form-row">
+									<div class="col-7 form-group">
+										<label>Стадион</label>
+										<select class="select2 v-stad form-control form-control-sm" style="width:100%">
+											{{range $i, $stad := .stads }}
+											<option value="{{$stad.ID}}" data-fields="{{$stad.Fields}}" data-format="{{$stad.Format}}" data-from="{{$stad.TimeFrom.Format "15:04"}}" data-to="{{$stad.TimeTo.Format "15:04"}}" data-game-dur="{{$stad.GameDur.Minutes}}" data-merges="{{mergesString $stad.Merges}}" data-breaks="{{breaksString $stad.Breaks}}" data-buffer="{{$stad.Buffer.Minutes}}" {{ if eq $i 0}}selected{{end}}>{{$stad.Name}}</option>
+											{{end}}
+										</select>
+									</div>
+									<div class="col-4 form-group">
+										<label>Дата</label>
+										<input class="v-date form-control form-control-sm" type="date">
+									</div>
+									<div class="col-1 form-group">
+										<label>&nbsp;</label>
+										<button type="button" class="btn btn-sm btn-warning x-venue-btn" title="Убрать стадион">✕</button>
+									</div>
+								</div>
+								<div class="form-group">
+									<table class="v-fields table table-sm">
+										<thead class="thead-light">
+											<tr>
+												<th scope="col">Поле</th>
+												<th scope="col">Формат</th>
+												<th scope="col" colspan="2">Работает (с/по)</th>
+												<th scope="col">Игра (мин.)</th>
+												<th scope="col">Пересм. (мин.)</th>
+												<th scope="col">Перерывы</th>
+												<th scope="col"></th>
+												<th scope="col"></th>
+											</tr>
+										</thead>
+										<tbody></tbody>
+									</table>
+									<button type="button" class="btn btn-sm btn-success v-add-field">＋ добавить</button>
+								</div>
+								<div class="form-group">
+									<label title="Номера полей стадиона через «+» и формат объединенного поля">Объединения полей</label>
+									<input class="v-merges form-control form-control-sm" type="text" placeholder="1+2:7; 3+4:7">
+								</div>
+							</div>
+						</div>
+						<div class="form-row">
+							<div class="col-6">
+								<button id="AddVenue" type="button" class="btn btn-sm btn-success">＋ стадион / день</button>
+							</div>
+							<div class="col-6">
+								<small title="Сколько времени нужно командам, тренерам и судьям на переезд между стадионами">Переезд между стадионами (мин.)</small>
+								<input id="TravelMin" class="form-control form-control-sm" type="text" value="0">
+							</div>
 						</div>
 					</div>
 					<div class="col-6">
@@ -59,12 +110,57 @@ var (
 								</select>
 							{{end}}
 						</div>
+						{{ if .referees }}
+						<div id="RefereesSelect" class="form-group">
+							<label>Судьи тура</label>
+							<span class="pull-right font-weight-bold">выбрано: <span id="RefereesCnt">_</span></span>
+							<select multiple="multiple" class="select2 form-control form-control-sm" style="width:100%">
+								{{range $i, $ref := .referees }}
+								<option value="{{$ref.ID}}" selected>{{$ref.Name}}</option>
+								{{end}}
+							</select>
+						</div>
+						{{ end }}
 					</div>
 				</div>
 				<div class="row" style="padding-bottom:20px">
 					<div class="col-6">
 						<div class="form-group">
 							<label>Предыдущие игры</label>
+							<div class="form-row">
+								<div class="col-6">
+									<small title="Сколько последних туров учитывать для игр с возможной переигровкой">Штраф за повтор, туров</small>
+									<input id="RematchTours" class="form-control form-control-sm" type="text" value="{{.rematchTours}}">
+								</div>
+								<div class="col-6">
+									<small title="Штраф за повтор встречи из последнего тура, для более ранних туров он уменьшается">Штраф за повтор, вес</small>
+									<input id="RematchWeight" class="form-control form-control-sm" type="text" value="{{.rematchWeight}}">
+								</div>
+							</div>
+							<div class="form-row">
+								<div class="col-6">
+									<small title="Штраф за каждые 100 пунктов разницы в рейтинге соперников">Разница в рейтинге, вес</small>
+									<input id="RatingWeight" class="form-control form-control-sm" type="text" value="{{.ratingWeight}}">
+								</div>
+								<div class="col-6">
+									<small title="Соперники с большей разницей в рейтинге не играют между собой, 0 - без ограничения">Разница в рейтинге, макс.</small>
+									<input id="RatingMaxDiff" class="form-control form-control-sm" type="text" value="0">
+								</div>
+							</div>
+							<div class="form-row">
+								<div class="col-6">
+									<small title="Штраф за игру команд разных дивизионов (см. «Играет с» на странице дивизионов)">Игра между дивизионами, вес</small>
+									<input id="CrossDivWeight" class="form-control form-control-sm" type="text" value="{{.crossDivWeight}}">
+								</div>
+								<div class="col-6">
+									<small title="Для команд с результатами рейтинг считается по очкам в среднем за игру в турнирной таблице или по Эло, команды без результатов остаются со своим рейтингом">Сила команд</small>
+									<select id="RatingSource" class="form-control form-control-sm">
+										<option value="">Рейтинг команды</option>
+										<option value="table">По таблице</option>
+										<option value="elo">Эло по результатам</option>
+									</select>
+								</div>
+							</div>
 							<table id="GamesTable" class="table table-sm">
 								<thead class="thead-light">
 									<tr>
@@ -76,7 +172,7 @@ var (
 								</thead>
 								<tbody>
 									{{range $i, $game := .gamesData }}
-									<tr data-team-id-1="{{index $game 1}}" data-team-id-2="{{index $game 3}}">
+									<tr data-tour="{{index $game 0}}" data-team-id-1="{{index $game 1}}" data-team-id-2="{{index $game 3}}">
 										<th>{{index $game 0}}</th>
 										<td>{{index $game 2}} ({{index $game 6}})</td>
 										<td>{{index $game 4}} ({{index $game 7}})</td>
@@ -94,6 +190,7 @@ var (
 								<thead class="thead-light">
 									<tr>
 										<th scope="col">Команда</th>
+										<th scope="col">Тип</th>
 										<th scope="col" style="width:100px">С</th>
 										<th scope="col" style="width:100px">По</th>
 										<th scope="col" style="width:10px"></th>
@@ -101,8 +198,9 @@ var (
 								</thead>
 								<tbody>
 									{{range $i, $wish := .wishesData }}
-									<tr data-team-id="{{index $wish 0}}">
+									<tr data-team-id="{{index $wish 0}}" data-kind="{{index $wish 5}}" data-weight="{{index $wish 7}}" data-value="{{index $wish 8}}">
 										<th>{{index $wish 1}} ({{index $wish 4}})</th>
+										<td>{{index $wish 6}} {{index $wish 9}}</td>
 										<td><input type="text" class="form-control form-control-sm w-from" value="{{index $wish 2}}"></td>
 										<td><input type="text" class="form-control form-control-sm w-to" value="{{index $wish 3}}"></td>
 										<td><button type="button" class="btn btn-sm btn-warning x-wish-btn">✕</button></td>
@@ -116,6 +214,12 @@ var (
 				<div class="row" style="padding-bottom:20px">
 					<div class="col-12">
 						<button id="GO" type="button" class="btn btn-success btn-lg" style="display:block;width:300px;margin:0 auto;">Пуск</button>
+						<div style="text-align:center;padding-top:10px">
+							<button id="SearchResume" type="button" class="btn btn-sm btn-success" style="display:none" title="Продолжить остановленный поиск с места остановки"><i class="fa fa-play" aria-hidden="true"></i> Продолжить</button>
+							<button id="ProblemDownload" type="button" class="btn btn-sm btn-secondary" title="Скачать задачу текущего поиска или открытого запуска вместе с точкой продолжения, чтобы воспроизвести или продолжить ее позже, в том числе на другой машине"><i class="fa fa-download" aria-hidden="true"></i> Скачать задачу</button>
+							<button id="ProblemUpload" type="button" class="btn btn-sm btn-secondary" title="Запустить поиск по файлу задачи, или продолжить, если в файле есть точка продолжения"><i class="fa fa-upload" aria-hidden="true"></i> Запустить из файла</button>
+							<input id="ProblemFile" type="file" accept=".json,application/json" style="display:none">
+						</div>
 					</div>
 				</div>
 			</form>
@@ -128,6 +232,7 @@ var (
 						<div class="card">
 							<div class="card-header">
 								Найдено <span id="SolCnt">0</span><small>/</small><small id="AttCnt">0</small>
+								<small id="RefRejected" class="text-muted" style="display:none" title="Решения, в которых игры не удалось расписать по судьям с переездами и лимитом игр">, без судей: <span>0</span></small>
 								<button id="LoadSolutions" type="button" class="btn btn-success btn-sm" style="float:right" title="Подгрузить новые">⟳</button>
 							</div>
 							<ul class="list-group">
@@ -163,6 +268,14 @@ func (tt *TimetableAPI) index(c *gin.Context) {
 	if err != nil {
 		errs = append(errs, err.Error())
 	}
+	seasonGames, err := tt.repo.GetSeason()
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
+	referees, err := tt.repo.GetReferees()
+	if err != nil {
+		errs = append(errs, err.Error())
+	}
 
 	teamsByDiv := make(map[string][]ds.Team)
 	teamsByID := make(map[int]ds.Team)
@@ -175,6 +288,11 @@ func (tt *TimetableAPI) index(c *gin.Context) {
 	}
 	sortTeamsByDiv(teamsByDiv)
 
+	teamNames := make(map[int]string, len(teamsByID))
+	for id, t := range teamsByID {
+		teamNames[id] = t.Name
+	}
+
 	wishesData := make([][]string, 0, len(wishes))
 	for _, w := range wishes {
 		t, tOk := teamsByID[w.TeamID]
@@ -188,11 +306,15 @@ func (tt *TimetableAPI) index(c *gin.Context) {
 		if !w.TimeTo.IsZero() {
 			to = w.TimeTo.Format("15:04")
 		}
-		wishesData = append(wishesData, []string{strconv.Itoa(w.TeamID), t.Name, from, to, divsMap[t.DivisionID].Name})
+		wishesData = append(wishesData, []string{strconv.Itoa(w.TeamID), t.Name, from, to, divsMap[t.DivisionID].Name,
+			strconv.Itoa(int(w.Kind)), w.Kind.String(), strconv.Itoa(w.Weight), strconv.Itoa(w.Value), wishValueName(w, teamNames)})
 	}
 
 	gamesData := make([][]string, 0, len(games))
 	for _, g := range games {
+		if g.Status == ds.GameCancelled {
+			continue // отмененная игра не мешает встрече в следующих турах
+		}
 		team1, ok1 := teamsByID[g.TeamID1]
 		team2, ok2 := teamsByID[g.TeamID2]
 		if !ok1 || !ok2 {
@@ -207,10 +329,17 @@ func (tt *TimetableAPI) index(c *gin.Context) {
 	}
 
 	body := tt.renderTemplate(mainTmpl, map[string]interface{}{
-		"teamsByDiv": teamsByDiv,
-		"stads":      stads,
-		"wishesData": wishesData,
-		"gamesData":  gamesData,
+		"seasonTours":    seasonTours(seasonGames),
+		"teamsByDiv":     teamsByDiv,
+		"stads":          stads,
+		"referees":       referees,
+		"wishesData":     wishesData,
+		"gamesData":      gamesData,
+		"rematchTours":   searcher.DefaultRematchTours,
+		"rematchWeight":  searcher.DefaultRematchWeight,
+		"ratingWeight":   searcher.DefaultRatingWeight,
+		"crossDivWeight": searcher.DefaultCrossDivWeight,
+		"resumeRun":      tt.resumeOffer(),
 	})
 
 	c.HTML(http.StatusOK, "tmpl.html", gin.H{
@@ -222,6 +351,33 @@ func (tt *TimetableAPI) index(c *gin.Context) {
 	})
 }
 
+// seasonTour тур плана сезона для выбора на главной странице
+type seasonTour struct {
+	Name  string
+	Date  string
+	Teams string // id команд тура через запятую
+}
+
+// seasonTours туры плана сезона в порядке плана
+func seasonTours(games []ds.SeasonGame) []seasonTour {
+	tours := make([]seasonTour, 0)
+	idx := make(map[string]int)
+	teams := make(map[string][]int)
+	for _, g := range games {
+		i, ok := idx[g.Tour]
+		if !ok {
+			i = len(tours)
+			idx[g.Tour] = i
+			tours = append(tours, seasonTour{Name: g.Tour, Date: g.Date})
+		}
+		teams[g.Tour] = append(teams[g.Tour], g.TeamID1, g.TeamID2)
+	}
+	for i := range tours {
+		tours[i].Teams = pkg.JoinInts(teams[tours[i].Name], ",")
+	}
+	return tours
+}
+
 func sortTeamsByDiv(teamsByDiv map[string][]ds.Team) {
 	for d := range teamsByDiv {
 		sort.Slice(teamsByDiv[d], func(i, j int) bool {
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sergrom/timetable/internal/services/problem"
+	"github.com/sergrom/timetable/internal/services/runs"
+)
+
+// problemDownload файл задачи текущего поиска или сохраненного запуска.
+// Если поиск можно продолжить, в файл попадают точка продолжения и лучшие решения
+func (tt *TimetableAPI) problemDownload(c *gin.Context) {
+	var p problem.Problem
+	if runID := c.Request.URL.Query().Get("run"); runID != "" {
+		run, err := tt.runs.Get(runID)
+		if err != nil || run.Condition == nil {
+			c.Writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		p = problem.FromCondition(run.Condition)
+		if run.Resumable {
+			if p.Checkpoint, err = tt.runs.Checkpoint(run.ID); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+			p.Solutions = run.Solutions
+		}
+	} else {
+		if tt.searcher.Condition == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Поиск еще не запускался"})
+			return
+		}
+		p = problem.FromCondition(tt.searcher.Condition)
+		// во время поиска точка снимается на ходу, поиск при этом продолжается
+		if p.Checkpoint = tt.searcher.Checkpoint(); p.Checkpoint != nil {
+			sols, _ := tt.searcher.GetSolutions()
+			if len(sols) > runs.TopSolutions {
+				sols = sols[:runs.TopSolutions]
+			}
+			p.Solutions = sols
+		}
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=Задача.json")
+	c.Header("Content-Type", "application/json")
+
+	if err := problem.Write(c.Writer, p); err != nil {
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// problemStart запустить поиск по загруженному файлу задачи, а если в нем есть точка продолжения - продолжить его
+func (tt *TimetableAPI) problemStart(c *gin.Context) {
+	p, err := problem.Read(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cond, err := p.Condition()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tt.startSearch(c, cond, p.Checkpoint, p.Solutions)
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestProblemStartBadField(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Auth.Enabled = false
+	ts := newTestServer(t, cfg)
+
+	p := smallProblem(t)
+	p.Fields[1].GameDur = 0 // файл правили руками
+	w := ts.do(http.MethodPost, "/problem-start", problemBody(t, p), ajax)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("code %d, %s", w.Code, w.Body)
+	}
+	if w := ts.do(http.MethodGet, "/status", "", ajax); w.Code != http.StatusOK {
+		t.Errorf("server is broken after a bad problem: code %d", w.Code)
+	}
+}
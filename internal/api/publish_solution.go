@@ -0,0 +1,171 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/repository"
+	"github.com/xuri/excelize/v2"
+)
+
+// DefaultPublishRematch переигровка для опубликованных игр, если не указана
+const DefaultPublishRematch = 0
+
+// publishSolution записать игры решения в предыдущие игры под названием тура.
+// Повторная публикация тура заменяет его игры, а не добавляет их еще раз,
+// пока в туре не внесены результаты.
+func (tt *TimetableAPI) publishSolution(c *gin.Context) {
+	q := c.Request.URL.Query()
+	hash := q.Get("hash")
+	canRematch := DefaultPublishRematch
+	if v := q.Get("can_rematch"); v != "" {
+		canRematch, _ = strconv.Atoi(v)
+	}
+
+	before := tt.tableSnapshot("games")
+	cnt, tourName, err := tt.publishTour(q.Get("run"), hash, canRematch)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"result": false,
+			"error":  err.Error(),
+		})
+		return
+	}
+	tt.tableChanged(c, "games", before, "публикация тура "+tourName)
+
+	c.JSON(http.StatusOK, gin.H{
+		"result": true,
+		"tour":   tourName,
+		"games":  cnt,
+	})
+}
+
+// unpublishTour удалить из предыдущих игр все игры тура, если результатов в нем еще нет
+func (tt *TimetableAPI) unpublishTour(c *gin.Context) {
+	tourName := c.Request.URL.Query().Get("tour")
+
+	before := tt.tableSnapshot("games")
+	err := tt.replaceTourGames(tourName, nil)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"result": false,
+			"error":  err.Error(),
+		})
+		return
+	}
+	tt.tableChanged(c, "games", before, "снятие тура "+tourName)
+
+	c.JSON(http.StatusOK, gin.H{
+		"result": true,
+	})
+}
+
+func (tt *TimetableAPI) publishTour(runID, hash string, canRematch int) (int, string, error) {
+	if canRematch != 0 && canRematch != 1 {
+		return 0, "", errors.New("CanRematch incorrect")
+	}
+
+	sol, tourName, err := tt.findSolution(runID, hash)
+	if err != nil {
+		return 0, "", err
+	}
+
+	games := make([]ds.Game, 0)
+	for _, gg := range sol.Games {
+		for _, g := range gg {
+			if g.IsBreak || g.TeamID1 == 0 || g.TeamID2 == 0 {
+				continue
+			}
+			games = append(games, ds.Game{Tour: tourName, TeamID1: g.TeamID1, TeamID2: g.TeamID2, CanRematch: canRematch, Date: g.Start.Format("2006-01-02")})
+		}
+	}
+
+	return len(games), tourName, tt.replaceTourGames(tourName, games)
+}
+
+// replaceTourGames заменить игры тура в предыдущих играх, без новых игр тур удаляется.
+// Новые игры встают на место старых и получают их ID, остальные игры не меняются.
+// Тур, в котором уже внесены результаты, не заменяется: результаты бы потерялись
+func (tt *TimetableAPI) replaceTourGames(tourName string, tourGames []ds.Game) error {
+	if tourName == "" {
+		return errors.New("Не задано название тура")
+	}
+
+	games, err := tt.repo.GetGames()
+	if err != nil {
+		return err
+	}
+
+	maxID, tourIDs, withResult := 0, make([]int, 0), 0
+	for _, game := range games {
+		if maxID < game.ID {
+			maxID = game.ID
+		}
+		if game.Tour == tourName {
+			tourIDs = append(tourIDs, game.ID)
+			if game.Status != ds.GameScheduled {
+				withResult++
+			}
+		}
+	}
+	if len(tourIDs) == 0 && len(tourGames) == 0 {
+		return fmt.Errorf("Тур %s не найден", tourName)
+	}
+	if withResult > 0 {
+		return fmt.Errorf("В туре %s уже внесены результаты (игр: %d), тур можно изменить только на странице предыдущих игр", tourName, withResult)
+	}
+	for i := range tourGames {
+		if i < len(tourIDs) {
+			tourGames[i].ID = tourIDs[i]
+		} else {
+			maxID++
+			tourGames[i].ID = maxID
+		}
+	}
+
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	// Create a new sheet.
+	index, err := f.NewSheet("Sheet1")
+	if err != nil {
+		return err
+	}
+
+	setGamesHeader(f)
+
+	rowIdx, placed := 2, false
+	for _, game := range games {
+		if game.Tour != tourName {
+			setGameRow(f, rowIdx, game)
+			rowIdx++
+			continue
+		}
+		if placed {
+			continue
+		}
+		for _, g := range tourGames {
+			setGameRow(f, rowIdx, g)
+			rowIdx++
+		}
+		placed = true
+	}
+	if !placed {
+		for _, g := range tourGames {
+			setGameRow(f, rowIdx, g)
+			rowIdx++
+		}
+	}
+
+	f.SetActiveSheet(index)
+
+	return tt.repo.SaveFile(f, repository.GamesFile)
+}
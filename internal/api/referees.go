@@ -0,0 +1,273 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sergrom/timetable/internal/api/req"
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/pkg"
+	"github.com/sergrom/timetable/internal/repository"
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	refereesTmpl, _ = template.New(`refereesTemplate`).Parse(`
+	<div class="bttns-top-panel">
+		<div class="pull-right">
+			<a href="/history?table=referees" class="btn btn-sm btn-secondary" title="Версии таблицы"><i class="fa fa-history" aria-hidden="true"></i> История</a>
+			<button id="AddEntity" data-tag="referee" data-id="-1" type="button" class="btn btn-sm btn-success"><i class="fa fa-plus" aria-hidden="true"></i> Добавить</button>
+		</div>
+		<div class="clearfix"></div>
+	</div>
+	<table class="data-table-powered table table-sm">
+	<thead>
+	  <tr>
+		<th scope="col">#ID</th>
+		<th scope="col">Имя</th>
+		<th scope="col">Форматы</th>
+		<th scope="col">Судит с</th>
+		<th scope="col">Судит по</th>
+		<th scope="col">Игр в день (макс.)</th>
+		<th scope="col"></th>
+	  </tr>
+	</thead>
+	<tbody>
+	  {{range $key, $ref := .referees }}
+	  <tr>
+		<td scope="row">{{ index $ref 0 }}</td>
+		<td>{{ index $ref 1 }}</td>
+		<td>{{ index $ref 2 }}</td>
+		<td>{{ index $ref 3 }}</td>
+		<td>{{ index $ref 4 }}</td>
+		<td>{{ index $ref 5 }}</td>
+		<td style="text-align:right">
+			<a href="/history?table=referees&id={{ index $ref 0 }}" class="btn btn-sm btn-secondary" title="История записи"><i class="fa fa-history" aria-hidden="true"></i></a>
+			<button data-tag="referee" data-id="{{ index $ref 0 }}" type="button" class="edit-btn btn btn-sm btn-info"><i class="fa fa-pencil" aria-hidden="true"></i></button>
+			<button data-tag="referee" data-id="{{ index $ref 0 }}" type="button" class="del-btn btn btn-sm btn-danger"><i class="fa fa-times" aria-hidden="true"></i></button>
+		</td>
+	  </tr>
+	  {{end}}
+	</tbody>
+  </table>
+`)
+)
+
+// referees ...
+func (tt *TimetableAPI) referees(c *gin.Context) {
+	errs := make([]string, 0)
+	body := ""
+
+	refs, err := tt.repo.GetReferees()
+	if err != nil {
+		log.Println(err.Error())
+		errs = append(errs, err.Error())
+	} else {
+		refsData := make([][]string, 0, len(refs))
+		for _, r := range refs {
+			refsData = append(refsData, refereeRow(r))
+		}
+		body = tt.renderTemplate(refereesTmpl, map[string]interface{}{
+			"referees": refsData,
+		})
+	}
+
+	c.HTML(http.StatusOK, "tmpl.html", gin.H{
+		"title":    "Конструктор турниров",
+		"subtitle": "Судьи",
+		"errors":   errs,
+		"body":     template.HTML(body),
+		"page":     "referees",
+	})
+}
+
+func (tt *TimetableAPI) delReferee(id int) error {
+	refs, err := tt.repo.GetReferees()
+	if err != nil {
+		return err
+	}
+
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	// Create a new sheet.
+	index, err := f.NewSheet("Sheet1")
+	if err != nil {
+		return err
+	}
+
+	setRefereesHeader(f)
+
+	for i, ref := range refs {
+		if ref.ID == id {
+			continue
+		}
+		row := refereeRow(ref)
+		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), ref.ID)
+		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), ref.Name)
+		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), row[2])
+		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), row[3])
+		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), row[4])
+		f.SetCellValue("Sheet1", fmt.Sprintf("F%d", i+2), row[5])
+	}
+
+	f.SetActiveSheet(index)
+
+	return tt.repo.SaveFile(f, repository.RefereesFile)
+}
+
+func (tt *TimetableAPI) saveReferee(msg req.SaveRefereeRequest) error {
+	if err := tt.validateReferee(msg); err != nil {
+		return err
+	}
+
+	refID, err := strconv.Atoi(msg.ID)
+	if err != nil {
+		return err
+	}
+
+	refs, err := tt.repo.GetReferees()
+	if err != nil {
+		return err
+	}
+
+	for _, r := range refs {
+		if r.ID != refID && strings.ToLower(msg.Name) == strings.ToLower(r.Name) {
+			return fmt.Errorf("Судья с именем %s уже существует", r.Name)
+		}
+	}
+
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	// Create a new sheet.
+	index, err := f.NewSheet("Sheet1")
+	if err != nil {
+		return err
+	}
+
+	setRefereesHeader(f)
+
+	maxID := 0
+	for i, ref := range refs {
+		if maxID < ref.ID {
+			maxID = ref.ID
+		}
+		if ref.ID == refID {
+			f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), msg.ID)
+			f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), msg.Name)
+			f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), msg.Formats)
+			f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), msg.TimeFrom)
+			f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), msg.TimeTo)
+			f.SetCellValue("Sheet1", fmt.Sprintf("F%d", i+2), msg.MaxGames)
+			continue
+		}
+
+		row := refereeRow(ref)
+		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), ref.ID)
+		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), ref.Name)
+		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), row[2])
+		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), row[3])
+		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), row[4])
+		f.SetCellValue("Sheet1", fmt.Sprintf("F%d", i+2), row[5])
+	}
+
+	if refID == -1 {
+		idx := len(refs) + 2
+		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", idx), maxID+1)
+		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", idx), msg.Name)
+		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", idx), msg.Formats)
+		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", idx), msg.TimeFrom)
+		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", idx), msg.TimeTo)
+		f.SetCellValue("Sheet1", fmt.Sprintf("F%d", idx), msg.MaxGames)
+	}
+
+	f.SetActiveSheet(index)
+
+	return tt.repo.SaveFile(f, repository.RefereesFile)
+}
+
+func (tt *TimetableAPI) validateReferee(msg req.SaveRefereeRequest) error {
+	id, err := strconv.Atoi(msg.ID)
+	if err != nil {
+		return err
+	}
+	if id < 1 && id != -1 {
+		return errors.New("ID incorrect")
+	}
+
+	if len(msg.Name) == 0 {
+		return errors.New("empty Name")
+	}
+
+	for _, fStr := range strings.Split(msg.Formats, ",") {
+		fStr = strings.TrimSpace(fStr)
+		if fStr == "" {
+			continue
+		}
+		format, err := strconv.Atoi(fStr)
+		if err != nil {
+			return err
+		}
+		if format < 3 || format > 11 {
+			return errors.New("Формат должен быть от 3 до 11")
+		}
+	}
+
+	if msg.TimeFrom != "" && !pkg.ValidateTime(msg.TimeFrom) {
+		return errors.New("TimeFrom incorrect")
+	}
+	if msg.TimeTo != "" && !pkg.ValidateTime(msg.TimeTo) {
+		return errors.New("TimeTo incorrect")
+	}
+
+	if msg.MaxGames != "" {
+		maxGames, err := strconv.Atoi(msg.MaxGames)
+		if err != nil {
+			return err
+		}
+		if maxGames < 0 {
+			return errors.New("MaxGames incorrect")
+		}
+	}
+
+	return nil
+}
+
+func setRefereesHeader(f *excelize.File) {
+	f.SetCellStr("Sheet1", "A1", "ID")
+	f.SetCellStr("Sheet1", "B1", "Имя")
+	f.SetCellStr("Sheet1", "C1", "Форматы (через запятую)")
+	f.SetCellStr("Sheet1", "D1", "Судит с")
+	f.SetCellStr("Sheet1", "E1", "Судит по")
+	f.SetCellStr("Sheet1", "F1", "Игр в день (макс.)")
+}
+
+// refereeRow судья в виде строки таблицы, пустые значения - без ограничений
+func refereeRow(r ds.Referee) []string {
+	from, to, maxGames := "", "", ""
+	if !r.TimeFrom.IsZero() {
+		from = r.TimeFrom.Format("15:04")
+	}
+	if !r.TimeTo.IsZero() {
+		to = r.TimeTo.Format("15:04")
+	}
+	if r.MaxGames > 0 {
+		maxGames = strconv.Itoa(r.MaxGames)
+	}
+	return []string{strconv.Itoa(r.ID), r.Name, pkg.JoinInts(r.Formats, ", "), from, to, maxGames}
+}
@@ -1,12 +1,32 @@
 package req
 
 type SearchStartRequest struct {
-	TourName   string  `json:"tour_name"`
-	StaduiumID int     `json:"stadium_id"`
-	Fields     []Field `json:"fields"`
-	Teams      []int   `json:"teams"`
-	Wishes     []Wish  `json:"wishes"`
-	Games      []Game  `json:"games"`
+	TourName       string  `json:"tour_name"`
+	SeasonTour     string  `json:"season_tour"` // тур из плана сезона, пусто - пары подбираются
+	StaduiumID     int     `json:"stadium_id"`
+	Fields         []Field `json:"fields"`
+	Merges         string  `json:"merges"`
+	Venues         []Venue `json:"venues"`
+	TravelMin      int     `json:"travel_min"`
+	Teams          []int   `json:"teams"`
+	Wishes         []Wish  `json:"wishes"`
+	Games          []Game  `json:"games"`
+	RematchTours   int     `json:"rematch_tours"`
+	RematchWeight  int     `json:"rematch_weight"`
+	RatingWeight   int     `json:"rating_weight"`
+	RatingMaxDiff  int     `json:"rating_max_diff"`
+	CrossDivWeight int     `json:"cross_div_weight"`
+	RatingByTable  bool    `json:"rating_by_table"` // то же, что rating_source "table", для старых запросов
+	RatingSource   string  `json:"rating_source"`   // сила команд: пусто - рейтинг команды, "table" - по таблице, "elo" - Эло по результатам
+	RefereeIDs     []int   `json:"referee_ids"`     // судьи тура, без поля - все судьи
+}
+
+// Venue стадион в один из дней тура
+type Venue struct {
+	StadiumID int     `json:"stadium_id"`
+	Date      string  `json:"date"` // 2006-01-02, пусто - сегодня
+	Fields    []Field `json:"fields"`
+	Merges    string  `json:"merges"`
 }
 
 type Field struct {
@@ -14,17 +34,32 @@ type Field struct {
 	From   string `json:"from"`
 	To     string `json:"to"`
 	Dur    int    `json:"dur"`
+	Breaks string `json:"breaks"`
+	Buffer int    `json:"buffer"`
 }
 
 type Wish struct {
 	TeamID int    `json:"team_id"`
+	Kind   int    `json:"kind"`
 	From   string `json:"from"`
 	To     string `json:"to"`
+	Weight int    `json:"weight"`
+	Value  int    `json:"value"`
 }
 
 type Game struct {
-	TeamID1 int `json:"team_id_1"`
-	TeamID2 int `json:"team_id_2"`
+	Tour       string `json:"tour"`
+	TeamID1    int    `json:"team_id_1"`
+	TeamID2    int    `json:"team_id_2"`
+	CanRematch int    `json:"can_rematch"`
+}
+
+// GetVenues стадионы и дни тура, старый запрос с одним стадионом - один стадион сегодня
+func (r SearchStartRequest) GetVenues() []Venue {
+	if len(r.Venues) > 0 {
+		return r.Venues
+	}
+	return []Venue{{StadiumID: r.StaduiumID, Fields: r.Fields, Merges: r.Merges}}
 }
 
 func (r SearchStartRequest) GetTemsMap() map[int]bool {
@@ -35,10 +70,6 @@ func (r SearchStartRequest) GetTemsMap() map[int]bool {
 	return m
 }
 
-type CheckAccessRequest struct {
-	Msg string `json:"msg"`
-}
-
 type SaveStadiumRequest struct {
 	ID       string `json:"id"`
 	Tag      string `json:"tag"`
@@ -48,19 +79,27 @@ type SaveStadiumRequest struct {
 	TimeFrom string `json:"time_from"`
 	TimeTo   string `json:"time_to"`
 	GameDur  string `json:"game_dur"`
+	Merges   string `json:"merges"`
+	Breaks   string `json:"breaks"`
+	Buffer   string `json:"buffer"`
 }
 
 type SaveDivisionRequest struct {
-	ID     string `json:"id"`
-	Tag    string `json:"tag"`
-	Name   string `json:"name"`
-	Format string `json:"format"`
+	ID        string   `json:"id"`
+	Tag       string   `json:"tag"`
+	Name      string   `json:"name"`
+	Format    string   `json:"format"`
+	PlaysWith []string `json:"plays_with"`
 }
 
 type SaveCoachRequest struct {
-	ID   string `json:"id"`
-	Tag  string `json:"tag"`
-	Name string `json:"name"`
+	ID             string `json:"id"`
+	Tag            string `json:"tag"`
+	Name           string `json:"name"`
+	TimeFrom       string `json:"time_from"`
+	TimeTo         string `json:"time_to"`
+	MaxConsecutive string `json:"max_consecutive"`
+	BreakDur       string `json:"break_dur"`
 }
 
 type SaveTeamRequest struct {
@@ -69,13 +108,18 @@ type SaveTeamRequest struct {
 	Name       string `json:"name"`
 	DivisionID string `json:"division_id"`
 	CoachID    string `json:"coach_id"`
+	Rating     string `json:"rating"`
 }
 
 type SaveWishRequest struct {
-	ID       string `json:"id"`
-	TeamID   string `json:"team_id"`
-	TimeFrom string `json:"time_from"`
-	TimeTo   string `json:"time_to"`
+	ID         string `json:"id"`
+	TeamID     string `json:"team_id"`
+	Kind       string `json:"kind"`
+	TimeFrom   string `json:"time_from"`
+	TimeTo     string `json:"time_to"`
+	Weight     string `json:"weight"`
+	Value      string `json:"value"`
+	OpponentID string `json:"opponent_id"`
 }
 
 type SaveGameRequest struct {
@@ -85,3 +129,26 @@ type SaveGameRequest struct {
 	TeamID2    string `json:"team_id_2"`
 	CanRematch string `json:"can_rematch"`
 }
+
+type SaveResultRequest struct {
+	ID     string `json:"id"`
+	Score1 string `json:"score1"`
+	Score2 string `json:"score2"`
+	Status string `json:"status"`
+	Date   string `json:"date"`
+}
+
+type SaveRefereeRequest struct {
+	ID       string `json:"id"`
+	Tag      string `json:"tag"`
+	Name     string `json:"name"`
+	Formats  string `json:"formats"`
+	TimeFrom string `json:"time_from"`
+	TimeTo   string `json:"time_to"`
+	MaxGames string `json:"max_games"`
+}
+
+type SeasonPlanRequest struct {
+	DivisionIDs []int    `json:"division_ids"`
+	Dates       []string `json:"dates"`
+}
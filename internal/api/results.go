@@ -0,0 +1,209 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sergrom/timetable/internal/api/req"
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/pkg"
+	"github.com/sergrom/timetable/internal/repository"
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	resultsTmpl, _ = template.New(`resultsTemplate`).Parse(`
+	<table class="data-table-powered table table-sm">
+	<thead>
+	  <tr>
+		<th scope="col">Тур</th>
+		<th scope="col">Дата</th>
+		<th scope="col">Команда1</th>
+		<th scope="col" class="no-sort" style="width:150px">Счет</th>
+		<th scope="col">Команда2</th>
+		<th scope="col" class="no-sort">Статус</th>
+		<th scope="col" class="no-sort"></th>
+	  </tr>
+	</thead>
+	<tbody>
+	  {{range $key, $game := .games }}
+	  <tr data-id="{{ index $game 0 }}">
+		<td scope="row">{{ index $game 1 }}</td>
+		<td><input type="date" class="r-date form-control form-control-sm" value="{{ index $game 2 }}"></td>
+		<td>{{ index $game 3 }}</td>
+		<td class="form-inline">
+			<input type="text" class="r-score1 form-control form-control-sm" style="width:50px" value="{{ index $game 5 }}">&nbsp;:&nbsp;<input type="text" class="r-score2 form-control form-control-sm" style="width:50px" value="{{ index $game 6 }}">
+		</td>
+		<td>{{ index $game 4 }}</td>
+		<td>
+			<select class="r-status form-control form-control-sm">
+				{{ $cur := index $game 7 }}
+				{{range $st := $.statuses }}
+				<option value="{{ index $st 0 }}" {{ if eq (index $st 0) $cur }}selected{{end}}>{{ index $st 1 }}</option>
+				{{end}}
+			</select>
+		</td>
+		<td style="text-align:right">
+			<button type="button" class="save-result-btn btn btn-sm btn-success"><i class="fa fa-check" aria-hidden="true"></i></button>
+		</td>
+	  </tr>
+	  {{end}}
+	</tbody>
+  </table>
+`)
+)
+
+// results внесение результатов игр
+func (tt *TimetableAPI) results(c *gin.Context) {
+	errs := make([]string, 0)
+	body := ""
+
+	games, err := tt.repo.GetGames()
+	if err != nil {
+		log.Println(err.Error())
+		errs = append(errs, err.Error())
+	}
+	teamsMap, err := tt.repo.GetTeamsMap()
+	if err != nil {
+		log.Println(err.Error())
+		errs = append(errs, err.Error())
+	}
+	divsMap, err := tt.repo.GetDivisionsMap()
+	if err != nil {
+		log.Println(err.Error())
+		errs = append(errs, err.Error())
+	}
+
+	gamesData := make([][]string, 0, len(games))
+	for _, g := range games {
+		team1, ok1 := teamsMap[g.TeamID1]
+		team2, ok2 := teamsMap[g.TeamID2]
+		if !ok1 || !ok2 {
+			continue
+		}
+		score1, score2 := "", ""
+		if g.Status != ds.GameScheduled {
+			score1, score2 = strconv.Itoa(g.Score1), strconv.Itoa(g.Score2)
+		}
+		gamesData = append(gamesData, []string{strconv.Itoa(g.ID), g.Tour, g.Date,
+			fmt.Sprintf("%s (%s)", team1.Name, divsMap[team1.DivisionID].Name),
+			fmt.Sprintf("%s (%s)", team2.Name, divsMap[team2.DivisionID].Name),
+			score1, score2, strconv.Itoa(int(g.Status))})
+	}
+
+	statuses := make([][]string, 0, 4)
+	for st := ds.GameScheduled; st <= ds.GameCancelled; st++ {
+		statuses = append(statuses, []string{strconv.Itoa(int(st)), st.String()})
+	}
+
+	body = tt.renderTemplate(resultsTmpl, map[string]interface{}{
+		"games":    gamesData,
+		"statuses": statuses,
+	})
+
+	c.HTML(http.StatusOK, "tmpl.html", gin.H{
+		"title":    "Конструктор турниров",
+		"subtitle": "Результаты игр",
+		"errors":   errs,
+		"body":     template.HTML(body),
+		"page":     "results",
+	})
+}
+
+// saveResultHandler внести результат игры. Результаты вносят организаторы,
+// поэтому у них отдельный адрес, а не общее сохранение справочников
+func (tt *TimetableAPI) saveResultHandler(c *gin.Context) {
+	var msg req.SaveResultRequest
+	if err := c.BindJSON(&msg); err != nil {
+		c.JSON(http.StatusOK, gin.H{"result": false, "error": err.Error()})
+		return
+	}
+
+	before := tt.tableSnapshot("games")
+	if err := tt.saveResult(msg); err != nil {
+		c.JSON(http.StatusOK, gin.H{"result": false, "error": err.Error()})
+		return
+	}
+	tt.tableChanged(c, "games", before, "")
+
+	c.JSON(http.StatusOK, gin.H{"result": true})
+}
+
+func (tt *TimetableAPI) saveResult(msg req.SaveResultRequest) error {
+	game, err := tt.validateResult(msg)
+	if err != nil {
+		return err
+	}
+
+	games, err := tt.repo.GetGames()
+	if err != nil {
+		return err
+	}
+
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	// Create a new sheet.
+	index, err := f.NewSheet("Sheet1")
+	if err != nil {
+		return err
+	}
+
+	setGamesHeader(f)
+
+	found := false
+	for i, g := range games {
+		if g.ID == game.ID {
+			g.Score1, g.Score2, g.Status, g.Date = game.Score1, game.Score2, game.Status, game.Date
+			found = true
+		}
+		setGameRow(f, i+2, g)
+	}
+	if !found {
+		return fmt.Errorf("Игра %d не найдена", game.ID)
+	}
+
+	f.SetActiveSheet(index)
+
+	return tt.repo.SaveFile(f, repository.GamesFile)
+}
+
+func (tt *TimetableAPI) validateResult(msg req.SaveResultRequest) (ds.Game, error) {
+	var game ds.Game
+	var err error
+
+	if game.ID, err = strconv.Atoi(msg.ID); err != nil || game.ID < 1 {
+		return game, errors.New("ID incorrect")
+	}
+
+	status, err := strconv.Atoi(msg.Status)
+	if err != nil || status < int(ds.GameScheduled) || status > int(ds.GameCancelled) {
+		return game, errors.New("Status incorrect")
+	}
+	game.Status = ds.GameStatus(status)
+
+	if game.HasResult() {
+		if game.Score1, err = strconv.Atoi(msg.Score1); err != nil || game.Score1 < 0 {
+			return game, errors.New("Не указан счет игры")
+		}
+		if game.Score2, err = strconv.Atoi(msg.Score2); err != nil || game.Score2 < 0 {
+			return game, errors.New("Не указан счет игры")
+		}
+	}
+
+	if _, err := pkg.ParseDate(msg.Date); err != nil {
+		return game, errors.New("Date incorrect")
+	}
+	game.Date = msg.Date
+
+	return game, nil
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/services/audit"
+)
+
+func TestSaveResult(t *testing.T) {
+	ts := authServer(t)
+	ts.seed(map[string][][]string{
+		"games": {{"ID", "Тур", "Команда 1", "Команда 2", "Можно переиграть"}, {"1", "Тур 1", "1", "2", "0"}},
+	})
+	result := func(id string, status ds.GameStatus) string {
+		return fmt.Sprintf(`{"id":%q,"score1":"2","score2":"1","status":"%d","date":"2024-05-18"}`, id, status)
+	}
+
+	viewer := withCookie(ts.login("viewer", "viewer-secret"))
+	if w := ts.do(http.MethodPost, "/save-result", result("1", ds.GamePlayed), ajax, viewer); w.Code != http.StatusForbidden {
+		t.Fatalf("viewer: code %d", w.Code)
+	}
+
+	planner := withCookie(ts.login("planner", "planner-secret"))
+	if ok, msg := ts.postJSON("/save-result", result("9", ds.GamePlayed), planner); ok {
+		t.Fatalf("unknown game saved: %s", msg)
+	}
+	if ok, msg := ts.postJSON("/save-result", result("1", ds.GamePlayed), planner); !ok {
+		t.Fatal(msg)
+	}
+
+	games, err := ts.api.repo.GetGames()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g := games[0]; g.Score1 != 2 || g.Score2 != 1 || g.Status != ds.GamePlayed || g.Date != "2024-05-18" {
+		t.Errorf("game %+v", g)
+	}
+	entries, _ := ts.api.audit.Read(audit.Filter{Entity: "games"})
+	if len(entries) != 1 || entries[0].User != "planner" || entries[0].Action != audit.ActionUpdate {
+		t.Errorf("audit %+v", entries)
+	}
+}
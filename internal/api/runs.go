@@ -0,0 +1,300 @@
+package api
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sergrom/timetable/internal/services/audit"
+	"github.com/sergrom/timetable/internal/services/problem"
+	"github.com/sergrom/timetable/internal/services/runs"
+	"github.com/sergrom/timetable/internal/services/searcher"
+)
+
+// RunSaveInterval как часто сохранять идущий поиск, чтобы решения пережили перезапуск сервера
+const RunSaveInterval = 30 * time.Second
+
+var (
+	runsTmpl, _ = template.New(`runsTemplate`).Parse(`
+	<table class="data-table-powered table table-sm">
+	<thead>
+	  <tr>
+		<th scope="col">#ID</th>
+		<th scope="col">Тур</th>
+		<th scope="col">Начат</th>
+		<th scope="col">Завершен</th>
+		<th scope="col">Статус</th>
+		<th scope="col">Решений</th>
+		<th scope="col">Попыток</th>
+		<th scope="col"></th>
+	  </tr>
+	</thead>
+	<tbody>
+	  {{range $key, $run := .runs }}
+	  <tr>
+		<td scope="row">{{ index $run 0 }}</td>
+		<td>{{ index $run 1 }}</td>
+		<td>{{ index $run 2 }}</td>
+		<td>{{ index $run 3 }}</td>
+		<td>{{ index $run 4 }}</td>
+		<td>{{ index $run 5 }}</td>
+		<td>{{ index $run 6 }}</td>
+		<td style="text-align:right">
+			{{ if eq (index $run 7) "1" }}<button type="button" class="btn btn-sm btn-success resume-run" data-run="{{ index $run 0 }}" title="Продолжить поиск с места остановки"><i class="fa fa-play" aria-hidden="true"></i></button>{{ end }}
+			<a href="/?run={{ index $run 0 }}" class="btn btn-sm btn-info" title="Открыть решения запуска"><i class="fa fa-folder-open" aria-hidden="true"></i></a>
+			<a href="/problem-download?run={{ index $run 0 }}" target="_blank" class="btn btn-sm btn-secondary" title="Скачать задачу{{ if eq (index $run 7) "1" }} с точкой продолжения, чтобы продолжить поиск на другой машине{{ end }}"><i class="fa fa-download" aria-hidden="true"></i></a>
+		</td>
+	  </tr>
+	  {{end}}
+	</tbody>
+  </table>
+`)
+)
+
+// runsPage прошлые запуски поиска
+func (tt *TimetableAPI) runsPage(c *gin.Context) {
+	errs := make([]string, 0)
+
+	list, err := tt.runs.List()
+	if err != nil {
+		log.Println(err.Error())
+		errs = append(errs, err.Error())
+	}
+
+	runsData := make([][]string, 0, len(list))
+	for _, r := range list {
+		finished := ""
+		if !r.Finished.IsZero() {
+			finished = r.Finished.Format("02.01.2006 15:04")
+		}
+		status := r.StatusLabel()
+		if r.StopReason != "" {
+			status += ": " + r.StopReason
+		}
+		resumable := ""
+		if r.Resumable {
+			resumable = "1"
+		}
+		runsData = append(runsData, []string{r.ID, r.TourName, r.Started.Format("02.01.2006 15:04"), finished, status,
+			strconv.Itoa(r.SolutionsCnt), strconv.Itoa(r.Attempts), resumable})
+	}
+
+	body := tt.renderTemplate(runsTmpl, map[string]interface{}{
+		"runs": runsData,
+	})
+
+	c.HTML(http.StatusOK, "tmpl.html", gin.H{
+		"title":    "Конструктор турниров",
+		"subtitle": "Запуски поиска",
+		"errors":   errs,
+		"body":     template.HTML(body),
+		"page":     "runs",
+	})
+}
+
+// getRun решения сохраненного запуска в том же виде, что и ответ на запуск поиска
+func (tt *TimetableAPI) getRun(c *gin.Context) {
+	run, err := tt.runs.Get(c.Request.URL.Query().Get("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if run.Condition == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "В запуске " + run.ID + " не сохранилось условие поиска, решения открыть нельзя"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"run_id":    run.ID,
+		"tour_name": run.TourName,
+		"solutions": run.Solutions,
+		"attempts":  run.Attempts,
+		"teams":     run.Condition.TeamsPrettyMap,
+		"referees":  run.Condition.RefereesPrettyMap,
+		"day_start": run.Condition.DayStart,
+		"day_end":   run.Condition.DayEnd,
+	})
+}
+
+// runResume продолжить остановленный запуск с его точки продолжения
+func (tt *TimetableAPI) runResume(c *gin.Context) {
+	tt.resumeRun(c, c.Request.URL.Query().Get("id"))
+}
+
+func (tt *TimetableAPI) resumeRun(c *gin.Context, id string) {
+	run, err := tt.runs.Get(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !run.Resumable || run.Condition == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Этот запуск нельзя продолжить"})
+		return
+	}
+	cp, err := tt.runs.Checkpoint(run.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// в сохраненном условии нет служебных полей, собираем его заново, как из файла задачи
+	cond, err := problem.FromCondition(run.Condition).Condition()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	solutions, att, err := tt.searcher.Resume(cond, cp, run.Solutions)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	run.Status, run.StopReason, run.Finished = runs.StatusProcess, "", time.Time{}
+	run.Condition = cond
+	tt.trackRun(run)
+	tt.auditSearch(c, audit.ActionSearchResume, run.ID, cond.TourName, att)
+
+	tt.searchStarted(c, cond, solutions, att)
+}
+
+// resumeOffer последний запуск, если его можно продолжить, а новый поиск еще не начат
+func (tt *TimetableAPI) resumeOffer() *runs.Run {
+	if tt.searcher.Status() == searcher.StatusInProcess {
+		return nil
+	}
+	list, err := tt.runs.List()
+	if err != nil || len(list) == 0 || !list[0].Resumable {
+		return nil
+	}
+	return &list[0]
+}
+
+func newRun(cond *searcher.Condition) runs.Run {
+	return runs.Run{
+		ID:        runs.NewRunID(time.Now()),
+		TourName:  cond.TourName,
+		Started:   time.Now(),
+		Status:    runs.StatusProcess,
+		Condition: cond,
+	}
+}
+
+// trackRun сохранять запуск и его точку продолжения, пока идет текущий поиск, и после его завершения.
+// Итог берется из результата, снятого самим поиском: к этому времени может идти уже следующий
+func (tt *TimetableAPI) trackRun(run runs.Run) {
+	searchID, result := tt.searcher.Current()
+	tt.runLock.Lock()
+	tt.current = currentRun{searchID: searchID, id: run.ID, tourName: run.TourName}
+	tt.runLock.Unlock()
+
+	tt.tracking.Add(1)
+	go func() {
+		defer tt.tracking.Done()
+
+		ticker := time.NewTicker(RunSaveInterval)
+		defer ticker.Stop()
+
+		for {
+			tt.snapshotRun(&run, searchID)
+			select {
+			case res := <-result:
+				run.Status = runs.StatusStopped
+				run.StopReason = res.Reason
+				run.Finished = time.Now()
+				tt.saveRun(&run, res.Solutions, res.Attempts)
+				tt.saveCheckpoint(run.ID, res.Checkpoint)
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// currentRun запуск поиска searchID
+type currentRun struct {
+	searchID     int
+	id, tourName string
+}
+
+// currentRunID запуск идущего поиска. Пока поиск запускается, запуска у него еще нет, и ID пустой
+func (tt *TimetableAPI) currentRunID() (string, string) {
+	tt.runLock.Lock()
+	defer tt.runLock.Unlock()
+	if tt.current.searchID != tt.searcher.SearchID() {
+		return "", ""
+	}
+	return tt.current.id, tt.current.tourName
+}
+
+// Shutdown остановить поиск и дождаться, пока запуск сохранится с точкой продолжения
+func (tt *TimetableAPI) Shutdown() {
+	tt.searcher.Shutdown()
+	tt.tracking.Wait()
+	if err := tt.repo.Close(); err != nil {
+		log.Println(err)
+	}
+}
+
+// snapshotRun сохранить запуск по ходу поиска searchID
+func (tt *TimetableAPI) snapshotRun(run *runs.Run, searchID int) {
+	// поиск мог завершиться и смениться следующим, пока его читали: итог запишет trackRun
+	sols, att := tt.searcher.GetSolutions()
+	if tt.searcher.SearchID() != searchID {
+		return
+	}
+	tt.saveRun(run, sols, att)
+
+	cp := tt.searcher.Checkpoint()
+	if tt.searcher.SearchID() != searchID {
+		return
+	}
+	tt.saveCheckpoint(run.ID, cp)
+}
+
+func (tt *TimetableAPI) saveRun(run *runs.Run, sols []searcher.Solution, att int) {
+	run.Attempts = att
+	run.SolutionsCnt = len(sols)
+	if len(sols) > runs.TopSolutions {
+		sols = sols[:runs.TopSolutions]
+	}
+	run.Solutions = append([]searcher.Solution(nil), sols...)
+
+	if err := tt.runs.Save(*run); err != nil {
+		log.Println("save run:", err)
+	}
+}
+
+// saveCheckpoint сохранить точку продолжения запуска. После завершения перебора точки нет, и старая удаляется
+func (tt *TimetableAPI) saveCheckpoint(runID string, cp *searcher.Checkpoint) {
+	if err := tt.runs.SaveCheckpoint(runID, cp); err != nil {
+		log.Println("save checkpoint:", err)
+	}
+}
+
+// findSolution решение по хешу из текущего поиска или из сохраненного запуска, и название его тура
+func (tt *TimetableAPI) findSolution(runID, hash string) (*searcher.Solution, string, error) {
+	var sols []searcher.Solution
+	var tourName string
+	if runID != "" {
+		run, err := tt.runs.Get(runID)
+		if err != nil {
+			return nil, "", err
+		}
+		sols, tourName = run.Solutions, run.TourName
+	} else {
+		sols, _ = tt.searcher.GetSolutions()
+		if tt.searcher.Condition != nil {
+			tourName = tt.searcher.Condition.TourName
+		}
+	}
+
+	for i := range sols {
+		if sols[i].HashStr == hash {
+			return &sols[i], tourName, nil
+		}
+	}
+	return nil, "", errors.New("Решение не найдено, возможно поиск был перезапущен")
+}
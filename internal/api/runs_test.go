@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sergrom/timetable/internal/services/runs"
+	"github.com/sergrom/timetable/internal/services/searcher"
+)
+
+func TestRunFinished(t *testing.T) {
+	ts := authServer(t)
+	planner := withCookie(ts.login("planner", "planner-secret"))
+
+	// три команды на одном поле: перебор заканчивается сам
+	p := smallProblem(t)
+	p.Teams, p.Coaches, p.Fields = p.Teams[:3], p.Coaches[:3], p.Fields[:1]
+	if w := ts.do(http.MethodPost, "/problem-start", problemBody(t, p), ajax, planner); w.Code != http.StatusOK {
+		t.Fatalf("problem start: code %d, %s", w.Code, w.Body)
+	}
+
+	tracked := make(chan struct{})
+	go func() {
+		ts.api.tracking.Wait()
+		close(tracked)
+	}()
+	select {
+	case <-tracked:
+	case <-time.After(30 * time.Second):
+		ts.api.searcher.Stop()
+		t.Fatal("search does not finish")
+	}
+
+	list, err := ts.api.runs.List()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("runs %+v, err %v", list, err)
+	}
+	run := list[0]
+	if run.Status != runs.StatusStopped || run.StopReason != searcher.StopReasonFinished || run.Finished.IsZero() {
+		t.Errorf("run status %s, reason %q, finished %v", run.Status, run.StopReason, run.Finished)
+	}
+	if run.Resumable || run.SolutionsCnt == 0 {
+		t.Errorf("run resumable %v, solutions %d", run.Resumable, run.SolutionsCnt)
+	}
+	if w := ts.do(http.MethodPost, "/run-resume?id="+run.ID, "", ajax, planner); w.Code != http.StatusBadRequest {
+		t.Errorf("finished run resumed: code %d", w.Code)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -9,6 +10,7 @@ import (
 	"github.com/sergrom/timetable/internal/api/req"
 	"github.com/sergrom/timetable/internal/ds"
 	"github.com/sergrom/timetable/internal/pkg"
+	"github.com/sergrom/timetable/internal/services/audit"
 	"github.com/sergrom/timetable/internal/services/searcher"
 )
 
@@ -19,26 +21,76 @@ func (tt *TimetableAPI) searchStart(c *gin.Context) {
 		return
 	}
 
-	var stad *ds.Stadium
 	stads, err := tt.repo.GetStadiums()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	stadsMap := make(map[int]ds.Stadium, len(stads))
 	for _, s := range stads {
-		if s.ID == msg.StaduiumID {
-			stad = &s
-			break
-		}
-	}
-	if stad == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "could not find stadium by id " + strconv.Itoa(msg.StaduiumID)})
-		return
+		stadsMap[s.ID] = s
 	}
 
+	// поля нумеруются подряд по всем стадионам и дням тура
 	fields := make([]ds.Field, 0, len(msg.Fields))
-	for i, f := range msg.Fields {
-		fields = append(fields, ds.NewField(i+1, f.Format, time.Duration(f.Dur)*time.Minute, f.From, f.To))
+	var merges []ds.FieldMerge
+	for _, v := range msg.GetVenues() {
+		stad, ok := stadsMap[v.StadiumID]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "could not find stadium by id " + strconv.Itoa(v.StadiumID)})
+			return
+		}
+		date, err := pkg.ParseDate(v.Date)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s: неверная дата %s", stad.Name, v.Date)})
+			return
+		}
+
+		offset := len(fields)
+		for i, f := range v.Fields {
+			breaks, err := ds.ParseBreaks(f.Breaks)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s, поле %d: %s", stad.Name, i+1, err)})
+				return
+			}
+			if f.Buffer < 0 || f.Buffer > 60 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s, поле %d: пересменка должна быть от 0 до 60 минут", stad.Name, i+1)})
+				return
+			}
+			field, err := ds.NewField(offset+i+1, f.Format, time.Duration(f.Dur)*time.Minute, time.Duration(f.Buffer)*time.Minute, f.From, f.To, breaks)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s, поле %d: %s", stad.Name, i+1, err)})
+				return
+			}
+			field.MoveToDate(date)
+			field.StadiumID = stad.ID
+			field.Venue = fmt.Sprintf("%s, %s", stad.Name, date.Format("02.01"))
+			fields = append(fields, field)
+		}
+
+		// в объединениях номера полей стадиона, переводим их в номера полей тура
+		mm, err := ds.ParseFieldMerges(v.Merges)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s: %s", stad.Name, err)})
+			return
+		}
+		for _, m := range mm {
+			ids := make([]int, 0, len(m.FieldIDs))
+			for _, id := range m.FieldIDs {
+				if id > len(v.Fields) {
+					ids = nil
+					break // такого поля нет в этом туре
+				}
+				ids = append(ids, offset+id)
+			}
+			if ids != nil {
+				merges = append(merges, ds.FieldMerge{FieldIDs: ids, Format: m.Format})
+			}
+		}
+	}
+	if len(fields) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields"})
+		return
 	}
 
 	divisions, err := tt.repo.GetDivisions()
@@ -69,6 +121,23 @@ func (tt *TimetableAPI) searchStart(c *gin.Context) {
 			teams = append(teams, t)
 		}
 	}
+	ratingSource := msg.RatingSource
+	if ratingSource == "" && msg.RatingByTable {
+		ratingSource = RatingSourceTable
+	}
+	if ratingSource != "" {
+		// команды без результатов остаются со своим рейтингом
+		ratings, err := tt.resultRatings(ratingSource, divisions, allTeams)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		for i := range teams {
+			if r, ok := ratings[teams[i].ID]; ok {
+				teams[i].Rating = r
+			}
+		}
+	}
 
 	wishes := make([]ds.Wish, 0, len(msg.Wishes))
 	for _, w := range msg.Wishes {
@@ -78,35 +147,107 @@ func (tt *TimetableAPI) searchStart(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err1.Error() + ", " + err2.Error()})
 			return
 		}
+		if w.Kind < int(ds.WishWithin) || w.Kind > int(ds.WishNotOpponent) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wish kind"})
+			return
+		}
 		wishes = append(wishes, ds.Wish{
 			TeamID:   w.TeamID,
+			Kind:     ds.WishKind(w.Kind),
 			TimeFrom: from,
 			TimeTo:   to,
+			Weight:   w.Weight,
+			Value:    w.Value,
 		})
 	}
 
+	referees, err := tt.repo.GetReferees()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if msg.RefereeIDs != nil {
+		picked := make([]ds.Referee, 0, len(msg.RefereeIDs))
+		for _, ref := range referees {
+			if pkg.SliceContain(ref.ID, msg.RefereeIDs) {
+				picked = append(picked, ref)
+			}
+		}
+		referees = picked
+	}
+
 	games := make([]ds.Game, 0, len(msg.Games))
 	for _, g := range msg.Games {
 		games = append(games, ds.Game{
-			Tour:       "",
+			Tour:       g.Tour,
 			TeamID1:    g.TeamID1,
 			TeamID2:    g.TeamID2,
-			CanRematch: 0,
+			CanRematch: g.CanRematch,
 		})
 	}
 
-	cond := searcher.NewCondition(msg.TourName, fields, divisions, coaches, teams, wishes, games)
-	solutions, att, err := tt.searcher.Search(cond)
+	// в туре из плана сезона играются только пары плана
+	var pairings []ds.Game
+	if msg.SeasonTour != "" {
+		seasonGames, err := tt.repo.GetSeason()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		for _, g := range seasonGames {
+			if g.Tour == msg.SeasonTour && teamsMap[g.TeamID1] && teamsMap[g.TeamID2] {
+				pairings = append(pairings, ds.Game{Tour: g.Tour, TeamID1: g.TeamID1, TeamID2: g.TeamID2})
+			}
+		}
+		if len(pairings) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "в туре " + msg.SeasonTour + " нет игр выбранных команд"})
+			return
+		}
+	}
+
+	params := searcher.DefaultParams()
+	params.RematchTours = msg.RematchTours
+	params.RematchWeight = msg.RematchWeight
+	params.RatingWeight = msg.RatingWeight
+	params.RatingMaxDiff = msg.RatingMaxDiff
+	params.CrossDivWeight = msg.CrossDivWeight
+	params.TravelDur = time.Duration(msg.TravelMin) * time.Minute
+
+	cond, err := searcher.NewCondition(msg.TourName, fields, merges, divisions, coaches, teams, wishes, games, pairings, referees, params)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	tt.startSearch(c, cond, nil, nil)
+}
+
+// startSearch запустить поиск по условию, с точки продолжения cp, если она есть, и ответить первыми решениями
+func (tt *TimetableAPI) startSearch(c *gin.Context, cond *searcher.Condition, cp *searcher.Checkpoint, sols []searcher.Solution) {
+	solutions, att, err := tt.searcher.Resume(cond, cp, sols)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	run := newRun(cond)
+	tt.trackRun(run)
+	action := audit.ActionSearchStart
+	if cp != nil {
+		action = audit.ActionSearchResume
+	}
+	tt.auditSearch(c, action, run.ID, cond.TourName, att)
+
+	tt.searchStarted(c, cond, solutions, att)
+}
 
+// searchStarted ответ на запуск поиска первыми решениями
+func (tt *TimetableAPI) searchStarted(c *gin.Context, cond *searcher.Condition, solutions []searcher.Solution, att int) {
 	c.JSON(http.StatusOK, gin.H{
 		"tour_name": cond.TourName,
 		"solutions": solutions,
 		"attempts":  att,
+		"rejected":  tt.searcher.RefereeRejected(),
 		"teams":     cond.TeamsPrettyMap,
+		"referees":  cond.RefereesPrettyMap,
 		"day_start": cond.DayStart,
 		"day_end":   cond.DayEnd,
 	})
@@ -4,10 +4,27 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sergrom/timetable/internal/services/audit"
+	"github.com/sergrom/timetable/internal/services/searcher"
 )
 
 func (tt *TimetableAPI) searchStop(c *gin.Context) {
+	running := tt.searcher.Status() == searcher.StatusInProcess
+	runID, tourName := tt.currentRunID()
 	tt.searcher.Stop()
+	if running {
+		tt.auditSearch(c, audit.ActionSearchStop, runID, tourName, tt.searcher.AttemptsCnt())
+	}
 	//tt.searcher.Reset()
 	c.JSON(http.StatusOK, gin.H{})
 }
+
+// searchResume продолжить последний остановленный поиск с места остановки
+func (tt *TimetableAPI) searchResume(c *gin.Context) {
+	run := tt.resumeOffer()
+	if run == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Нет остановленного поиска, который можно продолжить"})
+		return
+	}
+	tt.resumeRun(c, run.ID)
+}
@@ -0,0 +1,281 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sergrom/timetable/internal/api/req"
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/pkg"
+	"github.com/sergrom/timetable/internal/repository"
+	"github.com/sergrom/timetable/internal/services/searcher"
+	"github.com/sergrom/timetable/internal/services/season"
+	"github.com/xuri/excelize/v2"
+)
+
+var (
+	seasonTmpl, _ = template.New(`seasonTemplate`).Parse(`
+	<div class="bttns-top-panel">
+		<form>
+			<div class="form-row">
+				<div class="col-5 form-group">
+					<label>Дивизионы</label>
+					<select id="SeasonDivs" multiple="multiple" class="select2 form-control form-control-sm" style="width:100%">
+						{{range $i, $div := .divisions }}
+						<option value="{{$div.ID}}">{{$div.Name}} (формат {{$div.Format}})</option>
+						{{end}}
+					</select>
+				</div>
+				<div class="col-5 form-group">
+					<label title="Один тур на каждую дату, через запятую">Даты туров</label>
+					<input id="SeasonDates" class="form-control form-control-sm" type="text" placeholder="2024-10-05, 2024-10-12, 2024-10-19">
+				</div>
+				<div class="col-2 form-group">
+					<label>&nbsp;</label>
+					<button id="SeasonPlan" type="button" class="btn btn-sm btn-success" style="display:block;width:100%" title="План дивизионов будет составлен заново">Составить план</button>
+				</div>
+			</div>
+		</form>
+		<div class="clearfix"></div>
+	</div>
+	<table class="data-table-powered table table-sm">
+	<thead>
+	  <tr>
+		<th scope="col">#ID</th>
+		<th scope="col">Тур</th>
+		<th scope="col">Дата</th>
+		<th scope="col">Хозяева</th>
+		<th scope="col">Гости</th>
+		<th scope="col">Дивизион</th>
+	  </tr>
+	</thead>
+	<tbody>
+	  {{range $key, $game := .games }}
+	  <tr>
+		<td scope="row">{{ index $game 0 }}</td>
+		<td>{{ index $game 1 }}</td>
+		<td>{{ index $game 2 }}</td>
+		<td>{{ index $game 3 }}</td>
+		<td>{{ index $game 4 }}</td>
+		<td>{{ index $game 5 }}</td>
+	  </tr>
+	  {{end}}
+	</tbody>
+  </table>
+`)
+)
+
+// season план сезона
+func (tt *TimetableAPI) season(c *gin.Context) {
+	errs := make([]string, 0)
+	body := ""
+
+	divisions, err := tt.repo.GetDivisions()
+	if err != nil {
+		log.Println(err.Error())
+		errs = append(errs, err.Error())
+	}
+	teamsMap, err := tt.repo.GetTeamsMap()
+	if err != nil {
+		log.Println(err.Error())
+		errs = append(errs, err.Error())
+	}
+	games, err := tt.repo.GetSeason()
+	if err != nil {
+		log.Println(err.Error())
+		errs = append(errs, err.Error())
+	}
+
+	divsMap := make(map[int]string, len(divisions))
+	for _, d := range divisions {
+		divsMap[d.ID] = d.Name
+	}
+
+	gamesData := make([][]string, 0, len(games))
+	for _, g := range games {
+		team1, ok1 := teamsMap[g.TeamID1]
+		team2, ok2 := teamsMap[g.TeamID2]
+		if !ok1 || !ok2 {
+			continue
+		}
+		gamesData = append(gamesData, []string{strconv.Itoa(g.ID), g.Tour, g.Date, team1.Name, team2.Name, divsMap[team1.DivisionID]})
+	}
+
+	body = tt.renderTemplate(seasonTmpl, map[string]interface{}{
+		"divisions": divisions,
+		"games":     gamesData,
+	})
+
+	c.HTML(http.StatusOK, "tmpl.html", gin.H{
+		"title":    "Конструктор турниров",
+		"subtitle": "План сезона",
+		"errors":   errs,
+		"body":     template.HTML(body),
+		"page":     "season",
+	})
+}
+
+// seasonPlan составить план сезона для выбранных дивизионов, их прежний план заменяется
+func (tt *TimetableAPI) seasonPlan(c *gin.Context) {
+	var msg req.SeasonPlanRequest
+	if err := c.BindJSON(&msg); err != nil {
+		c.JSON(http.StatusOK, gin.H{"result": false, "error": err.Error()})
+		return
+	}
+
+	before := tt.tableSnapshot("season")
+	skipped, err := tt.saveSeasonPlan(msg)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"result": false, "error": err.Error()})
+		return
+	}
+	tt.tableChanged(c, "season", before, "план сезона")
+
+	c.JSON(http.StatusOK, gin.H{"result": true, "skipped": skipped})
+}
+
+// saveSeasonPlan составить и сохранить план сезона, вернуть число пропущенных пар команд одного тренера
+func (tt *TimetableAPI) saveSeasonPlan(msg req.SeasonPlanRequest) (int, error) {
+	if len(msg.DivisionIDs) == 0 {
+		return 0, errors.New("Не выбраны дивизионы")
+	}
+	allDivisions, err := tt.repo.GetDivisions()
+	if err != nil {
+		return 0, err
+	}
+	selected := make(map[int]bool, len(msg.DivisionIDs))
+	for _, id := range msg.DivisionIDs {
+		selected[id] = true
+	}
+	divisions := make([]ds.Division, 0, len(msg.DivisionIDs))
+	for _, d := range allDivisions {
+		if selected[d.ID] {
+			divisions = append(divisions, d)
+		}
+	}
+
+	allTeams, err := tt.repo.GetTeams()
+	if err != nil {
+		return 0, err
+	}
+	teamDivs := make(map[int]int, len(allTeams))
+	teams := make([]ds.Team, 0, len(allTeams))
+	for _, t := range allTeams {
+		teamDivs[t.ID] = t.DivisionID
+		if selected[t.DivisionID] {
+			teams = append(teams, t)
+		}
+	}
+
+	prev, err := tt.repo.GetSeason()
+	if err != nil {
+		return 0, err
+	}
+	// план остальных дивизионов остается как был
+	kept := make([]ds.SeasonGame, 0, len(prev))
+	for _, g := range prev {
+		if !selected[teamDivs[g.TeamID1]] && !selected[teamDivs[g.TeamID2]] {
+			kept = append(kept, g)
+		}
+	}
+
+	tours, err := planTours(msg.Dates, kept)
+	if err != nil {
+		return 0, err
+	}
+	planned, skipped, err := season.Plan(divisions, teams, tours, searcher.GamesPerTeam)
+	if err != nil {
+		return 0, err
+	}
+
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	// Create a new sheet.
+	index, err := f.NewSheet("Sheet1")
+	if err != nil {
+		return 0, err
+	}
+
+	setSeasonHeader(f)
+
+	rowIdx, maxID := 2, 0
+	for _, g := range kept {
+		if maxID < g.ID {
+			maxID = g.ID
+		}
+		setSeasonRow(f, rowIdx, g)
+		rowIdx++
+	}
+	for _, g := range planned {
+		g.ID += maxID
+		setSeasonRow(f, rowIdx, g)
+		rowIdx++
+	}
+
+	f.SetActiveSheet(index)
+
+	return skipped, tt.repo.SaveFile(f, repository.SeasonFile)
+}
+
+// planTours туры для дат плана. Дата, на которую в плане других дивизионов уже есть тур, получает его название:
+// пары одного дня подбираются одним поиском. Остальные даты нумеруются после последнего тура плана,
+// так что у туров с разными датами названия не совпадают
+func planTours(dates []string, kept []ds.SeasonGame) ([]season.Tour, error) {
+	names := make(map[string]string, len(kept))
+	last := 0
+	for _, g := range kept {
+		if g.Date != "" && names[g.Date] == "" {
+			names[g.Date] = g.Tour
+		}
+		var n int
+		if _, err := fmt.Sscanf(g.Tour, "Тур %d", &n); err == nil && n > last {
+			last = n
+		}
+	}
+
+	tours := make([]season.Tour, 0, len(dates))
+	seen := make(map[string]bool, len(dates))
+	for _, d := range dates {
+		if _, err := pkg.ParseDate(d); d == "" || err != nil {
+			return nil, fmt.Errorf("Неверная дата тура %s", d)
+		}
+		if seen[d] {
+			return nil, fmt.Errorf("Дата тура %s указана дважды", d)
+		}
+		seen[d] = true
+
+		name, ok := names[d]
+		if !ok {
+			last++
+			name = fmt.Sprintf("Тур %d", last)
+		}
+		tours = append(tours, season.Tour{Name: name, Date: d})
+	}
+	return tours, nil
+}
+
+func setSeasonHeader(f *excelize.File) {
+	f.SetCellStr("Sheet1", "A1", "ID")
+	f.SetCellStr("Sheet1", "B1", "Тур")
+	f.SetCellStr("Sheet1", "C1", "Дата")
+	f.SetCellStr("Sheet1", "D1", "id команды хозяев")
+	f.SetCellStr("Sheet1", "E1", "id команды гостей")
+}
+
+func setSeasonRow(f *excelize.File, rowIdx int, g ds.SeasonGame) {
+	f.SetCellValue("Sheet1", fmt.Sprintf("A%d", rowIdx), g.ID)
+	f.SetCellValue("Sheet1", fmt.Sprintf("B%d", rowIdx), g.Tour)
+	f.SetCellStr("Sheet1", fmt.Sprintf("C%d", rowIdx), g.Date)
+	f.SetCellValue("Sheet1", fmt.Sprintf("D%d", rowIdx), g.TeamID1)
+	f.SetCellValue("Sheet1", fmt.Sprintf("E%d", rowIdx), g.TeamID2)
+}
@@ -0,0 +1,72 @@
+package api
+
+import "testing"
+
+func TestSeasonPlanTourNames(t *testing.T) {
+	ts := authServer(t)
+	ts.seed(map[string][][]string{
+		"divisions": {{"ID", "Дивизион", "Формат", "Играет с (ID дивизионов)"}, {"1", "2012", "6"}, {"2", "2014", "6"}},
+		"teams": {
+			{"ID", "Команда", "Тренер", "Дивизион"},
+			{"1", "А", "1", "1"}, {"2", "Б", "2", "1"},
+			{"3", "В", "3", "2"}, {"4", "Г", "4", "2"},
+		},
+	})
+	planner := withCookie(ts.login("planner", "planner-secret"))
+
+	steps := []struct {
+		name, body string
+		wantOk     bool
+		wantTours  map[string]string // дата -> название тура во всем плане
+	}{
+		{
+			name:      "first plan",
+			body:      `{"division_ids":[1],"dates":["2026-05-01"]}`,
+			wantOk:    true,
+			wantTours: map[string]string{"2026-05-01": "Тур 1"},
+		},
+		{
+			name:      "another date continues numbering",
+			body:      `{"division_ids":[2],"dates":["2026-05-08"]}`,
+			wantOk:    true,
+			wantTours: map[string]string{"2026-05-01": "Тур 1", "2026-05-08": "Тур 2"},
+		},
+		{
+			name:      "same date joins the existing tour",
+			body:      `{"division_ids":[2],"dates":["2026-05-01"]}`,
+			wantOk:    true,
+			wantTours: map[string]string{"2026-05-01": "Тур 1"},
+		},
+		{name: "date twice", body: `{"division_ids":[2],"dates":["2026-05-08","2026-05-08"]}`},
+	}
+
+	for _, st := range steps {
+		ok, msg := ts.postJSON("/season-plan", st.body, planner)
+		if ok != st.wantOk {
+			t.Fatalf("%s: result %v (%s), want %v", st.name, ok, msg, st.wantOk)
+		}
+		if !ok {
+			continue
+		}
+
+		games, err := ts.api.repo.GetSeason()
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]string)
+		for _, g := range games {
+			if name, ok := got[g.Date]; ok && name != g.Tour {
+				t.Errorf("%s: date %s has tours %s and %s", st.name, g.Date, name, g.Tour)
+			}
+			got[g.Date] = g.Tour
+		}
+		if len(got) != len(st.wantTours) {
+			t.Errorf("%s: tours %v, want %v", st.name, got, st.wantTours)
+		}
+		for date, name := range st.wantTours {
+			if got[date] != name {
+				t.Errorf("%s: tour on %s is %q, want %q", st.name, date, got[date], name)
+			}
+		}
+	}
+}
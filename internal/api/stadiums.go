@@ -6,21 +6,25 @@ import (
 	"html/template"
 	"log"
 	"net/http"
-	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sergrom/timetable/internal/api/req"
+	"github.com/sergrom/timetable/internal/ds"
 	"github.com/sergrom/timetable/internal/pkg"
 	"github.com/sergrom/timetable/internal/repository"
 	"github.com/xuri/excelize/v2"
 )
 
 var (
-	stadiumsTmpl, _ = template.New(`stadiumsTemplate`).Parse(`
+	stadiumsTmpl, _ = template.New(`stadiumsTemplate`).Funcs(template.FuncMap{
+		"mergesString": ds.FieldMergesString,
+		"breaksString": ds.BreaksString,
+	}).Parse(`
 	<div class="bttns-top-panel">
 		<div class="pull-right">
+			<a href="/history?table=stadiums" class="btn btn-sm btn-secondary" title="Версии таблицы"><i class="fa fa-history" aria-hidden="true"></i> История</a>
 			<button id="AddEntity" data-tag="stadium" data-id="-1" type="button" class="btn btn-sm btn-success"><i class="fa fa-plus" aria-hidden="true"></i> Добавить</button>
 		</div>
 		<div class="clearfix"></div>
@@ -35,6 +39,9 @@ var (
 		<th scope="col">Работает с</th>
 		<th scope="col">Работает по</th>
 		<th scope="col">Игра (минут)</th>
+		<th scope="col">Объединения полей</th>
+		<th scope="col">Перерывы</th>
+		<th scope="col">Пересменка (мин.)</th>
 		<th scope="col"></th>
 	  </tr>
 	</thead>
@@ -48,7 +55,11 @@ var (
 		<td>{{ $stad.TimeFrom.Format "15:04" }}</td>
 		<td>{{ $stad.TimeTo.Format "15:04" }}</td>
 		<td>{{ $stad.GameDur.Minutes }} мин.</td>
+		<td>{{ mergesString $stad.Merges }}</td>
+		<td>{{ breaksString $stad.Breaks }}</td>
+		<td>{{ $stad.Buffer.Minutes }}</td>
 		<td style="text-align:right">
+			<a href="/history?table=stadiums&id={{ $stad.ID }}" class="btn btn-sm btn-secondary" title="История записи"><i class="fa fa-history" aria-hidden="true"></i></a>
 			<button data-tag="stadium" data-id="{{ $stad.ID }}" type="button" class="edit-btn btn btn-sm btn-info"><i class="fa fa-pencil" aria-hidden="true"></i></button>
 			<button data-tag="stadium" data-id="{{ $stad.ID }}" type="button" class="del-btn btn btn-sm btn-danger"><i class="fa fa-times" aria-hidden="true"></i></button>
 		</td>
@@ -102,13 +113,7 @@ func (tt *TimetableAPI) delStadium(id int) error {
 		return err
 	}
 
-	f.SetCellStr("Sheet1", "A1", "ID")
-	f.SetCellStr("Sheet1", "B1", "Название")
-	f.SetCellStr("Sheet1", "C1", "Полей")
-	f.SetCellStr("Sheet1", "D1", "Формат")
-	f.SetCellStr("Sheet1", "E1", "Работает с")
-	f.SetCellStr("Sheet1", "F1", "Работает по")
-	f.SetCellStr("Sheet1", "G1", "Игра (минут)")
+	setStadiumsHeader(f)
 
 	for i, stad := range stads {
 		if stad.ID == id {
@@ -121,17 +126,24 @@ func (tt *TimetableAPI) delStadium(id int) error {
 		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), stad.TimeFrom.Format("15:04"))
 		f.SetCellValue("Sheet1", fmt.Sprintf("F%d", i+2), stad.TimeTo.Format("15:04"))
 		f.SetCellValue("Sheet1", fmt.Sprintf("G%d", i+2), stad.GameDur.Minutes())
+		f.SetCellValue("Sheet1", fmt.Sprintf("H%d", i+2), ds.FieldMergesString(stad.Merges))
+		f.SetCellValue("Sheet1", fmt.Sprintf("I%d", i+2), ds.BreaksString(stad.Breaks))
+		f.SetCellValue("Sheet1", fmt.Sprintf("J%d", i+2), stad.Buffer.Minutes())
 	}
 
 	f.SetActiveSheet(index)
 
-	return f.SaveAs(filepath.Join(repository.DataDir, repository.StadiumsFile))
+	return tt.repo.SaveFile(f, repository.StadiumsFile)
 }
 
 func (tt *TimetableAPI) saveStadium(msg req.SaveStadiumRequest) error {
 	if err := tt.validateStad(msg); err != nil {
 		return err
 	}
+	mm, _ := ds.ParseFieldMerges(msg.Merges)
+	merges := ds.FieldMergesString(mm)
+	bb, _ := ds.ParseBreaks(msg.Breaks)
+	breaks := ds.BreaksString(bb)
 
 	stadID, err := strconv.Atoi(msg.ID)
 	if err != nil {
@@ -162,13 +174,7 @@ func (tt *TimetableAPI) saveStadium(msg req.SaveStadiumRequest) error {
 		return err
 	}
 
-	f.SetCellStr("Sheet1", "A1", "ID")
-	f.SetCellStr("Sheet1", "B1", "Название")
-	f.SetCellStr("Sheet1", "C1", "Полей")
-	f.SetCellStr("Sheet1", "D1", "Формат")
-	f.SetCellStr("Sheet1", "E1", "Работает с")
-	f.SetCellStr("Sheet1", "F1", "Работает по")
-	f.SetCellStr("Sheet1", "G1", "Игра (минут)")
+	setStadiumsHeader(f)
 
 	maxID := 0
 	for i, stad := range stads {
@@ -183,6 +189,9 @@ func (tt *TimetableAPI) saveStadium(msg req.SaveStadiumRequest) error {
 			f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), msg.TimeFrom)
 			f.SetCellValue("Sheet1", fmt.Sprintf("F%d", i+2), msg.TimeTo)
 			f.SetCellValue("Sheet1", fmt.Sprintf("G%d", i+2), msg.GameDur)
+			f.SetCellValue("Sheet1", fmt.Sprintf("H%d", i+2), merges)
+			f.SetCellValue("Sheet1", fmt.Sprintf("I%d", i+2), breaks)
+			f.SetCellValue("Sheet1", fmt.Sprintf("J%d", i+2), msg.Buffer)
 			continue
 		}
 
@@ -193,6 +202,9 @@ func (tt *TimetableAPI) saveStadium(msg req.SaveStadiumRequest) error {
 		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), stad.TimeFrom.Format("15:04"))
 		f.SetCellValue("Sheet1", fmt.Sprintf("F%d", i+2), stad.TimeTo.Format("15:04"))
 		f.SetCellValue("Sheet1", fmt.Sprintf("G%d", i+2), stad.GameDur.Minutes())
+		f.SetCellValue("Sheet1", fmt.Sprintf("H%d", i+2), ds.FieldMergesString(stad.Merges))
+		f.SetCellValue("Sheet1", fmt.Sprintf("I%d", i+2), ds.BreaksString(stad.Breaks))
+		f.SetCellValue("Sheet1", fmt.Sprintf("J%d", i+2), stad.Buffer.Minutes())
 	}
 
 	if stadID == -1 {
@@ -204,11 +216,14 @@ func (tt *TimetableAPI) saveStadium(msg req.SaveStadiumRequest) error {
 		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", idx), msg.TimeFrom)
 		f.SetCellValue("Sheet1", fmt.Sprintf("F%d", idx), msg.TimeTo)
 		f.SetCellValue("Sheet1", fmt.Sprintf("G%d", idx), msg.GameDur)
+		f.SetCellValue("Sheet1", fmt.Sprintf("H%d", idx), merges)
+		f.SetCellValue("Sheet1", fmt.Sprintf("I%d", idx), breaks)
+		f.SetCellValue("Sheet1", fmt.Sprintf("J%d", idx), msg.Buffer)
 	}
 
 	f.SetActiveSheet(index)
 
-	return f.SaveAs(filepath.Join(repository.DataDir, repository.StadiumsFile))
+	return tt.repo.SaveFile(f, repository.StadiumsFile)
 }
 
 func (tt *TimetableAPI) validateStad(msg req.SaveStadiumRequest) error {
@@ -255,9 +270,48 @@ func (tt *TimetableAPI) validateStad(msg req.SaveStadiumRequest) error {
 		return errors.New("gameDur must be from 10 to 150 min")
 	}
 
+	merges, err := ds.ParseFieldMerges(msg.Merges)
+	if err != nil {
+		return err
+	}
+	for _, m := range merges {
+		for _, fID := range m.FieldIDs {
+			if fID > fields {
+				return fmt.Errorf("Объединение %s: на стадионе нет поля %d", m, fID)
+			}
+		}
+	}
+
+	if _, err := ds.ParseBreaks(msg.Breaks); err != nil {
+		return err
+	}
+
+	if msg.Buffer != "" {
+		buffer, err := strconv.Atoi(msg.Buffer)
+		if err != nil {
+			return err
+		}
+		if buffer < 0 || buffer > 60 {
+			return errors.New("Пересменка должна быть от 0 до 60 минут")
+		}
+	}
+
 	return nil
 }
 
+func setStadiumsHeader(f *excelize.File) {
+	f.SetCellStr("Sheet1", "A1", "ID")
+	f.SetCellStr("Sheet1", "B1", "Название")
+	f.SetCellStr("Sheet1", "C1", "Полей")
+	f.SetCellStr("Sheet1", "D1", "Формат")
+	f.SetCellStr("Sheet1", "E1", "Работает с")
+	f.SetCellStr("Sheet1", "F1", "Работает по")
+	f.SetCellStr("Sheet1", "G1", "Игра (минут)")
+	f.SetCellStr("Sheet1", "H1", "Объединения полей")
+	f.SetCellStr("Sheet1", "I1", "Перерывы")
+	f.SetCellStr("Sheet1", "J1", "Пересменка (мин.)")
+}
+
 // func (tt *TimetableAPI) stadiumsDownload(c *gin.Context) {
 // 	stads, err := tt.repo.GetStadiums()
 // 	if err != nil {
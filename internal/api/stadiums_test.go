@@ -0,0 +1,58 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/sergrom/timetable/internal/ds"
+)
+
+func TestStadiumMerges(t *testing.T) {
+	legacyHeader := []string{"ID", "Название", "Полей", "Формат", "Работает с", "Работает по", "Игра (минут)"}
+	header := append(append([]string(nil), legacyHeader...), "Объединения полей", "Перерывы")
+
+	tests := []struct {
+		name string
+		rows [][]string
+		want []string
+	}{
+		{
+			name: "file without merges column merges fields as before",
+			rows: [][]string{legacyHeader, {"1", "Большой", "4", "6", "09:00", "18:00", "60"}, {"2", "Малый", "2", "6", "09:00", "18:00", "60"}, {"3", "Один", "1", "6", "09:00", "18:00", "60"}},
+			want: []string{"1+2:7; 3+4:7", "1+2:7", ""},
+		},
+		{
+			name: "empty merges are kept empty",
+			rows: [][]string{header, {"1", "Большой", "4", "6", "09:00", "18:00", "60"}, {"2", "Малый", "2", "6", "09:00", "18:00", "60", "", "13:00-13:30"}},
+			want: []string{"", ""},
+		},
+		{
+			name: "declared merges",
+			rows: [][]string{header, {"1", "Большой", "4", "6", "09:00", "18:00", "60", "1+2+3+4:11"}},
+			want: []string{"1+2+3+4:11"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ts := newTestServer(t, testConfig(t))
+			ts.seed(map[string][][]string{"stadiums": tc.rows})
+
+			stads, err := ts.api.repo.GetStadiums()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(stads))
+			for _, st := range stads {
+				got = append(got, ds.FieldMergesString(st.Merges))
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("merges %q, want %q", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("stadium %d merges %q, want %q", i+1, got[i], tc.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/services/standings"
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+var (
+	standingsTmpl, _ = template.New(`standingsTemplate`).Parse(`
+	<div class="bttns-top-panel d-print-none">
+		<div class="pull-right">
+			<a href="/standings-download" target="blank" class="btn btn-sm btn-primary"><i class="fa fa-file-excel-o" aria-hidden="true"></i> Скачать</a>
+			<a href="/standings-pdf" target="blank" class="btn btn-sm btn-primary"><i class="fa fa-file-pdf-o" aria-hidden="true"></i> PDF</a>
+			<button type="button" class="btn btn-sm btn-secondary" onclick="window.print()" title="Печать страницы средствами браузера"><i class="fa fa-print" aria-hidden="true"></i> Печать</button>
+		</div>
+		<div class="clearfix"></div>
+	</div>
+	{{range $i, $div := .divisions }}
+	<h5>{{ $div.Name }}</h5>
+	<table class="table table-sm">
+	<thead>
+	  <tr>
+		<th scope="col">#</th>
+		<th scope="col">Команда</th>
+		<th scope="col" title="Игры">И</th>
+		<th scope="col" title="Победы">В</th>
+		<th scope="col" title="Ничьи">Н</th>
+		<th scope="col" title="Поражения">П</th>
+		<th scope="col">Мячи</th>
+		<th scope="col" title="Разница мячей">+/-</th>
+		<th scope="col">Очки</th>
+		<th scope="col" title="Рейтинг Эло по всем внесенным результатам, начальный - рейтинг команды">Эло</th>
+	  </tr>
+	</thead>
+	<tbody>
+	  {{range $j, $row := $div.Rows }}
+	  <tr>
+		{{range $k, $cell := $row }}
+		{{ if eq $k 0 }}<th scope="row">{{ $cell }}</th>{{ else }}<td>{{ $cell }}</td>{{ end }}
+		{{end}}
+	  </tr>
+	  {{end}}
+	</tbody>
+	</table>
+	{{end}}
+`)
+)
+
+// standingsHeader заголовок таблицы дивизиона в файлах, standingsWidths - ширина колонок в PDF, мм
+var (
+	standingsHeader = []string{"#", "Команда", "И", "В", "Н", "П", "Мячи", "+/-", "Очки", "Эло"}
+	standingsWidths = []float64{8, 70, 12, 12, 12, 12, 18, 14, 14, 18}
+)
+
+// divisionStandings таблица дивизиона для вывода
+type divisionStandings struct {
+	Name string
+	Rows [][]string
+}
+
+// standingsPage турнирные таблицы дивизионов
+func (tt *TimetableAPI) standingsPage(c *gin.Context) {
+	errs := make([]string, 0)
+
+	tables, err := tt.calcStandings()
+	if err != nil {
+		log.Println(err.Error())
+		errs = append(errs, err.Error())
+	}
+
+	body := tt.renderTemplate(standingsTmpl, map[string]interface{}{
+		"divisions": tables,
+	})
+
+	c.HTML(http.StatusOK, "tmpl.html", gin.H{
+		"title":    "Конструктор турниров",
+		"subtitle": "Турнирные таблицы",
+		"errors":   errs,
+		"body":     template.HTML(body),
+		"page":     "standings",
+	})
+}
+
+// standingsDownload турнирные таблицы в xlsx, каждый дивизион - под своим заголовком
+func (tt *TimetableAPI) standingsDownload(c *gin.Context) {
+	tables, err := tt.calcStandings()
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			fmt.Println(err)
+		}
+	}()
+
+	// Create a new sheet.
+	index, err := f.NewSheet("Sheet1")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	styleHead, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true, Size: 14}})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	rowIdx := 1
+	for _, t := range tables {
+		f.SetCellStr("Sheet1", fmt.Sprintf("A%d", rowIdx), t.Name)
+		f.SetCellStyle("Sheet1", fmt.Sprintf("A%d", rowIdx), fmt.Sprintf("A%d", rowIdx), styleHead)
+		rowIdx++
+		for i, h := range standingsHeader {
+			cell, _ := excelize.CoordinatesToCellName(i+1, rowIdx)
+			f.SetCellStr("Sheet1", cell, h)
+		}
+		rowIdx++
+		for _, row := range t.Rows {
+			for i, v := range row {
+				cell, _ := excelize.CoordinatesToCellName(i+1, rowIdx)
+				f.SetCellStr("Sheet1", cell, v)
+			}
+			rowIdx++
+		}
+		rowIdx++
+	}
+	f.SetColWidth("Sheet1", "B", "B", 35)
+	f.SetActiveSheet(index)
+
+	c.Header("Content-Disposition", "attachment; filename=Таблицы.xlsx")
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Pragma", "public")
+	c.Header("Content-Transfer-Encoding", "binary")
+	c.Header("Cache-Control", "must-revalidate")
+
+	f.WriteTo(c.Writer)
+}
+
+// standingsPDF турнирные таблицы в PDF. Шрифты Go встроены в программу: в них есть кириллица
+func (tt *TimetableAPI) standingsPDF(c *gin.Context) {
+	tables, err := tt.calcStandings()
+	if err != nil {
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes("go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("go", "B", gobold.TTF)
+	pdf.SetTitle("Турнирные таблицы", true)
+	pdf.AddPage()
+
+	for _, t := range tables {
+		pdf.SetFont("go", "B", 13)
+		pdf.CellFormat(0, 9, t.Name, "", 1, "L", false, 0, "")
+
+		pdf.SetFont("go", "B", 9)
+		for i, h := range standingsHeader {
+			pdf.CellFormat(standingsWidths[i], 6, h, "1", 0, "C", false, 0, "")
+		}
+		pdf.Ln(-1)
+
+		pdf.SetFont("go", "", 9)
+		for _, row := range t.Rows {
+			for i, v := range row {
+				align := "C"
+				if i == 1 {
+					align = "L"
+				}
+				pdf.CellFormat(standingsWidths[i], 6, v, "1", 0, align, false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+		pdf.Ln(4)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		log.Println(err)
+		c.Writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=Таблицы.pdf")
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+func (tt *TimetableAPI) calcStandings() ([]divisionStandings, error) {
+	divisions, err := tt.repo.GetDivisions()
+	if err != nil {
+		return nil, err
+	}
+	teams, err := tt.repo.GetTeams()
+	if err != nil {
+		return nil, err
+	}
+	games, err := tt.repo.GetGames()
+	if err != nil {
+		return nil, err
+	}
+
+	elo := standings.Elo(teams, games)
+	tables := make([]divisionStandings, 0, len(divisions))
+	for _, div := range divisions {
+		rows := standings.Calc(div, teams, games)
+		if len(rows) == 0 {
+			continue
+		}
+		t := divisionStandings{Name: div.Name, Rows: make([][]string, 0, len(rows))}
+		for i, r := range rows {
+			t.Rows = append(t.Rows, standingsRow(i+1, r, elo[r.Team.ID]))
+		}
+		tables = append(tables, t)
+	}
+
+	return tables, nil
+}
+
+func standingsRow(place int, r standings.Row, elo int) []string {
+	eloStr := ""
+	if elo > 0 {
+		eloStr = fmt.Sprint(elo)
+	}
+	return []string{
+		fmt.Sprint(place),
+		r.Team.Name,
+		fmt.Sprint(r.Played),
+		fmt.Sprint(r.Won),
+		fmt.Sprint(r.Drawn),
+		fmt.Sprint(r.Lost),
+		fmt.Sprintf("%d-%d", r.GoalsFor, r.GoalsAgainst),
+		fmt.Sprintf("%+d", r.GoalDiff()),
+		fmt.Sprint(r.Points),
+		eloStr,
+	}
+}
+
+// RatingSourceTable, RatingSourceElo откуда берется сила команд с результатами при подборе пар
+const (
+	RatingSourceTable = "table"
+	RatingSourceElo   = "elo"
+)
+
+// resultRatings сила команд по внесенным результатам, только для команд с результатами
+func (tt *TimetableAPI) resultRatings(source string, divisions []ds.Division, teams []ds.Team) (map[int]int, error) {
+	games, err := tt.repo.GetGames()
+	if err != nil {
+		return nil, err
+	}
+
+	switch source {
+	case RatingSourceElo:
+		return standings.Elo(teams, games), nil
+	case RatingSourceTable:
+	default:
+		return nil, fmt.Errorf("неизвестный источник рейтинга %s", source)
+	}
+
+	ratings := make(map[int]int, len(teams))
+	for _, div := range divisions {
+		for _, r := range standings.Calc(div, teams, games) {
+			if rating := r.Rating(); rating > 0 {
+				ratings[r.Team.ID] = rating
+			}
+		}
+	}
+	return ratings, nil
+}
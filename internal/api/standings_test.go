@@ -0,0 +1,35 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/sergrom/timetable/internal/ds"
+)
+
+func TestStandingsPDF(t *testing.T) {
+	ts := authServer(t)
+	ts.seed(map[string][][]string{
+		"divisions": {{"ID", "Дивизион", "Формат", "Играет с (ID дивизионов)"}, {"1", "2012", "6"}},
+		"teams":     {{"ID", "Команда", "Тренер", "Дивизион"}, {"1", "Спартак", "1", "1"}, {"2", "Динамо", "2", "1"}},
+		"games":     {{"ID", "Тур", "Команда 1", "Команда 2", "Можно переиграть"}, {"1", "Тур 1", "1", "2", "0"}},
+	})
+	planner := withCookie(ts.login("planner", "planner-secret"))
+	body := fmt.Sprintf(`{"id":"1","score1":"2","score2":"1","status":"%d","date":"2024-05-18"}`, ds.GamePlayed)
+	if ok, msg := ts.postJSON("/save-result", body, planner); !ok {
+		t.Fatal(msg)
+	}
+
+	w := ts.do(http.MethodGet, "/standings-pdf", "", withCookie(ts.login("viewer", "viewer-secret")))
+	if w.Code != http.StatusOK {
+		t.Fatalf("code %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("content type %s", ct)
+	}
+	if !bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")) {
+		t.Errorf("body is not a PDF: %.20q", w.Body.String())
+	}
+}
@@ -15,7 +15,9 @@ func (tt *TimetableAPI) status(c *gin.Context) {
 	retJson := gin.H{
 		"solutions_cnt": solutionsCnt,
 		"attempts":      attemptsCnt,
+		"rejected":      tt.searcher.RefereeRejected(),
 		"status":        status,
+		"resumable":     status == "stopped" && tt.searcher.Checkpoint() != nil,
 	}
 
 	withData := c.Request.URL.Query().Get("with-data")
@@ -23,6 +25,7 @@ func (tt *TimetableAPI) status(c *gin.Context) {
 	if status == "process" && withData == "1" {
 		retJson["tour_name"] = tt.searcher.Condition.TourName
 		retJson["teams"] = tt.searcher.Condition.TeamsPrettyMap
+		retJson["referees"] = tt.searcher.Condition.RefereesPrettyMap
 		retJson["day_start"] = tt.searcher.Condition.DayStart
 		retJson["day_end"] = tt.searcher.Condition.DayEnd
 	}
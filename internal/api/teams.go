@@ -6,7 +6,6 @@ import (
 	"html/template"
 	"log"
 	"net/http"
-	"path/filepath"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
@@ -31,6 +30,7 @@ var (
 	</script>
 	<div class="bttns-top-panel">
 		<div class="pull-right">
+			<a href="/history?table=teams" class="btn btn-sm btn-secondary" title="Версии таблицы"><i class="fa fa-history" aria-hidden="true"></i> История</a>
 			<button id="AddEntity" data-tag="team" data-id="-1" type="button" class="btn btn-sm btn-success"><i class="fa fa-plus" aria-hidden="true"></i> Добавить</button>
 		</div>
 		<div class="clearfix"></div>
@@ -42,6 +42,7 @@ var (
 		<th scope="col">Название</th>
 		<th scope="col">Дивизион</th>
 		<th scope="col">Тренер</th>
+		<th scope="col">Рейтинг</th>
 		<th scope="col"></th>
 	  </tr>
 	</thead>
@@ -52,7 +53,9 @@ var (
 		<td>{{ index $team 1}}</td>
 		<td>{{ index $team 2}}</td>
 		<td>{{ index $team 3}}</td>
+		<td>{{ index $team 6}}</td>
 		<td style="text-align:right">
+			<a href="/history?table=teams&id={{ index $team 0 }}" class="btn btn-sm btn-secondary" title="История записи"><i class="fa fa-history" aria-hidden="true"></i></a>
 			<button data-tag="team" data-id="{{ index $team 0 }}" data-div-id="{{ index $team 4 }}" data-coach-id="{{ index $team 5 }}" type="button" class="edit-btn btn btn-sm btn-info"><i class="fa fa-pencil" aria-hidden="true"></i></button>
 			<button data-tag="team" data-id="{{ index $team 0 }}" type="button" class="del-btn btn btn-sm btn-danger"><i class="fa fa-times" aria-hidden="true"></i></button>
 		</td>
@@ -88,7 +91,11 @@ func (tt *TimetableAPI) teams(c *gin.Context) {
 				// todo err
 				continue
 			}
-			teamsData = append(teamsData, []string{strconv.Itoa(t.ID), t.Name, div.Name, coach.Name, strconv.Itoa(div.ID), strconv.Itoa(coach.ID)})
+			rating := ""
+			if t.Rating != 0 {
+				rating = strconv.Itoa(t.Rating)
+			}
+			teamsData = append(teamsData, []string{strconv.Itoa(t.ID), t.Name, div.Name, coach.Name, strconv.Itoa(div.ID), strconv.Itoa(coach.ID), rating})
 		}
 
 		body = tt.renderTemplate(teamsTmpl, map[string]interface{}{
@@ -130,6 +137,7 @@ func (tt *TimetableAPI) delTeam(id int) error {
 	f.SetCellStr("Sheet1", "B1", "Название")
 	f.SetCellStr("Sheet1", "C1", "ID тренера")
 	f.SetCellStr("Sheet1", "D1", "ID дивизиона")
+	f.SetCellStr("Sheet1", "E1", "Рейтинг")
 
 	for i, team := range teams {
 		if team.ID == id {
@@ -139,11 +147,12 @@ func (tt *TimetableAPI) delTeam(id int) error {
 		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), team.Name)
 		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), team.CoachID)
 		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), team.DivisionID)
+		setRatingCell(f, fmt.Sprintf("E%d", i+2), team.Rating)
 	}
 
 	f.SetActiveSheet(index)
 
-	return f.SaveAs(filepath.Join(repository.DataDir, repository.TeamsFile))
+	return tt.repo.SaveFile(f, repository.TeamsFile)
 }
 
 func (tt *TimetableAPI) saveTeam(msg req.SaveTeamRequest) error {
@@ -178,6 +187,7 @@ func (tt *TimetableAPI) saveTeam(msg req.SaveTeamRequest) error {
 	f.SetCellStr("Sheet1", "B1", "Название")
 	f.SetCellStr("Sheet1", "C1", "ID тренера")
 	f.SetCellStr("Sheet1", "D1", "ID дивизиона")
+	f.SetCellStr("Sheet1", "E1", "Рейтинг")
 
 	maxID := 0
 	for i, team := range teams {
@@ -189,6 +199,7 @@ func (tt *TimetableAPI) saveTeam(msg req.SaveTeamRequest) error {
 			f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), msg.Name)
 			f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), msg.CoachID)
 			f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), msg.DivisionID)
+			f.SetCellValue("Sheet1", fmt.Sprintf("E%d", i+2), msg.Rating)
 			continue
 		}
 
@@ -196,6 +207,7 @@ func (tt *TimetableAPI) saveTeam(msg req.SaveTeamRequest) error {
 		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), team.Name)
 		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), team.CoachID)
 		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), team.DivisionID)
+		setRatingCell(f, fmt.Sprintf("E%d", i+2), team.Rating)
 	}
 
 	if teamID == -1 {
@@ -204,11 +216,12 @@ func (tt *TimetableAPI) saveTeam(msg req.SaveTeamRequest) error {
 		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", idx), msg.Name)
 		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", idx), msg.CoachID)
 		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", idx), msg.DivisionID)
+		f.SetCellValue("Sheet1", fmt.Sprintf("E%d", idx), msg.Rating)
 	}
 
 	f.SetActiveSheet(index)
 
-	return f.SaveAs(filepath.Join(repository.DataDir, repository.TeamsFile))
+	return tt.repo.SaveFile(f, repository.TeamsFile)
 }
 
 func (tt *TimetableAPI) validateTeam(msg req.SaveTeamRequest) error {
@@ -240,9 +253,26 @@ func (tt *TimetableAPI) validateTeam(msg req.SaveTeamRequest) error {
 		return errors.New("CoachID incorrect")
 	}
 
+	if msg.Rating != "" {
+		rating, err := strconv.Atoi(msg.Rating)
+		if err != nil {
+			return err
+		}
+		if rating < 0 || rating > 5000 {
+			return errors.New("Рейтинг должен быть от 0 до 5000")
+		}
+	}
+
 	return nil
 }
 
+func setRatingCell(f *excelize.File, cell string, rating int) {
+	if rating == 0 {
+		return
+	}
+	f.SetCellValue("Sheet1", cell, rating)
+}
+
 // func (tt *TimetableAPI) teamsDownload(c *gin.Context) {
 // 	teams, err := tt.repo.GetTeams()
 // 	if err != nil {
@@ -6,11 +6,11 @@ import (
 	"html/template"
 	"log"
 	"net/http"
-	"path/filepath"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sergrom/timetable/internal/api/req"
+	"github.com/sergrom/timetable/internal/ds"
 	"github.com/sergrom/timetable/internal/pkg"
 	"github.com/sergrom/timetable/internal/repository"
 	"github.com/xuri/excelize/v2"
@@ -27,6 +27,7 @@ var (
 	</script>
 	<div class="bttns-top-panel">
 		<div class="pull-right">
+			<a href="/history?table=wishes" class="btn btn-sm btn-secondary" title="Версии таблицы"><i class="fa fa-history" aria-hidden="true"></i> История</a>
 			<button id="AddEntity" data-tag="wish" data-id="-1" type="button" class="btn btn-sm btn-success"><i class="fa fa-plus" aria-hidden="true"></i> Добавить</button>
 		</div>
 		<div class="clearfix"></div>
@@ -37,6 +38,9 @@ var (
 		<th scope="col">Команда</th>
 		<th scope="col">с</th>
 		<th scope="col">по</th>
+		<th scope="col">Тип</th>
+		<th scope="col">Поле / соперник</th>
+		<th scope="col">Вес</th>
 		<th scope="col"></th>
 	  </tr>
 	</thead>
@@ -46,8 +50,12 @@ var (
 		<td scope="row">{{ index $wish 1 }}</td>
 		<td>{{ index $wish 2}}</td>
 		<td>{{ index $wish 3}}</td>
+		<td>{{ index $wish 6}}</td>
+		<td>{{ index $wish 9}}</td>
+		<td>{{ index $wish 7}}</td>
 		<td style="text-align:right">
-			<button data-tag="wish" data-id="{{ index $wish 0 }}" data-team-id="{{ index $wish 4}}" type="button" class="edit-btn btn btn-sm btn-info"><i class="fa fa-pencil" aria-hidden="true"></i></button>
+			<a href="/history?table=wishes&id={{ index $wish 0 }}" class="btn btn-sm btn-secondary" title="История записи"><i class="fa fa-history" aria-hidden="true"></i></a>
+			<button data-tag="wish" data-id="{{ index $wish 0 }}" data-team-id="{{ index $wish 4}}" data-kind="{{ index $wish 5}}" data-value="{{ index $wish 8}}" type="button" class="edit-btn btn btn-sm btn-info"><i class="fa fa-pencil" aria-hidden="true"></i></button>
 			<button data-tag="wish" data-id="{{ index $wish 0 }}" type="button" class="del-btn btn btn-sm btn-danger"><i class="fa fa-times" aria-hidden="true"></i></button>
 		</td>
 	  </tr>
@@ -90,14 +98,9 @@ func (tt *TimetableAPI) wishes(c *gin.Context) {
 		if !ok {
 			continue
 		}
-		from, to := "", ""
-		if !w.TimeFrom.IsZero() {
-			from = w.TimeFrom.Format("15:04")
-		}
-		if !w.TimeTo.IsZero() {
-			to = w.TimeTo.Format("15:04")
-		}
-		wishesData = append(wishesData, []string{strconv.Itoa(w.ID), fmt.Sprintf("%s (%s)", team.Name, divsMap[team.DivisionID].Name), from, to, strconv.Itoa(team.ID)})
+		row := wishRow(w)
+		wishesData = append(wishesData, []string{strconv.Itoa(w.ID), fmt.Sprintf("%s (%s)", team.Name, divsMap[team.DivisionID].Name),
+			row[2], row[3], strconv.Itoa(team.ID), row[4], w.Kind.String(), row[5], row[6], wishValueName(w, teamDivsMap)})
 	}
 
 	body = tt.renderTemplate(wishesTmpl, map[string]interface{}{
@@ -133,24 +136,18 @@ func (tt *TimetableAPI) delWish(id int) error {
 		return err
 	}
 
-	f.SetCellStr("Sheet1", "A1", "ID")
-	f.SetCellStr("Sheet1", "B1", "ID команды")
-	f.SetCellStr("Sheet1", "C1", "с")
-	f.SetCellStr("Sheet1", "D1", "по")
+	setWishesHeader(f)
 
 	for i, wish := range wishes {
 		if wish.ID == id {
 			continue
 		}
-		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), wish.ID)
-		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), wish.TeamID)
-		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), wish.TimeFrom.Format("15:04"))
-		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), wish.TimeTo.Format("15:04"))
+		setWishCells(f, i+2, wishRow(wish))
 	}
 
 	f.SetActiveSheet(index)
 
-	return f.SaveAs(filepath.Join(repository.DataDir, repository.WishesFile))
+	return tt.repo.SaveFile(f, repository.WishesFile)
 }
 
 func (tt *TimetableAPI) saveWish(msg req.SaveWishRequest) error {
@@ -162,22 +159,12 @@ func (tt *TimetableAPI) saveWish(msg req.SaveWishRequest) error {
 	if err != nil {
 		return err
 	}
-	teamID, err := strconv.Atoi(msg.TeamID)
-	if err != nil {
-		return err
-	}
 
 	wishes, err := tt.repo.GetWishes()
 	if err != nil {
 		return err
 	}
 
-	for _, w := range wishes {
-		if w.ID != wishID && teamID == w.TeamID {
-			return fmt.Errorf("Пожелание для этой команды уже есть")
-		}
-	}
-
 	f := excelize.NewFile()
 	defer func() {
 		if err := f.Close(); err != nil {
@@ -191,10 +178,7 @@ func (tt *TimetableAPI) saveWish(msg req.SaveWishRequest) error {
 		return err
 	}
 
-	f.SetCellStr("Sheet1", "A1", "ID")
-	f.SetCellStr("Sheet1", "B1", "ID команды")
-	f.SetCellStr("Sheet1", "C1", "с")
-	f.SetCellStr("Sheet1", "D1", "по")
+	setWishesHeader(f)
 
 	maxID := 0
 	for i, wish := range wishes {
@@ -202,37 +186,20 @@ func (tt *TimetableAPI) saveWish(msg req.SaveWishRequest) error {
 			maxID = wish.ID
 		}
 		if wish.ID == wishID {
-			f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), msg.ID)
-			f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), msg.TeamID)
-			f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), msg.TimeFrom)
-			f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), msg.TimeTo)
+			setWishCells(f, i+2, []string{msg.ID, msg.TeamID, msg.TimeFrom, msg.TimeTo, msg.Kind, msg.Weight, msgWishValue(msg)})
 			continue
 		}
 
-		from, to := "", ""
-		if !wish.TimeFrom.IsZero() {
-			from = wish.TimeFrom.Format("15:04")
-		}
-		if !wish.TimeTo.IsZero() {
-			to = wish.TimeTo.Format("15:04")
-		}
-		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", i+2), wish.ID)
-		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", i+2), wish.TeamID)
-		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", i+2), from)
-		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", i+2), to)
+		setWishCells(f, i+2, wishRow(wish))
 	}
 
 	if wishID == -1 {
-		idx := len(wishes) + 2
-		f.SetCellValue("Sheet1", fmt.Sprintf("A%d", idx), maxID+1)
-		f.SetCellValue("Sheet1", fmt.Sprintf("B%d", idx), msg.TeamID)
-		f.SetCellValue("Sheet1", fmt.Sprintf("C%d", idx), msg.TimeFrom)
-		f.SetCellValue("Sheet1", fmt.Sprintf("D%d", idx), msg.TimeTo)
+		setWishCells(f, len(wishes)+2, []string{strconv.Itoa(maxID + 1), msg.TeamID, msg.TimeFrom, msg.TimeTo, msg.Kind, msg.Weight, msgWishValue(msg)})
 	}
 
 	f.SetActiveSheet(index)
 
-	return f.SaveAs(filepath.Join(repository.DataDir, repository.WishesFile))
+	return tt.repo.SaveFile(f, repository.WishesFile)
 }
 
 func (tt *TimetableAPI) validateWish(msg req.SaveWishRequest) error {
@@ -252,9 +219,6 @@ func (tt *TimetableAPI) validateWish(msg req.SaveWishRequest) error {
 		return errors.New("TeamID incorrect")
 	}
 
-	if msg.TimeFrom == "" && msg.TimeTo == "" {
-		return errors.New("TimeFrom and TimeTo cannot be empty both")
-	}
 	if msg.TimeFrom != "" && !pkg.ValidateTime(msg.TimeFrom) {
 		return errors.New("TimeFrom incorrect")
 	}
@@ -262,5 +226,96 @@ func (tt *TimetableAPI) validateWish(msg req.SaveWishRequest) error {
 		return errors.New("TimeTo incorrect")
 	}
 
+	kind, err := strconv.Atoi(msg.Kind)
+	if err != nil {
+		return err
+	}
+	if kind < int(ds.WishWithin) || kind > int(ds.WishNotOpponent) {
+		return errors.New("Kind incorrect")
+	}
+
+	if ds.WishKind(kind).IsTimeWish() {
+		if msg.TimeFrom == "" && msg.TimeTo == "" {
+			return errors.New("TimeFrom and TimeTo cannot be empty both")
+		}
+	} else {
+		value, err := strconv.Atoi(msgWishValue(msg))
+		if err != nil {
+			return err
+		}
+		if value < 1 {
+			return errors.New("Value incorrect")
+		}
+		if (ds.WishKind(kind) == ds.WishOpponent || ds.WishKind(kind) == ds.WishNotOpponent) && value == teamID {
+			return errors.New("Команда не может быть соперником самой себе")
+		}
+	}
+
+	if msg.Weight != "" {
+		weight, err := strconv.Atoi(msg.Weight)
+		if err != nil {
+			return err
+		}
+		if weight < 0 {
+			return errors.New("Weight incorrect")
+		}
+	}
+
 	return nil
 }
+
+func setWishesHeader(f *excelize.File) {
+	f.SetCellStr("Sheet1", "A1", "ID")
+	f.SetCellStr("Sheet1", "B1", "ID команды")
+	f.SetCellStr("Sheet1", "C1", "с")
+	f.SetCellStr("Sheet1", "D1", "по")
+	f.SetCellStr("Sheet1", "E1", "Тип (0 - только, 1 - не может, 2 - предпочитает, 3 - только поле, 4 - не на поле, 5 - только формат поля, 6 - сыграть с, 7 - не играть с)")
+	f.SetCellStr("Sheet1", "F1", "Вес")
+	f.SetCellStr("Sheet1", "G1", "Поле, формат или ID соперника")
+}
+
+func setWishCells(f *excelize.File, rowIdx int, row []string) {
+	for i, col := range []string{"A", "B", "C", "D", "E", "F", "G"} {
+		f.SetCellValue("Sheet1", fmt.Sprintf("%s%d", col, rowIdx), row[i])
+	}
+}
+
+// wishRow пожелание в виде строки файла: id, команда, с, по, тип, вес, значение
+func wishRow(w ds.Wish) []string {
+	from, to, weight, value := "", "", "", ""
+	if !w.TimeFrom.IsZero() {
+		from = w.TimeFrom.Format("15:04")
+	}
+	if !w.TimeTo.IsZero() {
+		to = w.TimeTo.Format("15:04")
+	}
+	if w.Kind == ds.WishPrefer {
+		weight = strconv.Itoa(w.Weight)
+	}
+	if !w.Kind.IsTimeWish() {
+		value = strconv.Itoa(w.Value)
+	}
+	return []string{strconv.Itoa(w.ID), strconv.Itoa(w.TeamID), from, to, strconv.Itoa(int(w.Kind)), weight, value}
+}
+
+// msgWishValue для пожеланий по сопернику значение берется из списка команд
+func msgWishValue(msg req.SaveWishRequest) string {
+	switch msg.Kind {
+	case strconv.Itoa(int(ds.WishOpponent)), strconv.Itoa(int(ds.WishNotOpponent)):
+		return msg.OpponentID
+	}
+	return msg.Value
+}
+
+// wishValueName поле, формат или соперник пожелания для отображения
+func wishValueName(w ds.Wish, teamNames map[int]string) string {
+	switch w.Kind {
+	case ds.WishField, ds.WishNotField:
+		return fmt.Sprintf("поле %d", w.Value)
+	case ds.WishFieldFormat:
+		return fmt.Sprintf("поле формата %d", w.Value)
+	case ds.WishOpponent, ds.WishNotOpponent:
+		return teamNames[w.Value]
+	}
+	return ""
+}
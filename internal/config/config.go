@@ -0,0 +1,163 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/sergrom/timetable/internal/repository"
+	"github.com/sergrom/timetable/internal/services/auth"
+	"github.com/sergrom/timetable/internal/services/searcher"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFile файл настроек по умолчанию, если его нет - работаем на значениях по умолчанию
+const DefaultFile = "timetable.yaml"
+
+// Config настройки сервера и поиска. Порядок: значения по умолчанию, файл, переменные окружения, флаги
+type Config struct {
+	Server Server            `yaml:"server"`
+	Auth   Auth              `yaml:"auth"`
+	Search searcher.Settings `yaml:"search"`
+}
+
+// Server ...
+type Server struct {
+	Addr         string `yaml:"addr"`
+	DataDir      string `yaml:"data_dir"`
+	Storage      string `yaml:"storage"`       // где справочники: xlsx-файлы или база в каталоге данных
+	WebDir       string `yaml:"web_dir"`       // шаблоны и статика
+	MaxSolutions int    `yaml:"max_solutions"` // сколько решений отдавать странице за раз
+}
+
+// Auth вход пользователей
+type Auth struct {
+	Enabled    bool          `yaml:"enabled"`
+	UsersFile  string        `yaml:"users_file"` // пусто - users.json в каталоге данных
+	SessionTTL time.Duration `yaml:"session_ttl"`
+	Basic      bool          `yaml:"basic"` // пускать скрипты по HTTP basic без входа через форму
+}
+
+// Default ...
+func Default() Config {
+	return Config{
+		Server: Server{
+			Addr:         ":8899",
+			DataDir:      repository.DataDir,
+			Storage:      repository.StorageXlsx,
+			WebDir:       "web",
+			MaxSolutions: 5000,
+		},
+		Auth: Auth{
+			Enabled:    true,
+			SessionTTL: 12 * time.Hour,
+		},
+		Search: searcher.DefaultSettings(),
+	}
+}
+
+// Load настройки из файла path и переменных окружения TIMETABLE_*
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && path == DefaultFile:
+	case err != nil:
+		return cfg, err
+	default:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&cfg); err != nil {
+			return cfg, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+// applyEnv переменные окружения поверх файла
+func (c *Config) applyEnv() error {
+	if v := os.Getenv("TIMETABLE_ADDR"); v != "" {
+		c.Server.Addr = v
+	}
+	if v := os.Getenv("TIMETABLE_DATA_DIR"); v != "" {
+		c.Server.DataDir = v
+	}
+	if v := os.Getenv("TIMETABLE_STORAGE"); v != "" {
+		c.Server.Storage = v
+	}
+	if v := os.Getenv("TIMETABLE_WEB_DIR"); v != "" {
+		c.Server.WebDir = v
+	}
+	if v := os.Getenv("TIMETABLE_AUTH_ENABLED"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("TIMETABLE_AUTH_ENABLED: %w", err)
+		}
+		c.Auth.Enabled = b
+	}
+	if v := os.Getenv("TIMETABLE_MAX_SOLUTIONS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("TIMETABLE_MAX_SOLUTIONS: %w", err)
+		}
+		c.Server.MaxSolutions = n
+	}
+	if v := os.Getenv("TIMETABLE_MAX_MEMORY_MB"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return fmt.Errorf("TIMETABLE_MAX_MEMORY_MB: %w", err)
+		}
+		c.Search.MaxMemoryMB = n
+	}
+	if v := os.Getenv("TIMETABLE_ROTATE_INTERVAL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("TIMETABLE_ROTATE_INTERVAL: %w", err)
+		}
+		c.Search.RotateInterval = d
+	}
+	return nil
+}
+
+// Validate ...
+func (c Config) Validate() error {
+	switch {
+	case c.Server.Addr == "":
+		return errors.New("server.addr: не задан адрес")
+	case c.Server.DataDir == "":
+		return errors.New("server.data_dir: не задан каталог данных")
+	case c.Server.Storage != repository.StorageXlsx && c.Server.Storage != repository.StorageDB:
+		return fmt.Errorf("server.storage: неизвестное хранилище %s, нужно %s или %s", c.Server.Storage, repository.StorageXlsx, repository.StorageDB)
+	case c.Server.MaxSolutions < 1:
+		return errors.New("server.max_solutions: должно быть больше 0")
+	case c.Auth.SessionTTL <= 0:
+		return errors.New("auth.session_ttl: должно быть больше 0")
+	case c.Search.MaxMemoryMB < 1:
+		return errors.New("search.max_memory_mb: должно быть больше 0")
+	case c.Search.RotateInterval <= 0:
+		return errors.New("search.rotate_interval: должно быть больше 0")
+	}
+	for i, l := range c.Search.NodeLimits {
+		if l.Keep < 1 {
+			return fmt.Errorf("search.node_limits[%d].keep: должно быть больше 0", i)
+		}
+	}
+	return nil
+}
+
+// UsersPath файл пользователей
+func (c Config) UsersPath() string {
+	if c.Auth.UsersFile != "" {
+		return c.Auth.UsersFile
+	}
+	return filepath.Join(c.Server.DataDir, auth.UsersFile)
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sergrom/timetable/internal/repository"
+)
+
+var envNames = []string{
+	"TIMETABLE_ADDR", "TIMETABLE_DATA_DIR", "TIMETABLE_STORAGE", "TIMETABLE_WEB_DIR",
+	"TIMETABLE_MAX_SOLUTIONS", "TIMETABLE_MAX_MEMORY_MB", "TIMETABLE_ROTATE_INTERVAL",
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string // содержимое файла, когда path не задан
+		path    string // файл вместо записанного yaml
+		env     map[string]string
+		check   func(c Config) bool
+		wantErr bool
+	}{
+		{
+			name: "no default file - defaults",
+			path: DefaultFile,
+			check: func(c Config) bool {
+				return c.Server.Addr == ":8899" && c.Server.DataDir == repository.DataDir && c.Server.Storage == repository.StorageXlsx
+			},
+		},
+		{name: "no given file", path: "нет.yaml", wantErr: true},
+		{
+			name: "file over defaults",
+			yaml: "server:\n  addr: \":9000\"\n  data_dir: /srv/timetable\n  storage: db\nsearch:\n  rotate_interval: 5s\n",
+			check: func(c Config) bool {
+				return c.Server.Addr == ":9000" && c.Server.DataDir == "/srv/timetable" && c.Server.Storage == repository.StorageDB &&
+					c.Search.RotateInterval == 5*time.Second && c.Server.MaxSolutions == 5000
+			},
+		},
+		{name: "unknown key", yaml: "server:\n  port: 9000\n", wantErr: true},
+		{name: "broken yaml", yaml: "server: [", wantErr: true},
+		{
+			name: "env over file",
+			yaml: "server:\n  addr: \":9000\"\n",
+			env: map[string]string{
+				"TIMETABLE_ADDR": ":9100", "TIMETABLE_MAX_SOLUTIONS": "10",
+				"TIMETABLE_MAX_MEMORY_MB": "512", "TIMETABLE_ROTATE_INTERVAL": "2m",
+			},
+			check: func(c Config) bool {
+				return c.Server.Addr == ":9100" && c.Server.MaxSolutions == 10 &&
+					c.Search.MaxMemoryMB == 512 && c.Search.RotateInterval == 2*time.Minute
+			},
+		},
+		{name: "bad env number", path: DefaultFile, env: map[string]string{"TIMETABLE_MAX_SOLUTIONS": "много"}, wantErr: true},
+		{name: "bad env duration", path: DefaultFile, env: map[string]string{"TIMETABLE_ROTATE_INTERVAL": "10"}, wantErr: true},
+		{name: "unknown storage", path: DefaultFile, env: map[string]string{"TIMETABLE_STORAGE": "csv"}, wantErr: true},
+		{name: "zero max solutions", yaml: "server:\n  max_solutions: 0\n", wantErr: true},
+		{name: "zero keep", yaml: "search:\n  node_limits:\n    - min_depth: 3\n      min_nodes: 10\n      keep: 0\n", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, name := range envNames {
+				t.Setenv(name, "")
+			}
+			for name, v := range tc.env {
+				t.Setenv(name, v)
+			}
+			path := tc.path
+			if path == "" {
+				path = filepath.Join(t.TempDir(), "timetable.yaml")
+				if err := os.WriteFile(path, []byte(tc.yaml), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cfg, err := Load(path)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error %v, want error %v", err, tc.wantErr)
+			}
+			if err == nil && !tc.check(cfg) {
+				t.Errorf("config %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestUsersPath(t *testing.T) {
+	tests := []struct {
+		auth Auth
+		dir  string
+		want string
+	}{
+		{dir: "data", want: filepath.Join("data", "users.json")},
+		{auth: Auth{UsersFile: "/etc/timetable/users.json"}, dir: "data", want: "/etc/timetable/users.json"},
+	}
+
+	for _, tc := range tests {
+		c := Config{Server: Server{DataDir: tc.dir}, Auth: tc.auth}
+		if got := c.UsersPath(); got != tc.want {
+			t.Errorf("users path %s, want %s", got, tc.want)
+		}
+	}
+}
@@ -1,6 +1,23 @@
 package ds
 
+import "time"
+
 type Coach struct {
-	ID   int
-	Name string
+	ID             int
+	Name           string
+	TimeFrom       time.Time     // приезжает, пусто - без ограничения
+	TimeTo         time.Time     // уезжает, пусто - без ограничения
+	MaxConsecutive int           // максимум игр подряд, 0 - без ограничения
+	BreakDur       time.Duration // перерыв, после которого игры уже не считаются подряд
+}
+
+// IsAvailable тренер на месте с from по to
+func (c *Coach) IsAvailable(from, to time.Time) bool {
+	if !c.TimeFrom.IsZero() && c.TimeFrom.After(from) {
+		return false
+	}
+	if !c.TimeTo.IsZero() && c.TimeTo.Before(to) {
+		return false
+	}
+	return true
 }
@@ -1,7 +1,8 @@
 package ds
 
 type Division struct {
-	ID     int
-	Name   string
-	Format int
+	ID        int
+	Name      string
+	Format    int
+	PlaysWith []int // дивизионы того же формата, с командами которых возможны игры
 }
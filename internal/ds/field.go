@@ -1,42 +1,49 @@
 package ds
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sergrom/timetable/internal/pkg"
 )
 
 type Field struct {
-	ID       int
-	Format   int
-	GameDur  time.Duration
-	TimeFrom time.Time // format example 9:00
-	TimeTo   time.Time // format example 21:00
-	slotsCnt int
-	slots    []int
+	ID        int
+	StadiumID int
+	Venue     string // стадион и дата, для вывода
+	Format    int
+	GameDur   time.Duration
+	TimeFrom  time.Time     // format example 9:00
+	TimeTo    time.Time     // format example 21:00
+	Buffer    time.Duration // пересменка между играми
+	Breaks    []Break       // перерывы, во время которых на поле не играют
+	starts    []time.Time
 }
 
-func NewField(id, format int, dur time.Duration, from, to string) Field {
-	var timeFrom, timeTo time.Time
-	var err error
-	timeFrom, err = pkg.ParseHM(from)
+// NewField поле с играми с from до to, время в формате 15:04. Ошибка, если время неверное
+// или в расписание не помещается от 1 до 40 игр (например, перерывы занимают весь день)
+func NewField(id, format int, dur, buffer time.Duration, from, to string, breaks []Break) (Field, error) {
+	timeFrom, err := pkg.ParseHM(from)
 	if err != nil {
-		panic(err.Error())
+		return Field{}, fmt.Errorf("неверное время начала \"%s\"", from)
 	}
-	timeTo, err = pkg.ParseHM(to)
+	timeTo, err := pkg.ParseHM(to)
 	if err != nil {
-		panic(err.Error())
+		return Field{}, fmt.Errorf("неверное время конца \"%s\"", to)
 	}
-
-	slotsCnt := int(timeTo.Sub(timeFrom).Minutes() / dur.Minutes())
-	if slotsCnt < 1 || slotsCnt > 40 {
-		panic(fmt.Sprintf("slotsCnt(%d) error. slots count must be from 1 to 40", slotsCnt))
+	if dur <= 0 {
+		return Field{}, errors.New("длительность игры должна быть больше нуля")
+	}
+	if !timeFrom.Before(timeTo) {
+		return Field{}, errors.New("начало должно быть раньше конца")
 	}
 
-	slots := make([]int, 0, slotsCnt)
-	for i := 0; i < slotsCnt; i++ {
-		slots = append(slots, i)
+	starts := calcStarts(timeFrom, timeTo, dur, buffer, breaks)
+	if len(starts) < 1 || len(starts) > 40 {
+		return Field{}, fmt.Errorf("игр на поле %d, должно быть от 1 до 40: проверьте время, длительность игры и перерывы", len(starts))
 	}
 
 	return Field{
@@ -45,30 +52,178 @@ func NewField(id, format int, dur time.Duration, from, to string) Field {
 		GameDur:  dur,
 		TimeFrom: timeFrom,
 		TimeTo:   timeTo,
-		slotsCnt: slotsCnt,
-		slots:    slots,
-	}
+		Buffer:   buffer,
+		Breaks:   breaks,
+		starts:   starts,
+	}, nil
 }
 
 func (f *Field) SlotsCnt() int {
-	return f.slotsCnt
+	return len(f.starts)
+}
+
+// GetFromTo время начала и конца игры в слоте
+func (f *Field) GetFromTo(slot int) (time.Time, time.Time) {
+	return f.starts[slot], f.starts[slot].Add(f.GameDur)
+}
+
+// MoveToDate перенести расписание поля на день date
+func (f *Field) MoveToDate(date time.Time) {
+	f.TimeFrom = pkg.OnDate(f.TimeFrom, date)
+	f.TimeTo = pkg.OnDate(f.TimeTo, date)
+	breaks := make([]Break, 0, len(f.Breaks))
+	for _, b := range f.Breaks {
+		breaks = append(breaks, Break{From: pkg.OnDate(b.From, date), To: pkg.OnDate(b.To, date)})
+	}
+	f.Breaks = breaks
+	starts := make([]time.Time, 0, len(f.starts))
+	for _, t := range f.starts {
+		starts = append(starts, pkg.OnDate(t, date))
+	}
+	f.starts = starts
+}
+
+// RestoreStarts пересчитать начала игр поля, загруженного из файла задачи
+func (f *Field) RestoreStarts() error {
+	if f.GameDur <= 0 {
+		return fmt.Errorf("поле %d: длительность игры должна быть больше нуля", f.ID)
+	}
+	f.starts = calcStarts(f.TimeFrom, f.TimeTo, f.GameDur, f.Buffer, f.Breaks)
+	if len(f.starts) < 1 || len(f.starts) > 40 {
+		return fmt.Errorf("поле %d: слотов %d, должно быть от 1 до 40", f.ID, len(f.starts))
+	}
+	return nil
+}
+
+// calcStarts время начала игр: между играми пересменка buffer, а перерыв сдвигает следующую игру на его окончание
+func calcStarts(from, to time.Time, dur, buffer time.Duration, breaks []Break) []time.Time {
+	var starts []time.Time
+	for t := from; !t.Add(dur).After(to); {
+		end := t.Add(dur)
+		resume := time.Time{} // конец последнего из перерывов, в которые попадает игра
+		for _, b := range breaks {
+			if t.Before(b.To) && b.From.Before(end) && b.To.After(resume) {
+				resume = b.To
+			}
+		}
+		if !resume.IsZero() {
+			t = resume
+			continue
+		}
+		starts = append(starts, t)
+		t = end.Add(buffer)
+	}
+	return starts
 }
 
-func (f *Field) GetSlots() []int {
-	return f.slots
+// Break перерыв на поле (обед, обслуживание, награждение)
+type Break struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+func (b Break) String() string {
+	return b.From.Format("15:04") + "-" + b.To.Format("15:04")
+}
+
+// ParseBreaks разобрать перерывы, например "13:00-13:30; 16:00-16:15"
+func ParseBreaks(str string) ([]Break, error) {
+	var breaks []Break
+	for _, part := range strings.Split(str, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fromStr, toStr, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, fmt.Errorf("перерыв \"%s\": нужно указать начало и конец", part)
+		}
+		from, err := pkg.ParseHM(strings.TrimSpace(fromStr))
+		if err != nil {
+			return nil, fmt.Errorf("перерыв \"%s\": неверное время начала", part)
+		}
+		to, err := pkg.ParseHM(strings.TrimSpace(toStr))
+		if err != nil {
+			return nil, fmt.Errorf("перерыв \"%s\": неверное время конца", part)
+		}
+		if !from.Before(to) {
+			return nil, fmt.Errorf("перерыв \"%s\": начало должно быть раньше конца", part)
+		}
+		breaks = append(breaks, Break{From: from, To: to})
+	}
+	return breaks, nil
+}
+
+// BreaksString перерывы в виде строки для хранения и редактирования
+func BreaksString(breaks []Break) string {
+	parts := make([]string, 0, len(breaks))
+	for _, b := range breaks {
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, "; ")
 }
 
 type FieldNode struct {
-	Field1           *Field
-	Field2           *Field
+	fields           []*Field
+	format           int // формат объединенного поля, для одиночного поля - формат самого поля
 	timeFrom, timeTo time.Time
+	starts           []time.Time
+}
+
+// NewFieldNode нода из одного поля
+func NewFieldNode(f *Field) *FieldNode {
+	return &FieldNode{fields: []*Field{f}, format: f.Format, starts: f.starts}
+}
+
+// NewMergedFieldNode нода из нескольких объединенных полей, перерыв любого из них - перерыв для всей ноды
+func NewMergedFieldNode(ff []*Field, format int) *FieldNode {
+	fn := &FieldNode{fields: ff, format: format}
+	var breaks []Break
+	for _, f := range ff {
+		breaks = append(breaks, f.Breaks...)
+	}
+	fn.starts = calcStarts(fn.GetTimeFrom(), fn.GetTimeTo(), fn.GameDur(), fn.Buffer(), breaks)
+	return fn
+}
+
+// IsMerged игра на ноде идет на объединенных полях
+func (fn *FieldNode) IsMerged() bool {
+	return len(fn.fields) > 1
+}
+
+// Fits команда формата format может играть на ноде.
+// На объединенном поле играют только команды его формата, на одиночном - его формата и меньше
+func (fn *FieldNode) Fits(format int) bool {
+	if fn.IsMerged() {
+		return format == fn.format
+	}
+	return format <= fn.format
 }
 
 func (fn *FieldNode) GameDur() time.Duration {
-	if fn.Field2 == nil || fn.Field1.GameDur > fn.Field2.GameDur {
-		return fn.Field1.GameDur
+	dur := fn.fields[0].GameDur
+	for _, f := range fn.fields[1:] {
+		if f.GameDur > dur {
+			dur = f.GameDur
+		}
 	}
-	return fn.Field2.GameDur
+	return dur
+}
+
+// StadiumID стадион, на котором находятся поля ноды
+func (fn *FieldNode) StadiumID() int {
+	return fn.fields[0].StadiumID
+}
+
+// Buffer пересменка между играми, для объединенных полей - наибольшая
+func (fn *FieldNode) Buffer() time.Duration {
+	buffer := fn.fields[0].Buffer
+	for _, f := range fn.fields[1:] {
+		if f.Buffer > buffer {
+			buffer = f.Buffer
+		}
+	}
+	return buffer
 }
 
 func (fn *FieldNode) GetTimeFrom() time.Time {
@@ -76,10 +231,10 @@ func (fn *FieldNode) GetTimeFrom() time.Time {
 		return fn.timeFrom
 	}
 
-	from := fn.Field1.TimeFrom
-	if fn.Field2 != nil {
-		if from2 := fn.Field2.TimeFrom; from.Before(from2) {
-			from = from2
+	from := fn.fields[0].TimeFrom
+	for _, f := range fn.fields[1:] {
+		if from.Before(f.TimeFrom) {
+			from = f.TimeFrom
 		}
 	}
 
@@ -88,10 +243,7 @@ func (fn *FieldNode) GetTimeFrom() time.Time {
 }
 
 func (fn *FieldNode) Format() int {
-	if fn.Field2 != nil {
-		return 7
-	}
-	return fn.Field1.Format
+	return fn.format
 }
 
 func (fn *FieldNode) GetTimeTo() time.Time {
@@ -99,10 +251,10 @@ func (fn *FieldNode) GetTimeTo() time.Time {
 		return fn.timeTo
 	}
 
-	to := fn.Field1.TimeTo
-	if fn.Field2 != nil {
-		if to2 := fn.Field2.TimeTo; to.After(to2) {
-			to = to2
+	to := fn.fields[0].TimeTo
+	for _, f := range fn.fields[1:] {
+		if to.After(f.TimeTo) {
+			to = f.TimeTo
 		}
 	}
 
@@ -110,27 +262,105 @@ func (fn *FieldNode) GetTimeTo() time.Time {
 	return fn.timeTo
 }
 
-func (f *FieldNode) SlotsCnt() int {
-	cnt := f.Field1.SlotsCnt()
-	if f.Field2 != nil && f.Field2.SlotsCnt() < cnt {
-		return f.Field2.SlotsCnt()
+// SlotsCnt количество игр, которое помещается на поле (для объединенных полей - в общее время работы)
+func (fn *FieldNode) SlotsCnt() int {
+	return len(fn.starts)
+}
+
+// GetFromTo время начала и конца игры в слоте
+func (fn *FieldNode) GetFromTo(slot int) (time.Time, time.Time) {
+	return fn.starts[slot], fn.starts[slot].Add(fn.GameDur())
+}
+
+// Fields поля, которые занимает игра на ноде
+func (fn *FieldNode) Fields() []*Field {
+	return fn.fields
+}
+
+// HasField игра на ноде занимает поле fieldID
+func (fn *FieldNode) HasField(fieldID int) bool {
+	for _, f := range fn.fields {
+		if f.ID == fieldID {
+			return true
+		}
 	}
-	return cnt
+	return false
 }
 
-func (fn *FieldNode) IsSlotOk(slot int) bool {
-	if slot >= fn.Field1.SlotsCnt() {
-		return false
+func (fn *FieldNode) String() string {
+	names := make([]string, 0, len(fn.fields))
+	for _, f := range fn.fields {
+		names = append(names, fmt.Sprintf("поле%d", f.ID))
 	}
-	if fn.Field2 != nil && slot >= fn.Field2.SlotsCnt() {
-		return false
+	return "[" + strings.Join(names, ",") + "]"
+}
+
+// FieldMerge поля стадиона, которые можно объединить в одно поле формата Format
+type FieldMerge struct {
+	FieldIDs []int
+	Format   int
+}
+
+func (m FieldMerge) String() string {
+	ids := make([]string, 0, len(m.FieldIDs))
+	for _, id := range m.FieldIDs {
+		ids = append(ids, strconv.Itoa(id))
 	}
-	return true
+	return strings.Join(ids, "+") + ":" + strconv.Itoa(m.Format)
 }
 
-func (fn *FieldNode) String() string {
-	if fn.Field2 == nil {
-		return fmt.Sprintf("[поле%d]", fn.Field1.ID)
+// ParseFieldMerges разобрать объединения полей, например "1+2:7; 3+4:7; 1+2+3+4:11"
+func ParseFieldMerges(str string) ([]FieldMerge, error) {
+	var merges []FieldMerge
+	for _, part := range strings.Split(str, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		idsStr, formatStr, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, fmt.Errorf("объединение \"%s\": не указан формат", part)
+		}
+		format, err := strconv.Atoi(strings.TrimSpace(formatStr))
+		if err != nil || format < 3 || format > 11 {
+			return nil, fmt.Errorf("объединение \"%s\": формат должен быть от 3 до 11", part)
+		}
+		m := FieldMerge{Format: format}
+		seen := make(map[int]bool)
+		for _, idStr := range strings.Split(idsStr, "+") {
+			id, err := strconv.Atoi(strings.TrimSpace(idStr))
+			if err != nil || id < 1 {
+				return nil, fmt.Errorf("объединение \"%s\": неверный номер поля", part)
+			}
+			if seen[id] {
+				return nil, fmt.Errorf("объединение \"%s\": поле %d указано дважды", part, id)
+			}
+			seen[id] = true
+			m.FieldIDs = append(m.FieldIDs, id)
+		}
+		if len(m.FieldIDs) < 2 {
+			return nil, fmt.Errorf("объединение \"%s\": нужно хотя бы два поля", part)
+		}
+		merges = append(merges, m)
+	}
+	return merges, nil
+}
+
+// FieldMergesString объединения полей в виде строки для хранения и редактирования
+func FieldMergesString(merges []FieldMerge) string {
+	parts := make([]string, 0, len(merges))
+	for _, m := range merges {
+		parts = append(parts, m.String())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// DefaultFieldMerges объединения полей для стадионов, записанных до колонки объединений:
+// как раньше, поля 1+2 и 3+4 объединяются в поля формата 7, если на стадионе столько полей есть
+func DefaultFieldMerges(fields int) []FieldMerge {
+	var merges []FieldMerge
+	for id := 1; id+1 <= fields && id <= 3; id += 2 {
+		merges = append(merges, FieldMerge{FieldIDs: []int{id, id + 1}, Format: 7})
 	}
-	return fmt.Sprintf("[поле%d,поле%d]", fn.Field1.ID, fn.Field2.ID)
+	return merges
 }
@@ -0,0 +1,233 @@
+package ds
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sergrom/timetable/internal/pkg"
+)
+
+func hm(t *testing.T, s string) time.Time {
+	t.Helper()
+	v, err := pkg.ParseHM(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func mustBreaks(t *testing.T, s string) []Break {
+	t.Helper()
+	breaks, err := ParseBreaks(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return breaks
+}
+
+func mustField(t *testing.T, id int, dur, buffer time.Duration, from, to string, breaks []Break) Field {
+	t.Helper()
+	f, err := NewField(id, 6, dur, buffer, from, to, breaks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestCalcStarts(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		dur, buf time.Duration
+		breaks   string
+		want     []string
+	}{
+		{name: "games back to back", from: "09:00", to: "12:00", dur: time.Hour, want: []string{"09:00", "10:00", "11:00"}},
+		{
+			name: "buffer between games",
+			from: "09:00", to: "12:00", dur: 50 * time.Minute, buf: 10 * time.Minute,
+			want: []string{"09:00", "10:00", "11:00"},
+		},
+		{
+			name: "last game must end before the field closes",
+			from: "09:00", to: "11:30", dur: 50 * time.Minute, buf: 10 * time.Minute,
+			want: []string{"09:00", "10:00"},
+		},
+		{
+			name: "no buffer after the last game is needed",
+			from: "09:00", to: "10:50", dur: 50 * time.Minute, buf: 10 * time.Minute,
+			want: []string{"09:00", "10:00"},
+		},
+		{
+			name: "buffer is not added after a break",
+			from: "09:00", to: "13:00", dur: 50 * time.Minute, buf: 10 * time.Minute, breaks: "10:00-11:00",
+			want: []string{"09:00", "11:00", "12:00"},
+		},
+		{
+			name: "break skips its slot",
+			from: "09:00", to: "13:00", dur: time.Hour, breaks: "10:00-11:00",
+			want: []string{"09:00", "11:00", "12:00"},
+		},
+		{
+			name: "two breaks",
+			from: "09:00", to: "14:00", dur: time.Hour, breaks: "10:00-11:00; 12:00-13:00",
+			want: []string{"09:00", "11:00", "13:00"},
+		},
+		{
+			name: "break moves the next game to its end",
+			from: "09:00", to: "13:00", dur: 50 * time.Minute, buf: 10 * time.Minute, breaks: "10:00-10:30",
+			want: []string{"09:00", "10:30", "11:30"},
+		},
+		{
+			name: "overlapping breaks",
+			from: "09:00", to: "13:00", dur: time.Hour, breaks: "09:30-10:00; 09:45-11:00",
+			want: []string{"11:00", "12:00"},
+		},
+		{name: "break covers the whole day", from: "09:00", to: "12:00", dur: time.Hour, breaks: "08:00-13:00"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			starts := calcStarts(hm(t, tc.from), hm(t, tc.to), tc.dur, tc.buf, mustBreaks(t, tc.breaks))
+			var got []string
+			for _, s := range starts {
+				got = append(got, s.Format("15:04"))
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("starts %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewMergedFieldNode(t *testing.T) {
+	f1 := mustField(t, 1, 50*time.Minute, 10*time.Minute, "09:00", "13:00", nil)
+	f2 := mustField(t, 2, time.Hour, 0, "09:30", "14:00", mustBreaks(t, "11:45-13:00"))
+	fn := NewMergedFieldNode([]*Field{&f1, &f2}, 7)
+
+	// общее время полей, самая долгая игра и пересменка, перерывы обоих полей
+	if got := fn.GetTimeFrom().Format("15:04") + "-" + fn.GetTimeTo().Format("15:04"); got != "09:30-13:00" {
+		t.Errorf("time %s, want 09:30-13:00", got)
+	}
+	if fn.GameDur() != time.Hour || fn.Buffer() != 10*time.Minute {
+		t.Errorf("game %v, buffer %v, want 1h and 10m", fn.GameDur(), fn.Buffer())
+	}
+	var starts []string
+	for slot := 0; slot < fn.SlotsCnt(); slot++ {
+		from, _ := fn.GetFromTo(slot)
+		starts = append(starts, from.Format("15:04"))
+	}
+	if want := []string{"09:30", "10:40"}; !reflect.DeepEqual(starts, want) {
+		t.Errorf("starts %v, want %v", starts, want)
+	}
+	if !fn.Fits(7) || fn.Fits(6) {
+		t.Error("merged field must fit only its own format")
+	}
+}
+
+func TestNewField(t *testing.T) {
+	tests := []struct {
+		name      string
+		from, to  string
+		dur       time.Duration
+		breaks    string
+		wantSlots int
+		wantErr   bool
+	}{
+		{name: "ok", from: "09:00", to: "12:00", dur: time.Hour, wantSlots: 3},
+		{name: "bad start", from: "9", to: "12:00", dur: time.Hour, wantErr: true},
+		{name: "bad end", from: "09:00", to: "полдень", dur: time.Hour, wantErr: true},
+		{name: "no duration", from: "09:00", to: "12:00", wantErr: true},
+		{name: "end before start", from: "12:00", to: "09:00", dur: time.Hour, wantErr: true},
+		{name: "no game fits", from: "09:00", to: "09:30", dur: time.Hour, wantErr: true},
+		{name: "break covers the whole window", from: "09:00", to: "20:00", dur: time.Hour, breaks: "08:00-21:00", wantErr: true},
+		{name: "too many games", from: "00:00", to: "23:00", dur: 10 * time.Minute, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			f, err := NewField(1, 6, tc.dur, 0, tc.from, tc.to, mustBreaks(t, tc.breaks))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error %v, want error %v", err, tc.wantErr)
+			}
+			if err == nil && f.SlotsCnt() != tc.wantSlots {
+				t.Errorf("slots %d, want %d", f.SlotsCnt(), tc.wantSlots)
+			}
+		})
+	}
+}
+
+func TestParseBreaks(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    string
+		wantErr bool
+	}{
+		{str: "", want: ""},
+		{str: " ; ", want: ""},
+		{str: "13:00-13:30", want: "13:00-13:30"},
+		{str: "13:00 - 13:30;16:00-16:15;", want: "13:00-13:30; 16:00-16:15"},
+		{str: "13:00", wantErr: true},
+		{str: "13:00-", wantErr: true},
+		{str: "x-13:30", wantErr: true},
+		{str: "13:30-13:00", wantErr: true},
+		{str: "13:00-13:00", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.str, func(t *testing.T) {
+			breaks, err := ParseBreaks(tc.str)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error %v, want error %v", err, tc.wantErr)
+			}
+			if err == nil && BreaksString(breaks) != tc.want {
+				t.Errorf("breaks %q, want %q", BreaksString(breaks), tc.want)
+			}
+		})
+	}
+}
+
+func TestParseFieldMerges(t *testing.T) {
+	tests := []struct {
+		str     string
+		want    []FieldMerge
+		wantStr string // как объединения записываются обратно
+		wantErr bool
+	}{
+		{str: "", want: nil},
+		{str: "1+2:7", want: []FieldMerge{{FieldIDs: []int{1, 2}, Format: 7}}, wantStr: "1+2:7"},
+		{
+			str: " 1 + 2 : 7; 3+4:7;1+2+3+4:11 ;",
+			want: []FieldMerge{
+				{FieldIDs: []int{1, 2}, Format: 7},
+				{FieldIDs: []int{3, 4}, Format: 7},
+				{FieldIDs: []int{1, 2, 3, 4}, Format: 11},
+			},
+			wantStr: "1+2:7; 3+4:7; 1+2+3+4:11",
+		},
+		{str: "1+2", wantErr: true},
+		{str: "1+2:x", wantErr: true},
+		{str: "1+2:2", wantErr: true},
+		{str: "1+2:12", wantErr: true},
+		{str: "1:7", wantErr: true},
+		{str: "1+1:7", wantErr: true},
+		{str: "0+1:7", wantErr: true},
+		{str: "1+a:7", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.str, func(t *testing.T) {
+			got, err := ParseFieldMerges(tc.str)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error %v, want error %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("merges %v, want %v", got, tc.want)
+			}
+			if s := FieldMergesString(got); s != tc.wantStr {
+				t.Errorf("string %q, want %q", s, tc.wantStr)
+			}
+		})
+	}
+}
@@ -1,9 +1,41 @@
 package ds
 
+type GameStatus int
+
+const (
+	GameScheduled GameStatus = iota // результат не внесен
+	GamePlayed                      // сыграна
+	GameForfeit                     // техническое поражение, счет вносится вручную
+	GameCancelled                   // отменена, в таблице не учитывается
+)
+
 type Game struct {
 	ID         int
 	Tour       string
 	TeamID1    int
 	TeamID2    int
 	CanRematch int
+	Score1     int
+	Score2     int
+	Status     GameStatus
+	Date       string // дата игры "2006-01-02", может быть пустой
+}
+
+// String ...
+func (s GameStatus) String() string {
+	switch s {
+	case GamePlayed:
+		return "Сыграна"
+	case GameForfeit:
+		return "Тех. поражение"
+	case GameCancelled:
+		return "Отменена"
+	default:
+		return "Не сыграна"
+	}
+}
+
+// HasResult результат игры учитывается в турнирной таблице
+func (g Game) HasResult() bool {
+	return g.Status == GamePlayed || g.Status == GameForfeit
 }
@@ -0,0 +1,32 @@
+package ds
+
+import (
+	"time"
+
+	"github.com/sergrom/timetable/internal/pkg"
+)
+
+type Referee struct {
+	ID       int
+	Name     string
+	Formats  []int     // форматы, которые судья может судить, пусто - любые
+	TimeFrom time.Time // может судить с, пусто - без ограничения
+	TimeTo   time.Time // может судить по, пусто - без ограничения
+	MaxGames int       // максимум игр за день, 0 - без ограничения
+}
+
+// CanJudge ...
+func (r *Referee) CanJudge(format int) bool {
+	return len(r.Formats) == 0 || pkg.SliceContain(format, r.Formats)
+}
+
+// IsAvailable судья может судить игру с from по to
+func (r *Referee) IsAvailable(from, to time.Time) bool {
+	if !r.TimeFrom.IsZero() && r.TimeFrom.After(from) {
+		return false
+	}
+	if !r.TimeTo.IsZero() && r.TimeTo.Before(to) {
+		return false
+	}
+	return true
+}
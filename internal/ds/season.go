@@ -0,0 +1,10 @@
+package ds
+
+// SeasonGame игра из плана сезона, первая команда - хозяин
+type SeasonGame struct {
+	ID      int
+	Tour    string
+	Date    string // дата тура "2006-01-02", может быть пустой
+	TeamID1 int
+	TeamID2 int
+}
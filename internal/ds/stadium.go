@@ -10,4 +10,7 @@ type Stadium struct {
 	TimeFrom time.Time
 	TimeTo   time.Time
 	GameDur  time.Duration
+	Merges   []FieldMerge  // какие поля можно объединять в поле большего формата
+	Breaks   []Break       // перерывы для всех полей стадиона
+	Buffer   time.Duration // пересменка между играми
 }
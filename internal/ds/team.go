@@ -7,6 +7,7 @@ type Team struct {
 	Name       string
 	CoachID    int
 	DivisionID int
+	Rating     int // 0 - рейтинг не задан
 }
 
 type TeamPair struct {
@@ -2,20 +2,63 @@ package ds
 
 import "time"
 
+type WishKind int
+
+const (
+	WishWithin      WishKind = iota // играть только в промежутке
+	WishNotDuring                   // не может играть в промежутке
+	WishPrefer                      // хочет играть поближе ко времени (мягкое)
+	WishField                       // играть только на поле
+	WishNotField                    // не играть на поле
+	WishFieldFormat                 // играть только на полях формата
+	WishOpponent                    // обязательно сыграть с соперником
+	WishNotOpponent                 // не играть с соперником
+)
+
+const DefaultWishWeight = 3
+
 type Wish struct {
 	ID       int
 	TeamID   int
+	Kind     WishKind
 	TimeFrom time.Time
 	TimeTo   time.Time
+	Weight   int // только для WishPrefer: штраф за каждую продолжительность игры от желаемого времени
+	Value    int // номер поля, формат поля или ID соперника - в зависимости от Kind
+}
+
+// String ...
+func (k WishKind) String() string {
+	switch k {
+	case WishNotDuring:
+		return "Не может"
+	case WishPrefer:
+		return "Предпочитает"
+	case WishField, WishFieldFormat:
+		return "Только на"
+	case WishNotField:
+		return "Не на"
+	case WishOpponent:
+		return "Сыграть с"
+	case WishNotOpponent:
+		return "Не играть с"
+	default:
+		return "Только"
+	}
+}
+
+// IsTimeWish пожелание по времени, иначе - по полю или сопернику
+func (k WishKind) IsTimeWish() bool {
+	return k <= WishPrefer
 }
 
-func (w *Wish) IsSlotOk(fieldStart time.Time, gameDur time.Duration, slotIdx int) bool {
-	from, to := fieldStart.Add(gameDur*time.Duration(slotIdx)), fieldStart.Add(gameDur*time.Duration(slotIdx+1))
-	if !w.TimeFrom.IsZero() && w.TimeFrom.After(from) {
-		return false
+// Point желаемое время для WishPrefer - середина промежутка или заданная граница
+func (w *Wish) Point() time.Time {
+	if w.TimeFrom.IsZero() {
+		return w.TimeTo
 	}
-	if !w.TimeTo.IsZero() && w.TimeTo.Before(to) {
-		return false
+	if w.TimeTo.IsZero() {
+		return w.TimeFrom
 	}
-	return true
+	return w.TimeFrom.Add(w.TimeTo.Sub(w.TimeFrom) / 2)
 }
@@ -1,5 +1,10 @@
 package pkg
 
+import (
+	"strconv"
+	"strings"
+)
+
 func SliceContain(n int, sl []int) bool {
 	for _, el := range sl {
 		if el == n {
@@ -8,3 +13,12 @@ func SliceContain(n int, sl []int) bool {
 	}
 	return false
 }
+
+// JoinInts ...
+func JoinInts(nums []int, sep string) string {
+	strs := make([]string, 0, len(nums))
+	for _, n := range nums {
+		strs = append(strs, strconv.Itoa(n))
+	}
+	return strings.Join(strs, sep)
+}
@@ -30,3 +30,24 @@ func ParseHM(tStr string) (time.Time, error) {
 func ValidateTime(tStr string) bool {
 	return re.MatchString(tStr)
 }
+
+// ParseDate разобрать дату в формате 2006-01-02, пустая строка - сегодня
+func ParseDate(dStr string) (time.Time, error) {
+	if dStr == "" {
+		return time.Date(Now.Year(), Now.Month(), Now.Day(), 0, 0, 0, 0, Now.Location()), nil
+	}
+	return time.ParseInLocation("2006-01-02", dStr, Now.Location())
+}
+
+// OnDate время t в день date
+func OnDate(t, date time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// Clock время дня t, перенесенное на сегодня, чтобы сравнивать его со временем из ParseHM
+func Clock(t time.Time) time.Time {
+	return OnDate(t, Now)
+}
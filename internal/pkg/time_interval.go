@@ -75,12 +75,17 @@ func (ig *IvGroup) Exclude(from, to time.Time) {
 		if ig.intervals[i].from.Add(100*time.Millisecond).After(to) || ig.intervals[i].to.Add(-100*time.Millisecond).Before(from) {
 			continue
 		}
+		if !from.After(ig.intervals[i].from) && !to.Before(ig.intervals[i].to) {
+			// исключаем целиком
+			ig.intervals[i].from, ig.intervals[i].to = time.Time{}, time.Time{}
+			continue
+		}
 		if from.After(ig.intervals[i].from.Add(-100*time.Millisecond)) && to.Before(ig.intervals[i].to.Add(100*time.Millisecond)) {
 			// выкалываем из середины
-			newIntervals = []tInterval{
-				{from: ig.intervals[i].from, to: from},
-				{from: to, to: ig.intervals[i].to},
-			}
+			newIntervals = append(newIntervals,
+				tInterval{from: ig.intervals[i].from, to: from},
+				tInterval{from: to, to: ig.intervals[i].to},
+			)
 			ig.intervals[i].from, ig.intervals[i].to = time.Time{}, time.Time{}
 			continue
 		}
@@ -118,7 +123,7 @@ func (ig *IvGroup) PlaceDurationNear(dur time.Duration, point time.Time) (time.T
 			half := dur / 2
 			if point.Add(-half).Before(ig.intervals[i].from) {
 				return ig.intervals[i].from, 0
-			} else if point.Add(half).After(ig.intervals[i].from) {
+			} else if point.Add(half).After(ig.intervals[i].to) {
 				return ig.intervals[i].to.Add(-dur), 0
 			} else {
 				return point.Add(-half), 0
@@ -3,11 +3,12 @@ package repository
 import (
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 
 	"github.com/sergrom/timetable/internal/ds"
+	"github.com/xuri/excelize/v2"
 )
 
 const (
@@ -19,19 +20,85 @@ const (
 	StadiumsFile  = "Стадионы.xlsx"
 	TeamsFile     = "Команды.xlsx"
 	WishesFile    = "Пожелания.xlsx"
+	RefereesFile  = "Судьи.xlsx"
+	SeasonFile    = "Сезон.xlsx"
 	UploadedFile  = "uploaded.xlsx"
 )
 
-type Repo struct{}
+type Repo struct {
+	dir string
+	st  Storage
+}
+
+// NewRepo справочники в xlsx-файлах каталога данных dir
+func NewRepo(dir string) *Repo {
+	return &Repo{dir: dir, st: NewXlsxStorage(dir)}
+}
+
+// OpenRepo справочники в хранилище kind: xlsx-файлы или база в каталоге данных dir
+func OpenRepo(kind, dir string) (*Repo, error) {
+	st, err := OpenStorage(kind, dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Repo{dir: dir, st: st}, nil
+}
+
+// Close закрыть хранилище
+func (r *Repo) Close() error {
+	return r.st.Close()
+}
 
-// NewRepo ...
-func NewRepo() *Repo {
-	return &Repo{}
+// Path путь к файлу справочника в каталоге данных
+func (r *Repo) Path(file string) string {
+	return filepath.Join(r.dir, file)
+}
+
+// ReadTable строки таблицы справочника как есть, вместе с заголовком
+func (r *Repo) ReadTable(t Table) ([][]string, error) {
+	return r.st.ReadTable(t)
+}
+
+// WriteTable перезаписать таблицу справочника строками как есть
+func (r *Repo) WriteTable(t Table, rows [][]string) error {
+	return r.st.WriteTable(t, rows)
+}
+
+// SaveFile сохранить таблицу, собранную в первом листе f. В xlsx-хранилище файл
+// сохраняется как есть, в базу пишутся строки листа
+func (r *Repo) SaveFile(f *excelize.File, file string) error {
+	t, err := tableByFile(file)
+	if err != nil {
+		return err
+	}
+	if xs, ok := r.st.(*XlsxStorage); ok {
+		return f.SaveAs(filepath.Join(xs.dir, t.File))
+	}
+
+	rows, err := f.GetRows(f.GetSheetList()[0])
+	if err != nil {
+		return err
+	}
+	for i := range rows {
+		for j := range rows[i] {
+			rows[i][j] = strings.TrimSpace(rows[i][j])
+		}
+	}
+	return r.st.WriteTable(t, rows)
+}
+
+// readTable строки таблицы по имени ее xlsx-файла
+func (r *Repo) readTable(file string) ([][]string, error) {
+	t, err := tableByFile(file)
+	if err != nil {
+		return nil, err
+	}
+	return r.st.ReadTable(t)
 }
 
 // GetDivisions ...
 func (r *Repo) GetDivisions() ([]ds.Division, error) {
-	data, err := r.readFile(filepath.Join(DataDir, DivisionsFile))
+	data, err := r.readTable(DivisionsFile)
 	if err != nil {
 		return nil, err
 	}
@@ -45,23 +112,11 @@ func (r *Repo) GetDivisions() ([]ds.Division, error) {
 		if len(row) < 3 || strings.ToLower(row[0]) == "id" {
 			continue
 		}
-		idStr, dName, formatStr := strings.TrimSpace(row[0]), strings.TrimSpace(row[1]), strings.TrimSpace(row[2])
-		if formatStr == "" {
-			continue
-		}
-		id, err := strconv.Atoi(idStr)
+		d, err := r.getDivision(row)
 		if err != nil {
 			continue
 		}
-		format, err := strconv.Atoi(formatStr)
-		if err != nil {
-			continue
-		}
-		divisions = append(divisions, ds.Division{
-			ID:     id,
-			Name:   dName,
-			Format: format,
-		})
+		divisions = append(divisions, d)
 	}
 
 	return divisions, nil
@@ -83,7 +138,7 @@ func (r *Repo) GetDivisionsMap() (map[int]ds.Division, error) {
 
 // GetCoaches ...
 func (r *Repo) GetCoaches() ([]ds.Coach, error) {
-	data, err := r.readFile(filepath.Join(DataDir, CoachesFile))
+	data, err := r.readTable(CoachesFile)
 	if err != nil {
 		return nil, err
 	}
@@ -97,19 +152,11 @@ func (r *Repo) GetCoaches() ([]ds.Coach, error) {
 		if len(row) < 2 || strings.ToLower(row[0]) == "id" {
 			continue
 		}
-		idStr, cName := strings.TrimSpace(row[0]), strings.TrimSpace(row[1])
-		if idStr == "" || cName == "" {
-			continue
-		}
-		id, err := strconv.Atoi(idStr)
+		c, err := r.getCoach(row)
 		if err != nil {
 			continue
 		}
-
-		coaches = append(coaches, ds.Coach{
-			ID:   id,
-			Name: cName,
-		})
+		coaches = append(coaches, c)
 	}
 
 	return coaches, nil
@@ -131,7 +178,7 @@ func (r *Repo) GetCoachesMap() (map[int]ds.Coach, error) {
 
 // GetStadiums ...
 func (r *Repo) GetStadiums() ([]ds.Stadium, error) {
-	data, err := r.readFile(filepath.Join(DataDir, StadiumsFile))
+	data, err := r.readTable(StadiumsFile)
 	if err != nil {
 		return nil, err
 	}
@@ -140,15 +187,24 @@ func (r *Repo) GetStadiums() ([]ds.Stadium, error) {
 		return nil, errors.New("empty Stadium's list")
 	}
 
+	// в файлах, записанных до колонки объединений полей, ее нет в заголовке: поля объединяются, как раньше
+	legacy := false
 	stads := make([]ds.Stadium, 0, len(data))
 	for _, row := range data {
-		if len(row) < 7 || strings.ToLower(row[0]) == "id" {
+		if len(row) > 0 && strings.ToLower(row[0]) == "id" {
+			legacy = len(row) < 8
+			continue
+		}
+		if len(row) < 7 {
 			continue
 		}
 		st, err := r.getStadium(row)
 		if err != nil {
 			continue
 		}
+		if legacy {
+			st.Merges = ds.DefaultFieldMerges(st.Fields)
+		}
 		stads = append(stads, st)
 	}
 
@@ -156,7 +212,7 @@ func (r *Repo) GetStadiums() ([]ds.Stadium, error) {
 }
 
 func (r *Repo) GetTeams() ([]ds.Team, error) {
-	data, err := r.readFile(filepath.Join(DataDir, TeamsFile))
+	data, err := r.readTable(TeamsFile)
 	if err != nil {
 		return nil, err
 	}
@@ -195,7 +251,7 @@ func (r *Repo) GetTeamsMap() (map[int]ds.Team, error) {
 }
 
 func (r *Repo) GetWishes() ([]ds.Wish, error) {
-	data, err := r.readFile(filepath.Join(DataDir, WishesFile))
+	data, err := r.readTable(WishesFile)
 	if err != nil {
 		return nil, err
 	}
@@ -221,7 +277,7 @@ func (r *Repo) GetWishes() ([]ds.Wish, error) {
 }
 
 func (r *Repo) GetGames() ([]ds.Game, error) {
-	data, err := r.readFile(filepath.Join(DataDir, GamesFile))
+	data, err := r.readTable(GamesFile)
 	if err != nil {
 		return nil, err
 	}
@@ -246,6 +302,58 @@ func (r *Repo) GetGames() ([]ds.Game, error) {
 	return games, nil
 }
 
+// GetReferees список судей, если файла со судьями нет - пустой список
+func (r *Repo) GetReferees() ([]ds.Referee, error) {
+	data, err := r.readTable(RefereesFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return []ds.Referee{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	referees := make([]ds.Referee, 0, len(data))
+	for _, row := range data {
+		if len(row) < 2 || strings.ToLower(row[0]) == "id" {
+			continue
+		}
+		ref, err := r.getReferee(row)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		referees = append(referees, ref)
+	}
+
+	return referees, nil
+}
+
+// GetSeason план сезона, если плана нет - пустой список
+func (r *Repo) GetSeason() ([]ds.SeasonGame, error) {
+	data, err := r.readTable(SeasonFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return []ds.SeasonGame{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	games := make([]ds.SeasonGame, 0, len(data))
+	for _, row := range data {
+		if len(row) < 5 || strings.ToLower(row[0]) == "id" {
+			continue
+		}
+		g, err := r.getSeasonGame(row)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		games = append(games, g)
+	}
+
+	return games, nil
+}
+
 // func (r *Repo) UplodStadiums() error {
 // 	data, err := r.readFile(filepath.Join(DataDir, UploadedFile))
 // 	if err != nil {
@@ -44,6 +44,27 @@ func (r *Repo) getStadium(row []string) (ds.Stadium, error) {
 	if gameDur <= 0 || gameDur > 150 {
 		return ds.Stadium{}, errors.New("gameDuration must be (0;150]")
 	}
+	var merges []ds.FieldMerge
+	if len(row) > 7 {
+		merges, err = ds.ParseFieldMerges(row[7])
+		if err != nil {
+			return ds.Stadium{}, errors.New("invalid merges")
+		}
+	}
+	var breaks []ds.Break
+	if len(row) > 8 {
+		breaks, err = ds.ParseBreaks(row[8])
+		if err != nil {
+			return ds.Stadium{}, errors.New("invalid breaks")
+		}
+	}
+	buffer := 0
+	if len(row) > 9 && strings.TrimSpace(row[9]) != "" {
+		buffer, err = strconv.Atoi(strings.TrimSpace(row[9]))
+		if err != nil {
+			return ds.Stadium{}, errors.New("buffer is not integer")
+		}
+	}
 
 	return ds.Stadium{
 		ID:       id,
@@ -53,6 +74,86 @@ func (r *Repo) getStadium(row []string) (ds.Stadium, error) {
 		TimeFrom: timeFrom,
 		TimeTo:   timeTo,
 		GameDur:  time.Duration(gameDur) * time.Minute,
+		Merges:   merges,
+		Breaks:   breaks,
+		Buffer:   time.Duration(buffer) * time.Minute,
+	}, nil
+}
+
+func (r *Repo) getDivision(row []string) (ds.Division, error) {
+	idStr, dName, formatStr := strings.TrimSpace(row[0]), strings.TrimSpace(row[1]), strings.TrimSpace(row[2])
+	if formatStr == "" {
+		return ds.Division{}, errors.New("empty col")
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return ds.Division{}, errors.New("id is not integer")
+	}
+	format, err := strconv.Atoi(formatStr)
+	if err != nil {
+		return ds.Division{}, errors.New("format is not integer")
+	}
+	var playsWith []int
+	if len(row) > 3 {
+		playsWith, err = parseIntList(row[3])
+		if err != nil {
+			return ds.Division{}, errors.New("playsWith is not list of integers")
+		}
+	}
+
+	return ds.Division{
+		ID:        id,
+		Name:      dName,
+		Format:    format,
+		PlaysWith: playsWith,
+	}, nil
+}
+
+func (r *Repo) getCoach(row []string) (ds.Coach, error) {
+	cell := func(i int) string {
+		if len(row) > i {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	idStr, cName, timeFromStr, timeToStr, maxConsecutiveStr, breakStr := cell(0), cell(1), cell(2), cell(3), cell(4), cell(5)
+
+	if idStr == "" || cName == "" {
+		return ds.Coach{}, errors.New("empty col")
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return ds.Coach{}, errors.New("id is not integer")
+	}
+	timeFrom, err := pkg.ParseHM(timeFromStr)
+	if err != nil {
+		return ds.Coach{}, errors.New("finvalid timeFrom")
+	}
+	timeTo, err := pkg.ParseHM(timeToStr)
+	if err != nil {
+		return ds.Coach{}, errors.New("finvalid timeTo")
+	}
+	maxConsecutive, breakMin := 0, 0
+	if maxConsecutiveStr != "" {
+		maxConsecutive, err = strconv.Atoi(maxConsecutiveStr)
+		if err != nil {
+			return ds.Coach{}, errors.New("maxConsecutive is not integer")
+		}
+	}
+	if breakStr != "" {
+		breakMin, err = strconv.Atoi(breakStr)
+		if err != nil {
+			return ds.Coach{}, errors.New("break is not integer")
+		}
+	}
+
+	return ds.Coach{
+		ID:             id,
+		Name:           cName,
+		TimeFrom:       timeFrom,
+		TimeTo:         timeTo,
+		MaxConsecutive: maxConsecutive,
+		BreakDur:       time.Duration(breakMin) * time.Minute,
 	}, nil
 }
 
@@ -76,12 +177,20 @@ func (r *Repo) getTeam(row []string) (ds.Team, error) {
 	if err != nil {
 		return ds.Team{}, errors.New("divisionID is not integer")
 	}
+	rating := 0
+	if len(row) > 4 && strings.TrimSpace(row[4]) != "" {
+		rating, err = strconv.Atoi(strings.TrimSpace(row[4]))
+		if err != nil {
+			return ds.Team{}, errors.New("rating is not integer")
+		}
+	}
 
 	return ds.Team{
 		ID:         id,
 		Name:       sName,
 		CoachID:    coachID,
 		DivisionID: divID,
+		Rating:     rating,
 	}, nil
 }
 
@@ -103,9 +212,33 @@ func (r *Repo) getWish(row []string) (ds.Wish, error) {
 		return ds.Wish{}, errors.New("teamId is not integer")
 	}
 
-	if timeFrom == "" && timeTo == "" {
+	kind, weight, value := ds.WishWithin, ds.DefaultWishWeight, 0
+	if len(row) > 4 && strings.TrimSpace(row[4]) != "" {
+		k, err := strconv.Atoi(strings.TrimSpace(row[4]))
+		if err != nil || k < int(ds.WishWithin) || k > int(ds.WishNotOpponent) {
+			return ds.Wish{}, errors.New("invalid kind")
+		}
+		kind = ds.WishKind(k)
+	}
+	if len(row) > 5 && strings.TrimSpace(row[5]) != "" {
+		weight, err = strconv.Atoi(strings.TrimSpace(row[5]))
+		if err != nil {
+			return ds.Wish{}, errors.New("weight is not integer")
+		}
+	}
+	if len(row) > 6 && strings.TrimSpace(row[6]) != "" {
+		value, err = strconv.Atoi(strings.TrimSpace(row[6]))
+		if err != nil {
+			return ds.Wish{}, errors.New("value is not integer")
+		}
+	}
+
+	if kind.IsTimeWish() && timeFrom == "" && timeTo == "" {
 		return ds.Wish{}, errors.New("'timeFrom' and 'timeTo' cannot be both empty")
 	}
+	if !kind.IsTimeWish() && value == 0 {
+		return ds.Wish{}, errors.New("empty value")
+	}
 
 	from, to := time.Time{}, time.Time{}
 
@@ -127,14 +260,23 @@ func (r *Repo) getWish(row []string) (ds.Wish, error) {
 	return ds.Wish{
 		ID:       id,
 		TeamID:   tId,
+		Kind:     kind,
 		TimeFrom: from,
 		TimeTo:   to,
+		Weight:   weight,
+		Value:    value,
 	}, nil
 }
 
 func (r *Repo) getGame(row []string) (ds.Game, error) {
-	idStr, tourName, team1, team2, rematchStr :=
-		strings.TrimSpace(row[0]), strings.TrimSpace(row[1]), strings.TrimSpace(row[2]), strings.TrimSpace(row[3]), strings.TrimSpace(row[4])
+	cell := func(i int) string {
+		if len(row) > i {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	idStr, tourName, team1, team2, rematchStr := cell(0), cell(1), cell(2), cell(3), cell(4)
+	score1Str, score2Str, statusStr, date := cell(5), cell(6), cell(7), cell(8)
 
 	gameID, err := strconv.Atoi(idStr)
 	if err != nil {
@@ -152,11 +294,125 @@ func (r *Repo) getGame(row []string) (ds.Game, error) {
 	if err != nil {
 		return ds.Game{}, errors.New("rematch is not integer")
 	}
-	return ds.Game{
+
+	// результат игры - необязательные колонки
+	game := ds.Game{
 		ID:         gameID,
 		Tour:       tourName,
 		TeamID1:    id1,
 		TeamID2:    id2,
 		CanRematch: rematch,
+		Date:       date,
+	}
+	if score1Str != "" || score2Str != "" {
+		if game.Score1, err = strconv.Atoi(score1Str); err != nil {
+			return ds.Game{}, errors.New("score1 is not integer")
+		}
+		if game.Score2, err = strconv.Atoi(score2Str); err != nil {
+			return ds.Game{}, errors.New("score2 is not integer")
+		}
+	}
+	if statusStr != "" {
+		status, err := strconv.Atoi(statusStr)
+		if err != nil || status < int(ds.GameScheduled) || status > int(ds.GameCancelled) {
+			return ds.Game{}, errors.New("invalid status")
+		}
+		game.Status = ds.GameStatus(status)
+	}
+	if _, err := pkg.ParseDate(date); err != nil {
+		return ds.Game{}, errors.New("invalid date")
+	}
+
+	return game, nil
+}
+
+func (r *Repo) getSeasonGame(row []string) (ds.SeasonGame, error) {
+	idStr, tourName, date, team1, team2 :=
+		strings.TrimSpace(row[0]), strings.TrimSpace(row[1]), strings.TrimSpace(row[2]), strings.TrimSpace(row[3]), strings.TrimSpace(row[4])
+
+	gameID, err := strconv.Atoi(idStr)
+	if err != nil {
+		return ds.SeasonGame{}, errors.New("ID is not integer")
+	}
+	if _, err := pkg.ParseDate(date); err != nil {
+		return ds.SeasonGame{}, errors.New("invalid date")
+	}
+	id1, err := strconv.Atoi(team1)
+	if err != nil {
+		return ds.SeasonGame{}, errors.New("teamID1 is not integer")
+	}
+	id2, err := strconv.Atoi(team2)
+	if err != nil {
+		return ds.SeasonGame{}, errors.New("teamID2 is not integer")
+	}
+	return ds.SeasonGame{
+		ID:      gameID,
+		Tour:    tourName,
+		Date:    date,
+		TeamID1: id1,
+		TeamID2: id2,
+	}, nil
+}
+
+func (r *Repo) getReferee(row []string) (ds.Referee, error) {
+	cell := func(i int) string {
+		if len(row) > i {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	idStr, name, formatsStr, timeFromStr, timeToStr, maxGamesStr := cell(0), cell(1), cell(2), cell(3), cell(4), cell(5)
+
+	if idStr == "" || name == "" {
+		return ds.Referee{}, errors.New("empty col")
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return ds.Referee{}, errors.New("id is not integer")
+	}
+	formats, err := parseIntList(formatsStr)
+	if err != nil {
+		return ds.Referee{}, errors.New("formats is not list of integers")
+	}
+	timeFrom, err := pkg.ParseHM(timeFromStr)
+	if err != nil {
+		return ds.Referee{}, errors.New("finvalid timeFrom")
+	}
+	timeTo, err := pkg.ParseHM(timeToStr)
+	if err != nil {
+		return ds.Referee{}, errors.New("finvalid timeTo")
+	}
+	maxGames := 0
+	if maxGamesStr != "" {
+		maxGames, err = strconv.Atoi(maxGamesStr)
+		if err != nil {
+			return ds.Referee{}, errors.New("maxGames is not integer")
+		}
+	}
+
+	return ds.Referee{
+		ID:       id,
+		Name:     name,
+		Formats:  formats,
+		TimeFrom: timeFrom,
+		TimeTo:   timeTo,
+		MaxGames: maxGames,
 	}, nil
 }
+
+// parseIntList разобрать список чисел через запятую, например "2, 4"
+func parseIntList(str string) ([]int, error) {
+	var out []int
+	for _, part := range strings.Split(str, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, errors.New("list element is not integer")
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
@@ -0,0 +1,199 @@
+package repository
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	DBFile = "timetable.db" // база справочников внутри каталога данных
+
+	StorageXlsx = "xlsx"
+	StorageDB   = "db"
+)
+
+// Table таблица справочника: в xlsx - файл, в базе - бакет
+type Table struct {
+	Name     string
+	File     string
+	Required bool // без таблицы сервер не работает, необязательные таблицы могут отсутствовать
+}
+
+// Tables все таблицы справочников
+var Tables = []Table{
+	{Name: "stadiums", File: StadiumsFile, Required: true},
+	{Name: "divisions", File: DivisionsFile, Required: true},
+	{Name: "coaches", File: CoachesFile, Required: true},
+	{Name: "teams", File: TeamsFile, Required: true},
+	{Name: "wishes", File: WishesFile, Required: true},
+	{Name: "games", File: GamesFile, Required: true},
+	{Name: "referees", File: RefereesFile},
+	{Name: "season", File: SeasonFile},
+}
+
+// GetTable таблица по имени
+func GetTable(name string) (Table, error) {
+	for _, t := range Tables {
+		if t.Name == name {
+			return t, nil
+		}
+	}
+	return Table{}, fmt.Errorf("неизвестная таблица %s", name)
+}
+
+// tableByFile таблица по имени ее xlsx-файла
+func tableByFile(file string) (Table, error) {
+	for _, t := range Tables {
+		if t.File == file {
+			return t, nil
+		}
+	}
+	return Table{}, fmt.Errorf("неизвестная таблица %s", file)
+}
+
+// Storage хранилище таблиц справочников. Строки хранятся как есть, вместе с заголовком,
+// поэтому перенос между хранилищами ничего не теряет. Отсутствующая таблица - os.ErrNotExist
+type Storage interface {
+	ReadTable(t Table) ([][]string, error)
+	WriteTable(t Table, rows [][]string) error
+	Close() error
+}
+
+// OpenStorage хранилище по виду: xlsx-файлы в каталоге dir или база dir/timetable.db
+func OpenStorage(kind, dir string) (Storage, error) {
+	switch kind {
+	case StorageXlsx:
+		return NewXlsxStorage(dir), nil
+	case StorageDB:
+		return NewDBStorage(filepath.Join(dir, DBFile))
+	}
+	return nil, fmt.Errorf("неизвестное хранилище %s, нужно %s или %s", kind, StorageXlsx, StorageDB)
+}
+
+// XlsxStorage таблицы в xlsx-файлах, первый лист файла
+type XlsxStorage struct {
+	dir string
+}
+
+// NewXlsxStorage ...
+func NewXlsxStorage(dir string) *XlsxStorage {
+	return &XlsxStorage{dir: dir}
+}
+
+// ReadTable ...
+func (s *XlsxStorage) ReadTable(t Table) ([][]string, error) {
+	return (&Repo{}).readFile(filepath.Join(s.dir, t.File))
+}
+
+// WriteTable файл перезаписывается целиком
+func (s *XlsxStorage) WriteTable(t Table, rows [][]string) error {
+	f := excelize.NewFile()
+	defer func() {
+		if err := f.Close(); err != nil {
+			log.Println(err)
+		}
+	}()
+
+	index, err := f.NewSheet("Sheet1")
+	if err != nil {
+		return err
+	}
+	for i, row := range rows {
+		cells := make([]interface{}, len(row))
+		for j, v := range row {
+			// числа пишем числами, как их пишет сервер, остальное - строками
+			if n, err := strconv.Atoi(v); err == nil && strconv.Itoa(n) == v {
+				cells[j] = n
+				continue
+			}
+			cells[j] = v
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", cell, &cells); err != nil {
+			return err
+		}
+	}
+	f.SetActiveSheet(index)
+
+	return f.SaveAs(filepath.Join(s.dir, t.File))
+}
+
+// Close ...
+func (s *XlsxStorage) Close() error {
+	return nil
+}
+
+// DBStorage таблицы в базе bbolt: бакет на таблицу, строка - json-массив ячеек под номером строки
+type DBStorage struct {
+	db *bolt.DB
+}
+
+// NewDBStorage открыть или создать базу
+func NewDBStorage(path string) (*DBStorage, error) {
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("база %s: %w", path, err)
+	}
+	return &DBStorage{db: db}, nil
+}
+
+// ReadTable ...
+func (s *DBStorage) ReadTable(t Table) ([][]string, error) {
+	var rows [][]string
+	err := s.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(t.Name))
+		if b == nil {
+			return fmt.Errorf("таблица %s: %w", t.Name, os.ErrNotExist)
+		}
+		return b.ForEach(func(_, v []byte) error {
+			var row []string
+			if err := json.Unmarshal(v, &row); err != nil {
+				return err
+			}
+			rows = append(rows, row)
+			return nil
+		})
+	})
+	return rows, err
+}
+
+// WriteTable таблица заменяется целиком
+func (s *DBStorage) WriteTable(t Table, rows [][]string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte(t.Name)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		b, err := tx.CreateBucket([]byte(t.Name))
+		if err != nil {
+			return err
+		}
+		for i, row := range rows {
+			v, err := json.Marshal(row)
+			if err != nil {
+				return err
+			}
+			// ключи в big-endian, чтобы строки читались в исходном порядке
+			k := make([]byte, 8)
+			binary.BigEndian.PutUint64(k, uint64(i))
+			if err := b.Put(k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Close ...
+func (s *DBStorage) Close() error {
+	return s.db.Close()
+}
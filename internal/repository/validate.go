@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sergrom/timetable/internal/ds"
+)
+
+// Issue ошибка в данных справочника
+type Issue struct {
+	Table string
+	Row   int // номер строки как в Excel, 0 - таблица целиком
+	Msg   string
+}
+
+func (i Issue) String() string {
+	if i.Row == 0 {
+		return fmt.Sprintf("%s: %s", i.Table, i.Msg)
+	}
+	return fmt.Sprintf("%s, строка %d: %s", i.Table, i.Row, i.Msg)
+}
+
+// rowCheck разобрать строку таблицы и проверить ее связи, вернуть ID строки
+type rowCheck func(row []string) (int, error)
+
+// Validate проверить таблицы хранилища: каждую строку тем же разбором, что и при чтении,
+// уникальность ID и ссылки между таблицами. Сервер такие строки молча пропускает.
+func Validate(st Storage) []Issue {
+	r := &Repo{}
+	var issues []Issue
+
+	divisions := make(map[int]ds.Division)
+	coaches := make(map[int]bool)
+	teams := make(map[int]bool)
+
+	checks := map[string]rowCheck{
+		"stadiums": func(row []string) (int, error) {
+			st, err := r.getStadium(row)
+			if err != nil {
+				return 0, err
+			}
+			for _, m := range st.Merges {
+				for _, id := range m.FieldIDs {
+					if id > st.Fields {
+						return st.ID, fmt.Errorf("в объединении %s поле %d, а полей %d", m, id, st.Fields)
+					}
+				}
+			}
+			return st.ID, nil
+		},
+		"divisions": func(row []string) (int, error) {
+			d, err := r.getDivision(row)
+			if err != nil {
+				return 0, err
+			}
+			divisions[d.ID] = d
+			return d.ID, nil
+		},
+		"coaches": func(row []string) (int, error) {
+			c, err := r.getCoach(row)
+			if err != nil {
+				return 0, err
+			}
+			coaches[c.ID] = true
+			return c.ID, nil
+		},
+		"teams": func(row []string) (int, error) {
+			t, err := r.getTeam(row)
+			if err != nil {
+				return 0, err
+			}
+			teams[t.ID] = true
+			if _, ok := divisions[t.DivisionID]; !ok {
+				return t.ID, fmt.Errorf("нет дивизиона %d", t.DivisionID)
+			}
+			if !coaches[t.CoachID] {
+				return t.ID, fmt.Errorf("нет тренера %d", t.CoachID)
+			}
+			return t.ID, nil
+		},
+		"wishes": func(row []string) (int, error) {
+			w, err := r.getWish(row)
+			if err != nil {
+				return 0, err
+			}
+			if !teams[w.TeamID] {
+				return w.ID, fmt.Errorf("нет команды %d", w.TeamID)
+			}
+			if (w.Kind == ds.WishOpponent || w.Kind == ds.WishNotOpponent) && !teams[w.Value] {
+				return w.ID, fmt.Errorf("нет соперника %d", w.Value)
+			}
+			return w.ID, nil
+		},
+		"games": func(row []string) (int, error) {
+			g, err := r.getGame(row)
+			if err != nil {
+				return 0, err
+			}
+			return g.ID, checkPair(teams, g.TeamID1, g.TeamID2)
+		},
+		"referees": func(row []string) (int, error) {
+			ref, err := r.getReferee(row)
+			if err != nil {
+				return 0, err
+			}
+			return ref.ID, nil
+		},
+		"season": func(row []string) (int, error) {
+			g, err := r.getSeasonGame(row)
+			if err != nil {
+				return 0, err
+			}
+			return g.ID, checkPair(teams, g.TeamID1, g.TeamID2)
+		},
+	}
+
+	// таблицы проверяются по порядку Tables, поэтому ссылки идут только на уже прочитанные таблицы
+	for _, t := range Tables {
+		rows, err := st.ReadTable(t)
+		if errors.Is(err, os.ErrNotExist) {
+			if t.Required {
+				issues = append(issues, Issue{Table: t.Name, Msg: "таблица отсутствует"})
+			}
+			continue
+		}
+		if err != nil {
+			issues = append(issues, Issue{Table: t.Name, Msg: err.Error()})
+			continue
+		}
+		issues = append(issues, validateRows(t.Name, rows, checks[t.Name])...)
+	}
+
+	// дивизионы, с которыми играет дивизион, известны только после чтения всей таблицы
+	for _, d := range divisions {
+		for _, id := range d.PlaysWith {
+			other, ok := divisions[id]
+			switch {
+			case !ok:
+				issues = append(issues, Issue{Table: "divisions", Msg: fmt.Sprintf("дивизион %d играет с несуществующим дивизионом %d", d.ID, id)})
+			case other.Format != d.Format:
+				issues = append(issues, Issue{Table: "divisions", Msg: fmt.Sprintf("дивизион %d играет с дивизионом %d другого формата", d.ID, id)})
+			}
+		}
+	}
+
+	return issues
+}
+
+func validateRows(table string, rows [][]string, check rowCheck) []Issue {
+	var issues []Issue
+	ids := make(map[int]int)
+	for i, row := range rows {
+		if isEmptyRow(row) || strings.HasPrefix(strings.ToLower(row[0]), "id") {
+			continue
+		}
+		if len(row) < minCols[table] {
+			issues = append(issues, Issue{Table: table, Row: i + 1, Msg: fmt.Sprintf("заполнено колонок %d, нужно не меньше %d", len(row), minCols[table])})
+			continue
+		}
+		id, err := check(row)
+		if err != nil {
+			issues = append(issues, Issue{Table: table, Row: i + 1, Msg: err.Error()})
+		}
+		if id == 0 {
+			continue
+		}
+		if prev, ok := ids[id]; ok {
+			issues = append(issues, Issue{Table: table, Row: i + 1, Msg: "ID " + strconv.Itoa(id) + " уже есть в строке " + strconv.Itoa(prev)})
+			continue
+		}
+		ids[id] = i + 1
+	}
+	return issues
+}
+
+// ValidateTable проверить строки одной таблицы без ссылок на другие таблицы
+func ValidateTable(t Table, rows [][]string) []Issue {
+	r := &Repo{}
+	checks := map[string]rowCheck{
+		"stadiums":  func(row []string) (int, error) { v, err := r.getStadium(row); return v.ID, err },
+		"divisions": func(row []string) (int, error) { v, err := r.getDivision(row); return v.ID, err },
+		"coaches":   func(row []string) (int, error) { v, err := r.getCoach(row); return v.ID, err },
+		"teams":     func(row []string) (int, error) { v, err := r.getTeam(row); return v.ID, err },
+		"wishes":    func(row []string) (int, error) { v, err := r.getWish(row); return v.ID, err },
+		"games":     func(row []string) (int, error) { v, err := r.getGame(row); return v.ID, err },
+		"referees":  func(row []string) (int, error) { v, err := r.getReferee(row); return v.ID, err },
+		"season":    func(row []string) (int, error) { v, err := r.getSeasonGame(row); return v.ID, err },
+	}
+	return validateRows(t.Name, rows, checks[t.Name])
+}
+
+// minCols обязательные колонки таблиц, как при чтении в Get-методах
+var minCols = map[string]int{
+	"stadiums":  7,
+	"divisions": 3,
+	"coaches":   2,
+	"teams":     4,
+	"wishes":    3,
+	"games":     5,
+	"referees":  2,
+	"season":    5,
+}
+
+func checkPair(teams map[int]bool, id1, id2 int) error {
+	switch {
+	case !teams[id1]:
+		return fmt.Errorf("нет команды %d", id1)
+	case !teams[id2]:
+		return fmt.Errorf("нет команды %d", id2)
+	case id1 == id2:
+		return errors.New("команда играет сама с собой")
+	}
+	return nil
+}
+
+func isEmptyRow(row []string) bool {
+	for _, v := range row {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
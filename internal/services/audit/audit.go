@@ -0,0 +1,292 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File журнал внутри каталога данных
+const File = "audit.log"
+
+const (
+	ActionCreate       = "create"
+	ActionUpdate       = "update"
+	ActionDelete       = "delete"
+	ActionSearchStart  = "search-start"
+	ActionSearchStop   = "search-stop"
+	ActionSearchResume = "search-resume"
+
+	EntitySearch = "search" // запуски поиска, остальные сущности - таблицы справочников
+)
+
+// ActionLabels названия действий для страницы журнала
+var ActionLabels = map[string]string{
+	ActionCreate:       "добавление",
+	ActionUpdate:       "изменение",
+	ActionDelete:       "удаление",
+	ActionSearchStart:  "запуск поиска",
+	ActionSearchStop:   "остановка поиска",
+	ActionSearchResume: "продолжение поиска",
+}
+
+// Entry запись журнала: кто, когда и откуда изменил строку таблицы или запустил поиск
+type Entry struct {
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`
+	IP       string    `json:"ip"`
+	Action   string    `json:"action"`
+	Entity   string    `json:"entity"`
+	EntityID string    `json:"entity_id"`
+	Columns  []string  `json:"columns,omitempty"` // заголовок таблицы
+	Before   []string  `json:"before,omitempty"`
+	After    []string  `json:"after,omitempty"`
+}
+
+// Change изменение одной колонки
+type Change struct {
+	Column string
+	Before string
+	After  string
+}
+
+// Changes изменившиеся колонки, у добавления и удаления - все заполненные
+func (e Entry) Changes() []Change {
+	n := len(e.Before)
+	if len(e.After) > n {
+		n = len(e.After)
+	}
+
+	changes := make([]Change, 0, n)
+	for i := 0; i < n; i++ {
+		ch := Change{Column: fmt.Sprintf("#%d", i+1), Before: cell(e.Before, i), After: cell(e.After, i)}
+		if i < len(e.Columns) && e.Columns[i] != "" {
+			ch.Column = e.Columns[i]
+		}
+		if ch.Before != ch.After {
+			changes = append(changes, ch)
+		}
+	}
+	return changes
+}
+
+func cell(row []string, i int) string {
+	if i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// Filter отбор записей, пустые поля не ограничивают
+type Filter struct {
+	User     string
+	Action   string
+	Entity   string
+	EntityID string
+	From     time.Time
+	To       time.Time // не включая
+	Query    string    // подстрока в значениях до и после
+}
+
+// Match ...
+func (f Filter) Match(e Entry) bool {
+	switch {
+	case f.User != "" && e.User != f.User,
+		f.Action != "" && e.Action != f.Action,
+		f.Entity != "" && e.Entity != f.Entity,
+		f.EntityID != "" && e.EntityID != f.EntityID,
+		!f.From.IsZero() && e.Time.Before(f.From),
+		!f.To.IsZero() && !e.Time.Before(f.To):
+		return false
+	}
+	if f.Query == "" {
+		return true
+	}
+	q := strings.ToLower(f.Query)
+	for _, v := range append(append([]string{e.EntityID}, e.Before...), e.After...) {
+		if strings.Contains(strings.ToLower(v), q) {
+			return true
+		}
+	}
+	return false
+}
+
+// Log журнал в файле, по записи в строке. Записи только дописываются в конец
+type Log struct {
+	lock sync.Mutex
+	path string
+}
+
+// NewLog ...
+func NewLog(path string) *Log {
+	return &Log{path: path}
+}
+
+// Append дописать записи
+func (l *Log) Append(entries ...Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var buf strings.Builder
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(buf.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read записи по фильтру от последней к первой
+func (l *Log) Read(filter Filter) ([]Entry, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("%s, строка %d: %w", l.path, n, err)
+		}
+		if filter.Match(e) {
+			entries = append(entries, e)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// Users кто есть в журнале, для фильтра
+func (l *Log) Users() ([]string, error) {
+	entries, err := l.Read(Filter{})
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var users []string
+	for _, e := range entries {
+		if !seen[e.User] {
+			seen[e.User] = true
+			users = append(users, e.User)
+		}
+	}
+	return users, nil
+}
+
+// Diff записи об изменении строк таблицы: строки сопоставляются по ID в первой колонке
+func Diff(entity string, before, after [][]string) []Entry {
+	oldColumns, old := indexRows(before)
+	columns, cur := indexRows(after)
+	if len(columns) == 0 {
+		columns = oldColumns
+	}
+
+	var entries []Entry
+	for _, id := range rowIDs(after) {
+		prev, ok := old[id]
+		switch {
+		case !ok:
+			entries = append(entries, Entry{Action: ActionCreate, Entity: entity, EntityID: id, Columns: columns, After: cur[id]})
+		case !equalRows(prev, cur[id]):
+			entries = append(entries, Entry{Action: ActionUpdate, Entity: entity, EntityID: id, Columns: columns, Before: prev, After: cur[id]})
+		}
+	}
+	for _, id := range rowIDs(before) {
+		if _, ok := cur[id]; !ok {
+			entries = append(entries, Entry{Action: ActionDelete, Entity: entity, EntityID: id, Columns: columns, Before: old[id]})
+		}
+	}
+	return entries
+}
+
+// indexRows заголовок и строки по ID
+func indexRows(rows [][]string) ([]string, map[string][]string) {
+	var header []string
+	byID := make(map[string][]string, len(rows))
+	for _, row := range rows {
+		if len(row) == 0 || row[0] == "" {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(row[0]), "id") {
+			header = row
+			continue
+		}
+		byID[row[0]] = row
+	}
+	return header, byID
+}
+
+func rowIDs(rows [][]string) []string {
+	ids := make([]string, 0, len(rows))
+	seen := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		if len(row) == 0 || row[0] == "" || strings.HasPrefix(strings.ToLower(row[0]), "id") || seen[row[0]] {
+			continue
+		}
+		seen[row[0]] = true
+		ids = append(ids, row[0])
+	}
+	return ids
+}
+
+// equalRows пустые ячейки в конце строки не считаются
+func equalRows(a, b []string) bool {
+	a, b = trimRow(a), trimRow(b)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func trimRow(row []string) []string {
+	for len(row) > 0 && row[len(row)-1] == "" {
+		row = row[:len(row)-1]
+	}
+	return row
+}
@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	header := []string{"ID", "Название", "Формат"}
+	row := func(cells ...string) []string { return cells }
+
+	tests := []struct {
+		name   string
+		before [][]string
+		after  [][]string
+		want   []Entry
+	}{
+		{
+			name:   "nothing changed",
+			before: [][]string{header, row("1", "А", "6")},
+			after:  [][]string{header, row("1", "А", "6")},
+		},
+		{
+			name:   "trailing empty cells are not a change",
+			before: [][]string{header, row("1", "А", "6")},
+			after:  [][]string{header, row("1", "А", "6", "", "")},
+		},
+		{
+			name:   "create, update, delete",
+			before: [][]string{header, row("1", "А", "6"), row("2", "Б", "6")},
+			after:  [][]string{header, row("3", "В", "8"), row("1", "А", "7")},
+			want: []Entry{
+				{Action: ActionCreate, Entity: "teams", EntityID: "3", Columns: header, After: row("3", "В", "8")},
+				{Action: ActionUpdate, Entity: "teams", EntityID: "1", Columns: header, Before: row("1", "А", "6"), After: row("1", "А", "7")},
+				{Action: ActionDelete, Entity: "teams", EntityID: "2", Columns: header, Before: row("2", "Б", "6")},
+			},
+		},
+		{
+			name:  "new table",
+			after: [][]string{header, row("1", "А", "6")},
+			want:  []Entry{{Action: ActionCreate, Entity: "teams", EntityID: "1", Columns: header, After: row("1", "А", "6")}},
+		},
+		{
+			name:   "emptied table keeps the old header",
+			before: [][]string{header, row("1", "А", "6")},
+			want:   []Entry{{Action: ActionDelete, Entity: "teams", EntityID: "1", Columns: header, Before: row("1", "А", "6")}},
+		},
+		{
+			name:   "rows without ID are ignored",
+			before: [][]string{header, row("", "x"), {}},
+			after:  [][]string{header, row("", "y")},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Diff("teams", tc.before, tc.after)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("entries\n%+v\nwant\n%+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestEntryChanges(t *testing.T) {
+	columns := []string{"ID", "Название"}
+
+	tests := []struct {
+		name  string
+		entry Entry
+		want  []Change
+	}{
+		{
+			name:  "update shows only changed columns",
+			entry: Entry{Columns: columns, Before: []string{"1", "А"}, After: []string{"1", "Б"}},
+			want:  []Change{{Column: "Название", Before: "А", After: "Б"}},
+		},
+		{
+			name:  "create shows filled columns",
+			entry: Entry{Columns: columns, After: []string{"1", ""}},
+			want:  []Change{{Column: "ID", After: "1"}},
+		},
+		{
+			name:  "column without header is numbered",
+			entry: Entry{Columns: columns, Before: []string{"1", "А"}, After: []string{"1", "А", "x"}},
+			want:  []Change{{Column: "#3", After: "x"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.entry.Changes(); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("changes %+v, want %+v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// UsersFile файл пользователей внутри каталога данных
+const UsersFile = "users.json"
+
+// ErrBadCredentials неверное имя или пароль, какое именно - не сообщаем
+var ErrBadCredentials = errors.New("неверное имя пользователя или пароль")
+
+// Role роль пользователя, каждая следующая может все, что предыдущая
+type Role int
+
+const (
+	RoleViewer  Role = iota + 1 // смотрит расписания и справочники
+	RolePlanner                 // запускает поиск и публикует решения
+	RoleAdmin                   // правит справочники
+)
+
+var roleNames = map[Role]string{
+	RoleViewer:  "viewer",
+	RolePlanner: "planner",
+	RoleAdmin:   "admin",
+}
+
+// ParseRole роль по имени: viewer, planner или admin
+func ParseRole(name string) (Role, error) {
+	for r, n := range roleNames {
+		if n == strings.ToLower(strings.TrimSpace(name)) {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("неизвестная роль %s, нужна viewer, planner или admin", name)
+}
+
+func (r Role) String() string {
+	return roleNames[r]
+}
+
+// MarshalJSON в файле роль хранится именем
+func (r Role) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+// UnmarshalJSON ...
+func (r *Role) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	role, err := ParseRole(name)
+	if err != nil {
+		return err
+	}
+	*r = role
+	return nil
+}
+
+// User пользователь, пароль хранится только хешем bcrypt
+type User struct {
+	Name         string `json:"name"`
+	Role         Role   `json:"role"`
+	PasswordHash string `json:"password_hash"`
+}
+
+// Store пользователи в json-файле. Файл меняется командой timetable user,
+// сервер перечитывает его, когда файл изменился
+type Store struct {
+	lock    sync.Mutex
+	path    string
+	users   map[string]User
+	modTime time.Time
+}
+
+// NewStore ...
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Users пользователи по имени
+func (s *Store) Users() ([]User, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	list := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list, nil
+}
+
+// Get пользователь по имени
+func (s *Store) Get(name string) (User, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.load(); err != nil {
+		return User{}, false
+	}
+	u, ok := s.users[name]
+	return u, ok
+}
+
+// Authenticate проверить имя и пароль
+func (s *Store) Authenticate(name, password string) (User, error) {
+	u, ok := s.Get(name)
+	if !ok {
+		return User{}, ErrBadCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)); err != nil {
+		return User{}, ErrBadCredentials
+	}
+	return u, nil
+}
+
+// Save добавить пользователя или поменять ему роль и пароль, пустой пароль не меняется
+func (s *Store) Save(name, password string, role Role) error {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsAny(name, ":") {
+		return errors.New("имя пользователя не должно быть пустым или содержать двоеточие")
+	}
+	if role.String() == "" {
+		return errors.New("не задана роль")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	u, ok := s.users[name]
+	if !ok && password == "" {
+		return errors.New("у нового пользователя должен быть пароль")
+	}
+	u.Name, u.Role = name, role
+	if password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		u.PasswordHash = string(hash)
+	}
+	s.users[name] = u
+	return s.write()
+}
+
+// Delete удалить пользователя
+func (s *Store) Delete(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, ok := s.users[name]; !ok {
+		return fmt.Errorf("нет пользователя %s", name)
+	}
+	delete(s.users, name)
+	return s.write()
+}
+
+// load перечитать файл, если он изменился. Файла нет - пользователей нет
+func (s *Store) load() error {
+	st, err := os.Stat(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.users, s.modTime = make(map[string]User), time.Time{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.users != nil && st.ModTime().Equal(s.modTime) {
+		return nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var list []User
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("%s: %w", s.path, err)
+	}
+	s.users = make(map[string]User, len(list))
+	for _, u := range list {
+		s.users[u.Name] = u
+	}
+	s.modTime = st.ModTime()
+	return nil
+}
+
+func (s *Store) write() error {
+	list := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	// хеши паролей читает только владелец файла
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	s.users = nil // перечитаем с новым временем изменения
+	return s.load()
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRole(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Role
+		wantErr bool
+	}{
+		{name: "viewer", want: RoleViewer},
+		{name: " Planner ", want: RolePlanner},
+		{name: "ADMIN", want: RoleAdmin},
+		{name: "", wantErr: true},
+		{name: "root", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ParseRole(tc.name)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error %v, want error %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("role %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data", UsersFile)
+	s := NewStore(path)
+
+	if users, err := s.Users(); err != nil || len(users) != 0 {
+		t.Fatalf("no file: users %v, error %v", users, err)
+	}
+
+	// шаги выполняются по порядку на одном хранилище
+	tests := []struct {
+		name     string
+		save     *User // Name, Role и пароль в PasswordHash
+		del      string
+		wantErr  bool
+		login    string
+		password string
+		wantRole Role // 0 - вход не пускает
+	}{
+		{name: "new user without password", save: &User{Name: "anna", Role: RoleAdmin}, wantErr: true},
+		{name: "empty name", save: &User{Name: " ", Role: RoleAdmin, PasswordHash: "x"}, wantErr: true},
+		{name: "name with colon", save: &User{Name: "a:b", Role: RoleAdmin, PasswordHash: "x"}, wantErr: true},
+		{name: "no role", save: &User{Name: "anna", PasswordHash: "x"}, wantErr: true},
+		{name: "add", save: &User{Name: "anna", Role: RoleAdmin, PasswordHash: "secret"}, login: "anna", password: "secret", wantRole: RoleAdmin},
+		{name: "wrong password", login: "anna", password: "secret2"},
+		{name: "unknown user", login: "boris", password: "secret"},
+		{name: "change role, keep password", save: &User{Name: "anna", Role: RoleViewer}, login: "anna", password: "secret", wantRole: RoleViewer},
+		{name: "change password", save: &User{Name: "anna", Role: RoleViewer, PasswordHash: "new"}, login: "anna", password: "new", wantRole: RoleViewer},
+		{name: "old password no longer works", login: "anna", password: "secret"},
+		{name: "delete unknown", del: "boris", wantErr: true},
+		{name: "delete", del: "anna", login: "anna", password: "new"},
+	}
+
+	for _, tc := range tests {
+		var err error
+		switch {
+		case tc.save != nil:
+			err = s.Save(tc.save.Name, tc.save.PasswordHash, tc.save.Role)
+		case tc.del != "":
+			err = s.Delete(tc.del)
+		}
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("%s: error %v, want error %v", tc.name, err, tc.wantErr)
+		}
+		if tc.login == "" {
+			continue
+		}
+
+		u, err := s.Authenticate(tc.login, tc.password)
+		if tc.wantRole == 0 {
+			if !errors.Is(err, ErrBadCredentials) {
+				t.Errorf("%s: error %v, want %v", tc.name, err, ErrBadCredentials)
+			}
+			continue
+		}
+		if err != nil || u.Role != tc.wantRole {
+			t.Errorf("%s: role %v, error %v, want role %v", tc.name, u.Role, err, tc.wantRole)
+		}
+	}
+}
+
+func TestStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), UsersFile)
+	server, cli := NewStore(path), NewStore(path)
+
+	if _, ok := server.Get("anna"); ok {
+		t.Fatal("user before the file exists")
+	}
+	if err := cli.Save("anna", "secret", RolePlanner); err != nil {
+		t.Fatal(err)
+	}
+	if u, ok := server.Get("anna"); !ok || u.Role != RolePlanner {
+		t.Fatalf("server does not see the new user: %+v", u)
+	}
+	if u, _ := server.Get("anna"); u.PasswordHash == "secret" {
+		t.Error("password is stored as is")
+	}
+
+	st, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Mode().Perm() != 0o600 {
+		t.Errorf("file mode %v, want 0600", st.Mode().Perm())
+	}
+
+	if err := os.WriteFile(path, []byte("не json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewStore(path).Users(); err == nil {
+		t.Error("broken file must be an error")
+	}
+}
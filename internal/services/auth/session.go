@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Sessions сессии вошедших пользователей, живут в памяти до перезапуска сервера
+type Sessions struct {
+	lock  sync.Mutex
+	ttl   time.Duration
+	items map[string]session
+}
+
+type session struct {
+	name    string
+	expires time.Time
+}
+
+// NewSessions ...
+func NewSessions(ttl time.Duration) *Sessions {
+	return &Sessions{
+		ttl:   ttl,
+		items: make(map[string]session),
+	}
+}
+
+// Create новая сессия пользователя, возвращает ее токен
+func (s *Sessions) Create(name string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	for t, it := range s.items {
+		if now.After(it.expires) {
+			delete(s.items, t)
+		}
+	}
+	s.items[token] = session{name: name, expires: now.Add(s.ttl)}
+	return token, nil
+}
+
+// Get имя пользователя по токену, если сессия не истекла
+func (s *Sessions) Get(token string) (string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	it, ok := s.items[token]
+	if !ok {
+		return "", false
+	}
+	if time.Now().After(it.expires) {
+		delete(s.items, token)
+		return "", false
+	}
+	return it.name, true
+}
+
+// Delete ...
+func (s *Sessions) Delete(token string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.items, token)
+}
+
+// TTL сколько живет сессия
+func (s *Sessions) TTL() time.Duration {
+	return s.ttl
+}
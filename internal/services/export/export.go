@@ -0,0 +1,285 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/services/searcher"
+	"github.com/xuri/excelize/v2"
+)
+
+// Names названия команд, дивизионов и судей для вывода решения
+type Names struct {
+	Teams     map[int]ds.Team
+	Divisions map[int]ds.Division
+	Referees  map[int]string
+}
+
+// NamesFromCondition названия из условия поиска, без обращения к справочникам
+func NamesFromCondition(cond *searcher.Condition) Names {
+	names := Names{
+		Teams:     make(map[int]ds.Team, len(cond.Teams)),
+		Divisions: make(map[int]ds.Division, len(cond.Divisions)),
+		Referees:  make(map[int]string, len(cond.Referees)),
+	}
+	for _, t := range cond.Teams {
+		names.Teams[t.ID] = t
+	}
+	for _, d := range cond.Divisions {
+		names.Divisions[d.ID] = d
+	}
+	for _, r := range cond.Referees {
+		names.Referees[r.ID] = r.Name
+	}
+	return names
+}
+
+// Xlsx записать решение на лист sheet: по четыре колонки на поле
+func Xlsx(f *excelize.File, sheet string, sol *searcher.Solution, names Names) error {
+	styleTopHead, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold: true,
+			Size: 20,
+		},
+		Alignment: &excelize.Alignment{
+			Horizontal: "center",
+		},
+	})
+	if err != nil {
+		return err
+	}
+	styleSecondHead, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold: true,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	styleWrap, err := f.NewStyle(&excelize.Style{
+		Alignment: &excelize.Alignment{
+			WrapText: true,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	styleBreak, err := f.NewStyle(&excelize.Style{
+		Fill: excelize.Fill{
+			Type:    "pattern",
+			Pattern: 1,
+			Color:   []string{"#D9D9D9"},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	fields, preparedGames := prepareGames(sol.Games, sol.Breaks)
+
+	fieldID := 1
+	f.SetRowHeight(sheet, 1, 32.0)
+	for _, field := range fields {
+		games := preparedGames[field]
+		cell, _ := excelize.CoordinatesToCellName(fieldID, 1)
+		cellTo, _ := excelize.CoordinatesToCellName(fieldID+3, 1)
+		title := strings.Trim(field, "[]")
+		if venue := sol.Venues[field]; venue != "" {
+			title = venue + ": " + title
+		}
+		f.SetCellValue(sheet, cell, title)
+		f.SetCellStyle(sheet, cell, cell, styleTopHead)
+
+		f.MergeCell(sheet, cell, cellTo)
+		cn, _ := excelize.ColumnNumberToName(fieldID + 2)
+		f.SetColWidth(sheet, cn, cn, 40)
+
+		ch1, _ := excelize.CoordinatesToCellName(fieldID, 2)
+		ch2, _ := excelize.CoordinatesToCellName(fieldID+1, 2)
+		ch3, _ := excelize.CoordinatesToCellName(fieldID+2, 2)
+		ch4, _ := excelize.CoordinatesToCellName(fieldID+3, 2)
+		f.SetCellValue(sheet, ch1, "Время")
+		f.SetCellValue(sheet, ch2, "Див.")
+		f.SetCellValue(sheet, ch3, "Команды")
+		f.SetCellValue(sheet, ch4, "Судья")
+		f.SetCellStyle(sheet, ch1, ch4, styleSecondHead)
+		cn4, _ := excelize.ColumnNumberToName(fieldID + 3)
+		f.SetColWidth(sheet, cn4, cn4, 20)
+
+		rowID := 3
+		for _, g := range games {
+			if g.FixRowIdx > 0 {
+				rowID = g.FixRowIdx + 3
+			}
+
+			teamsStr := fmt.Sprintf("%s\r\n%s", names.Teams[g.TeamID1].Name, names.Teams[g.TeamID2].Name)
+
+			cell1, _ := excelize.CoordinatesToCellName(fieldID, rowID)
+			f.SetCellValue(sheet, cell1, g.Start.Format("15:04"))
+			cell2, _ := excelize.CoordinatesToCellName(fieldID+1, rowID)
+			cell3, _ := excelize.CoordinatesToCellName(fieldID+2, rowID)
+
+			if g.ExtraInfo == "" {
+				f.SetCellValue(sheet, cell2, names.Divisions[names.Teams[g.TeamID1].DivisionID].Name)
+				f.SetCellStr(sheet, cell3, teamsStr)
+				if name, ok := names.Referees[g.RefereeID]; ok {
+					cell4, _ := excelize.CoordinatesToCellName(fieldID+3, rowID)
+					f.SetCellStr(sheet, cell4, name)
+				}
+			} else {
+				f.SetCellStr(sheet, cell3, g.ExtraInfo)
+			}
+
+			f.SetCellStyle(sheet, cell3, cell3, styleWrap)
+			if g.IsBreak {
+				cell4, _ := excelize.CoordinatesToCellName(fieldID+3, rowID)
+				f.SetCellStyle(sheet, cell1, cell4, styleBreak)
+			}
+			f.SetRowHeight(sheet, rowID, 32.0)
+			rowID++
+		}
+		fieldID += 4
+	}
+
+	return nil
+}
+
+// CSV записать игры решения построчно, игры на объединенных полях - один раз
+func CSV(w io.Writer, sol *searcher.Solution, names Names) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"Поле", "Дата", "Начало", "Конец", "Дивизион", "Команда1", "Команда2", "Судья"}); err != nil {
+		return err
+	}
+
+	fields := make([]string, 0, len(sol.Games))
+	for field := range sol.Games {
+		fields = append(fields, field)
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		return fieldNum(fields[i]) < fieldNum(fields[j])
+	})
+
+	for _, field := range fields {
+		title := strings.Trim(field, "[]")
+		if venue := sol.Venues[field]; venue != "" {
+			title = venue + ": " + title
+		}
+		for _, g := range sol.Games[field] {
+			team1, team2 := names.Teams[g.TeamID1], names.Teams[g.TeamID2]
+			err := cw.Write([]string{title, g.Start.Format("2006-01-02"), g.Start.Format("15:04"), g.End.Format("15:04"),
+				names.Divisions[team1.DivisionID].Name, team1.Name, team2.Name, names.Referees[g.RefereeID]})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func prepareGames(games map[string][]searcher.SolutioGame, breaks map[string][]ds.Break) ([]string, map[string][]searcher.SolutioGame) {
+	singleFileds := make([]string, 0, len(games))
+	mergedFields := make([]string, 0, len(games))
+	for field := range games {
+		if len(strings.Split(field, ",")) == 1 {
+			singleFileds = append(singleFileds, field)
+			continue
+		}
+		mergedFields = append(mergedFields, field)
+	}
+
+	output := make(map[string][]searcher.SolutioGame, len(singleFileds))
+	for _, f := range singleFileds {
+		games := games[f]
+		for _, g := range games {
+			output[f] = append(output[f], g)
+		}
+	}
+	for _, f := range mergedFields {
+		// игру на объединенных полях пишем в первое поле, в остальных - отметку
+		fields := strings.Split(strings.Trim(f, "[]"), ",")
+		extraInfo := "игра " + strings.Join(fields, ", ")
+		for i := range fields {
+			fields[i] = "[" + fields[i] + "]"
+			if _, ok := output[fields[i]]; !ok {
+				singleFileds = append(singleFileds, fields[i])
+				output[fields[i]] = nil
+			}
+		}
+		games := games[f]
+		for _, g := range games {
+			output[fields[0]] = append(output[fields[0]], g)
+			for _, f2 := range fields[1:] {
+				output[f2] = append(output[f2], searcher.SolutioGame{
+					Start:     g.Start,
+					ExtraInfo: extraInfo,
+				})
+			}
+		}
+	}
+
+	for f, bb := range breaks {
+		if _, ok := output[f]; !ok {
+			singleFileds = append(singleFileds, f)
+		}
+		for _, b := range bb {
+			output[f] = append(output[f], searcher.SolutioGame{
+				Start:     b.From,
+				End:       b.To,
+				ExtraInfo: "перерыв " + b.String(),
+				IsBreak:   true,
+			})
+		}
+	}
+
+	for f := range output {
+		sort.Slice(output[f], func(i, j int) bool {
+			return output[f][i].Start.Before(output[f][j].Start)
+		})
+	}
+	sort.Slice(singleFileds, func(i, j int) bool {
+		return fieldNum(singleFileds[i]) < fieldNum(singleFileds[j])
+	})
+
+	timeCntMap := make(map[string]int, 500)
+	idxTimeMap := make(map[string]int, 500)
+	for f := range output {
+		games := output[f]
+		for i, g := range games {
+			tStr := g.Start.Format("02.01 15:04")
+			if _, ok := timeCntMap[tStr]; !ok {
+				timeCntMap[tStr] = 0
+				idxTimeMap[tStr] = 0
+			}
+			timeCntMap[tStr]++
+			if idxTimeMap[tStr] < i {
+				idxTimeMap[tStr] = i
+			}
+		}
+	}
+
+	for f := range output {
+		games := output[f]
+		for i := range games {
+			tStr := games[i].Start.Format("02.01 15:04")
+			if timeCntMap[tStr] > 1 {
+				games[i].FixRowIdx = idxTimeMap[tStr]
+			}
+		}
+	}
+
+	return singleFileds, output
+}
+
+// fieldNum номер поля из названия вида "[поле12]"
+func fieldNum(field string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(strings.Trim(field, "[]"), "поле"))
+	return n
+}
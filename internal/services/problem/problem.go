@@ -0,0 +1,122 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/pkg"
+	"github.com/sergrom/timetable/internal/services/searcher"
+)
+
+// Version версия формата файла задачи, меняется при несовместимых изменениях
+const Version = 1
+
+// Problem задача поиска: все исходные данные тура, из которых строится условие поиска.
+// Не зависит от справочников, поэтому ее можно передать и воспроизвести на другой машине.
+type Problem struct {
+	Version   int             `json:"version"`
+	TourName  string          `json:"tour_name"`
+	Fields    []ds.Field      `json:"fields"`
+	Merges    []ds.FieldMerge `json:"merges"`
+	Divisions []ds.Division   `json:"divisions"`
+	Coaches   []ds.Coach      `json:"coaches"`
+	Teams     []ds.Team       `json:"teams"` // только выбранные для тура команды
+	Wishes    []ds.Wish       `json:"wishes"`
+	Games     []ds.Game       `json:"games"`    // предыдущие игры
+	Pairings  []ds.Game       `json:"pairings"` // заданные пары тура
+	Referees  []ds.Referee    `json:"referees"`
+	Params    searcher.Params `json:"params"`
+
+	// точка продолжения и лучшие решения, если задача выгружена из начатого поиска,
+	// по ним поиск продолжается без повторного перебора
+	Checkpoint *searcher.Checkpoint `json:"checkpoint,omitempty"`
+	Solutions  []searcher.Solution  `json:"solutions,omitempty"`
+}
+
+// FromCondition задача по условию поиска
+func FromCondition(cond *searcher.Condition) Problem {
+	return Problem{
+		Version:   Version,
+		TourName:  cond.TourName,
+		Fields:    cond.Fields,
+		Merges:    cond.Merges,
+		Divisions: cond.Divisions,
+		Coaches:   cond.Coaches,
+		Teams:     cond.Teams,
+		Wishes:    cond.Wishes,
+		Games:     cond.Games,
+		Pairings:  cond.Pairings,
+		Referees:  cond.Referees,
+		Params:    cond.Params,
+	}
+}
+
+// Condition условие поиска по задаче
+func (p Problem) Condition() (*searcher.Condition, error) {
+	if len(p.Fields) == 0 {
+		return nil, errors.New("в задаче нет полей")
+	}
+	if len(p.Teams) == 0 {
+		return nil, errors.New("в задаче нет команд")
+	}
+
+	fields := make([]ds.Field, len(p.Fields))
+	copy(fields, p.Fields)
+	for i := range fields {
+		if err := fields[i].RestoreStarts(); err != nil {
+			return nil, err
+		}
+	}
+
+	divs := make(map[int]bool, len(p.Divisions))
+	for _, d := range p.Divisions {
+		divs[d.ID] = true
+	}
+	for _, t := range p.Teams {
+		if !divs[t.DivisionID] {
+			return nil, fmt.Errorf("команда %s: нет дивизиона %d", t.Name, t.DivisionID)
+		}
+	}
+
+	// время тренеров, судей и пожеланий задано без даты и привязано ко дню выгрузки,
+	// переносим его на сегодня, как при чтении из справочников
+	coaches := make([]ds.Coach, len(p.Coaches))
+	for i, c := range p.Coaches {
+		c.TimeFrom, c.TimeTo = pkg.Clock(c.TimeFrom), pkg.Clock(c.TimeTo)
+		coaches[i] = c
+	}
+	referees := make([]ds.Referee, len(p.Referees))
+	for i, r := range p.Referees {
+		r.TimeFrom, r.TimeTo = pkg.Clock(r.TimeFrom), pkg.Clock(r.TimeTo)
+		referees[i] = r
+	}
+	wishes := make([]ds.Wish, len(p.Wishes))
+	for i, w := range p.Wishes {
+		w.TimeFrom, w.TimeTo = pkg.Clock(w.TimeFrom), pkg.Clock(w.TimeTo)
+		wishes[i] = w
+	}
+
+	return searcher.NewCondition(p.TourName, fields, p.Merges, p.Divisions, coaches, p.Teams, wishes, p.Games, p.Pairings, referees, p.Params)
+}
+
+// Read прочитать задачу из json
+func Read(r io.Reader) (Problem, error) {
+	var p Problem
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return p, fmt.Errorf("файл задачи: %w", err)
+	}
+	if p.Version < 1 || p.Version > Version {
+		return p, fmt.Errorf("файл задачи: версия %d не поддерживается, ожидается до %d", p.Version, Version)
+	}
+	return p, nil
+}
+
+// Write записать задачу в json
+func Write(w io.Writer, p Problem) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
@@ -0,0 +1,177 @@
+package revisions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	Dir         = "revisions" // каталог версий внутри каталога данных, в нем подкаталог на таблицу
+	MaxPerTable = 500         // сколько последних версий таблицы хранится
+)
+
+// Revision версия таблицы справочника после изменения, строки вместе с заголовком
+type Revision struct {
+	ID    string     `json:"id"`
+	Table string     `json:"table"`
+	Time  time.Time  `json:"time"`
+	User  string     `json:"user"`
+	Note  string     `json:"note"` // что сделано, если это не обычная правка
+	IDs   []string   `json:"ids"`  // записи, которые изменились
+	Rows  [][]string `json:"rows"`
+}
+
+// Store версии таблиц, каждая в своем json-файле
+type Store struct {
+	lock   sync.Mutex
+	dir    string
+	lastID string
+}
+
+// NewStore ...
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Record сохранить версию таблицы после изменения. У таблицы без версий
+// сначала сохраняется исходная версия before, чтобы первую правку тоже можно было отменить
+func (s *Store) Record(rev Revision, before [][]string) error {
+	if !validName(rev.Table) {
+		return fmt.Errorf("неверное имя таблицы %s", rev.Table)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ids, err := s.ids(rev.Table)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 && before != nil {
+		base := Revision{Table: rev.Table, Time: rev.Time, Note: "исходная версия", Rows: before}
+		if err := s.write(&base); err != nil {
+			return err
+		}
+		ids = append(ids, base.ID)
+	}
+	if err := s.write(&rev); err != nil {
+		return err
+	}
+	ids = append(ids, rev.ID)
+
+	for len(ids) > MaxPerTable {
+		if err := os.Remove(s.path(rev.Table, ids[0])); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// List версии таблицы от последней к первой
+func (s *Store) List(table string) ([]Revision, error) {
+	if !validName(table) {
+		return nil, fmt.Errorf("неверное имя таблицы %s", table)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ids, err := s.ids(table)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]Revision, 0, len(ids))
+	for i := len(ids) - 1; i >= 0; i-- {
+		rev, err := s.read(table, ids[i])
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, rev)
+	}
+	return list, nil
+}
+
+// Get версия таблицы по ID
+func (s *Store) Get(table, id string) (Revision, error) {
+	if !validName(table) || !validName(id) {
+		return Revision{}, fmt.Errorf("нет версии %s таблицы %s", id, table)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.read(table, id)
+}
+
+// ids версии таблицы от первой к последней, ID упорядочены по времени
+func (s *Store) ids(table string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, table))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasSuffix(e.Name(), ".json") {
+			ids = append(ids, strings.TrimSuffix(e.Name(), ".json"))
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (s *Store) read(table, id string) (Revision, error) {
+	data, err := os.ReadFile(s.path(table, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Revision{}, fmt.Errorf("нет версии %s таблицы %s", id, table)
+	}
+	if err != nil {
+		return Revision{}, err
+	}
+	var rev Revision
+	if err := json.Unmarshal(data, &rev); err != nil {
+		return Revision{}, fmt.Errorf("%s: %w", s.path(table, id), err)
+	}
+	return rev, nil
+}
+
+// write сохранить версию под новым ID, ID не повторяются, даже если правки пришли в одно мгновение
+func (s *Store) write(rev *Revision) error {
+	id := rev.Time.UTC().Format("20060102-150405.000000000")
+	if id <= s.lastID {
+		id = s.lastID + "-1"
+	}
+	s.lastID = id
+	rev.ID = id
+
+	if err := os.MkdirAll(filepath.Join(s.dir, rev.Table), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(rev)
+	if err != nil {
+		return err
+	}
+	tmp := s.path(rev.Table, id) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(rev.Table, id))
+}
+
+func (s *Store) path(table, id string) string {
+	return filepath.Join(s.dir, table, id+".json")
+}
+
+func validName(name string) bool {
+	return name != "" && !strings.ContainsAny(name, `/\`) && !strings.Contains(name, "..")
+}
@@ -0,0 +1,246 @@
+package runs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sergrom/timetable/internal/services/searcher"
+)
+
+const (
+	Dir           = "runs" // каталог запусков внутри каталога данных
+	TopSolutions  = 50     // сколько лучших решений хранится в запуске
+	StatusProcess = "process"
+	StatusStopped = "stopped"
+	// StatusInterrupted запуск не был завершен, потому что сервер перезапустили
+	StatusInterrupted = "interrupted"
+)
+
+// Run запуск поиска с условиями и лучшими решениями
+type Run struct {
+	ID           string              `json:"id"`
+	TourName     string              `json:"tour_name"`
+	Started      time.Time           `json:"started"`
+	Finished     time.Time           `json:"finished"`
+	Status       string              `json:"status"`
+	StopReason   string              `json:"stop_reason"`
+	Attempts     int                 `json:"attempts"`
+	SolutionsCnt int                 `json:"solutions_cnt"` // сколько всего решений найдено
+	Condition    *searcher.Condition `json:"condition"`
+	Solutions    []searcher.Solution `json:"solutions"`
+
+	Resumable bool `json:"-"` // есть точка продолжения и поиск сейчас не идет
+}
+
+// StatusLabel ...
+func (r Run) StatusLabel() string {
+	switch r.Status {
+	case StatusProcess:
+		return "идет поиск"
+	case StatusInterrupted:
+		return "прерван перезапуском"
+	default:
+		return "завершен"
+	}
+}
+
+// Store запуски поиска, каждый в своем json-файле
+type Store struct {
+	lock sync.Mutex
+	dir  string
+}
+
+// NewStore ...
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// NewRunID идентификатор запуска по времени старта
+func NewRunID(t time.Time) string {
+	return fmt.Sprintf("%s-%d", t.Format("20060102-150405"), searcher.SearchCounter)
+}
+
+// Save сохранить запуск, файл перезаписывается целиком
+func (s *Store) Save(run Run) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	return writeFile(s.path(run.ID), data)
+}
+
+// SaveCheckpoint сохранить точку продолжения запуска рядом с ним, nil - удалить ее
+func (s *Store) SaveCheckpoint(id string, cp *searcher.Checkpoint) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !validID(id) {
+		return errors.New("invalid run id")
+	}
+	if cp == nil {
+		if err := os.Remove(s.checkpointPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	return writeFile(s.checkpointPath(id), data)
+}
+
+// Checkpoint точка продолжения запуска
+func (s *Store) Checkpoint(id string) (*searcher.Checkpoint, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !validID(id) {
+		return nil, errors.New("invalid run id")
+	}
+	data, err := os.ReadFile(s.checkpointPath(id))
+	if err != nil {
+		return nil, err
+	}
+	var cp searcher.Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// Get запуск по идентификатору
+func (s *Store) Get(id string) (Run, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.read(id)
+}
+
+// List запуски от последнего к первому, без условий и решений
+func (s *Store) List() ([]Run, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	ids, err := s.ids()
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Run, 0, len(ids))
+	for _, id := range ids {
+		run, err := s.read(id)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		run.Condition, run.Solutions = nil, nil
+		list = append(list, run)
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Started.After(list[j].Started)
+	})
+
+	return list, nil
+}
+
+// MarkInterrupted пометить запуски, которые шли в прошлом процессе и не завершились
+func (s *Store) MarkInterrupted() error {
+	s.lock.Lock()
+	ids, err := s.ids()
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		run, err := s.Get(id)
+		if err != nil || run.Status != StatusProcess {
+			continue
+		}
+		run.Status = StatusInterrupted
+		if err := s.Save(run); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) ids() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	return ids, nil
+}
+
+func (s *Store) read(id string) (Run, error) {
+	var run Run
+	if !validID(id) {
+		return run, errors.New("invalid run id")
+	}
+
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		return run, err
+	}
+	if err := json.Unmarshal(data, &run); err != nil {
+		return run, err
+	}
+
+	if run.Status != StatusProcess {
+		_, err := os.Stat(s.checkpointPath(id))
+		run.Resumable = err == nil
+	}
+	return run, nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func (s *Store) checkpointPath(id string) string {
+	return filepath.Join(s.dir, id+".checkpoint")
+}
+
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\.`)
+}
+
+// writeFile пишем во временный файл, чтобы при падении не остался обрезанный файл
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
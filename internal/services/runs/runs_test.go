@@ -0,0 +1,144 @@
+package runs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sergrom/timetable/internal/services/searcher"
+)
+
+func TestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), Dir)
+	started := time.Date(2024, 5, 18, 10, 0, 0, 0, time.UTC)
+	start := started.Add(time.Hour)
+
+	first := Run{ID: "first", TourName: "Тур 1", Started: started, Status: StatusStopped, Attempts: 10}
+	second := Run{
+		ID:           "second",
+		TourName:     "Тур 2",
+		Started:      started.Add(24 * time.Hour),
+		Status:       StatusProcess,
+		SolutionsCnt: 1,
+		Solutions: []searcher.Solution{{
+			Sum:   3,
+			Games: map[string][]searcher.SolutioGame{"1": {{TeamID1: 1, TeamID2: 2, Start: start, End: start.Add(time.Hour)}}},
+		}},
+	}
+
+	store := NewStore(dir)
+	for _, run := range []Run{first, second} {
+		if err := store.Save(run); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// недописанный файл и лишние файлы в каталоге не мешают
+	if err := os.WriteFile(filepath.Join(dir, "third.json.tmp"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// запуски читаются новым хранилищем, как после перезапуска сервера
+	store = NewStore(dir)
+	got, err := store.Get("second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Solutions, second.Solutions) || got.TourName != second.TourName {
+		t.Errorf("run %+v, want %+v", got, second)
+	}
+
+	list, err := store.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != "second" || list[1].ID != "first" {
+		t.Fatalf("list %+v, want second, first", list)
+	}
+	if list[0].Solutions != nil {
+		t.Error("list must not carry solutions")
+	}
+
+	if err := store.MarkInterrupted(); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]string{"first": StatusStopped, "second": StatusInterrupted} {
+		run, err := store.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.Status != want {
+			t.Errorf("%s: status %s, want %s", id, run.Status, want)
+		}
+	}
+	if run, _ := store.Get("second"); len(run.Solutions) != 1 {
+		t.Error("interrupted run lost its solutions")
+	}
+
+	for _, id := range []string{"", "../first", "first.json", "missing"} {
+		if _, err := store.Get(id); err == nil {
+			t.Errorf("Get(%q): expected error", id)
+		}
+	}
+}
+
+func TestStoreEmptyDir(t *testing.T) {
+	store := NewStore(filepath.Join(t.TempDir(), Dir))
+	list, err := store.List()
+	if err != nil || len(list) != 0 {
+		t.Fatalf("list %v, err %v", list, err)
+	}
+	if err := store.MarkInterrupted(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStoreCheckpoint(t *testing.T) {
+	store := NewStore(t.TempDir())
+	cp := &searcher.Checkpoint{NextNodeIdx: 1, Attempts: 42, Branches: [][]searcher.Step{{{Field: "1", Team1: 1, Team2: 2, Next: 3}}}}
+
+	if err := store.Save(Run{ID: "run", Status: StatusProcess}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveCheckpoint("run", cp); err != nil {
+		t.Fatal(err)
+	}
+	// пока поиск идет, продолжать нечего
+	if run, _ := store.Get("run"); run.Resumable {
+		t.Error("run in process must not be resumable")
+	}
+
+	// сервер перезапустили посреди поиска: запуск прерван, но его можно продолжить
+	if err := NewStore(store.dir).MarkInterrupted(); err != nil {
+		t.Fatal(err)
+	}
+	run, err := store.Get("run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != StatusInterrupted || !run.Resumable {
+		t.Errorf("status %s, resumable %v", run.Status, run.Resumable)
+	}
+	got, err := store.Checkpoint("run")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, cp) {
+		t.Errorf("checkpoint %+v, want %+v", got, cp)
+	}
+
+	// перебор завершен - точка удаляется
+	if err := store.SaveCheckpoint("run", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SaveCheckpoint("run", nil); err != nil {
+		t.Fatalf("second delete: %v", err)
+	}
+	if run, _ := store.Get("run"); run.Resumable {
+		t.Error("run without checkpoint must not be resumable")
+	}
+	if _, err := store.Checkpoint("../run"); err == nil {
+		t.Error("invalid id: expected error")
+	}
+}
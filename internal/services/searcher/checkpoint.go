@@ -0,0 +1,140 @@
+package searcher
+
+import (
+	"fmt"
+)
+
+// Checkpoint точка продолжения поиска. Для каждой первой ноды хранится путь от нее до ноды,
+// которая перебирается сейчас, и курсор nextIdx каждой ноды пути. Следующие ноды генерируются
+// детерминированно, поэтому по пути и курсорам дерево восстанавливается без повторного перебора.
+type Checkpoint struct {
+	NextNodeIdx int      `json:"next_node_idx"` // первая нода, с которой продолжать
+	BestDepth   int      `json:"best_depth"`
+	Attempts    int      `json:"attempts"`
+	Branches    [][]Step `json:"branches"` // по ветке на каждую первую ноду
+}
+
+// Step нода пути: игра и сколько следующих нод уже перебрано
+type Step struct {
+	Field string `json:"field"`
+	Team1 int    `json:"team1"`
+	Team2 int    `json:"team2"`
+	Slot  int    `json:"slot"`
+	Next  int    `json:"next"`
+}
+
+func newStep(n *node) Step {
+	return Step{
+		Field: n.field.String(),
+		Team1: n.teamPair.Team1.ID,
+		Team2: n.teamPair.Team2.ID,
+		Slot:  n.slot,
+		Next:  n.nextIdx,
+	}
+}
+
+func (st Step) key() string {
+	return fmt.Sprintf("%s_%d_%d_%d", st.Field, st.Team1, st.Team2, st.Slot)
+}
+
+func (st Step) String() string {
+	return fmt.Sprintf("%s %d-%d слот %d", st.Field, st.Team1, st.Team2, st.Slot)
+}
+
+// Checkpoint точка продолжения текущего поиска, после остановки - снятая при остановке.
+// Если перебор завершен, продолжать нечего и возвращается nil
+func (s *Searcher) Checkpoint() *Checkpoint {
+	s.lock.RLock()
+	status, req, done := s.status, s.cpReq, s.done
+	s.lock.RUnlock()
+
+	if status == StatusInProcess {
+		// дерево меняется только в горутине поиска, поэтому снимаем точку там же между попытками
+		reply := make(chan *Checkpoint, 1)
+		select {
+		case req <- reply:
+			return <-reply
+		case <-done:
+		}
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.checkpoint
+}
+
+// takeCheckpoint снять точку продолжения с дерева, вызывается под блокировкой из горутины поиска
+func (s *Searcher) takeCheckpoint() *Checkpoint {
+	if s.tree == nil || len(s.tree.firstNodes) == 0 {
+		return nil
+	}
+
+	cp := &Checkpoint{
+		NextNodeIdx: s.nextNodeIdx,
+		BestDepth:   s.bestDepth,
+		Attempts:    s.attempts,
+		Branches:    make([][]Step, 0, len(s.tree.firstNodes)),
+	}
+	for _, root := range s.tree.firstNodes {
+		var branch []Step
+		for cur := root; cur != nil; {
+			branch = append(branch, newStep(cur))
+			if cur.nextIdx >= len(cur.next) {
+				break
+			}
+			cur = cur.next[cur.nextIdx]
+		}
+		cp.Branches = append(cp.Branches, branch)
+	}
+	return cp
+}
+
+// restoreCheckpoint восстановить курсоры дерева по точке продолжения.
+// Ноды перед курсором уже перебраны и не создаются заново
+func (s *Searcher) restoreCheckpoint(cp *Checkpoint) error {
+	roots := make(map[string]*node, len(s.tree.firstNodes))
+	for _, n := range s.tree.firstNodes {
+		roots[newStep(n).key()] = n
+	}
+
+	for _, branch := range cp.Branches {
+		if len(branch) == 0 {
+			continue
+		}
+		cur, ok := roots[branch[0].key()]
+		if !ok {
+			return fmt.Errorf("точка продолжения не подходит к задаче: нет первой игры %s", branch[0])
+		}
+
+		for i, st := range branch {
+			if i > 0 && newStep(cur).key() != st.key() {
+				return fmt.Errorf("точка продолжения не подходит к задаче: на глубине %d игра %s вместо %s", i+1, newStep(cur), st)
+			}
+			last := i == len(branch)-1
+			if last && st.Next == 0 {
+				break // следующие ноды еще не генерировались
+			}
+
+			cur.next = s.genNodes(cur)
+			if st.Next > len(cur.next) || (!last && st.Next == len(cur.next)) {
+				return fmt.Errorf("точка продолжения не подходит к задаче: после игры %s нет варианта %d", st, st.Next+1)
+			}
+			for j := 0; j < st.Next; j++ {
+				cur.next[j] = nil
+			}
+			cur.nextIdx = st.Next
+			if last {
+				break
+			}
+			cur = cur.next[st.Next]
+		}
+	}
+
+	if cp.NextNodeIdx >= 0 && cp.NextNodeIdx < len(s.tree.firstNodes) {
+		s.nextNodeIdx = cp.NextNodeIdx
+		s.nextNode = s.tree.firstNodes[cp.NextNodeIdx]
+	}
+	s.bestDepth = cp.BestDepth
+	s.attempts = cp.Attempts
+	return nil
+}
@@ -0,0 +1,232 @@
+package searcher
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sergrom/timetable/internal/ds"
+)
+
+// smallCondition маленькая задача: 6 команд, 3 поля по 3 слота. Перебор идет дольше любого теста
+func smallCondition(t *testing.T) *Condition {
+	return testCondition(t, 6, 3, "12:00")
+}
+
+// testCondition задача из teamsCnt команд разных тренеров одного дивизиона
+// и fieldsCnt полей с 09:00 до to, игра - час
+func testCondition(t *testing.T, teamsCnt, fieldsCnt int, to string) *Condition {
+	t.Helper()
+	var fields []ds.Field
+	for id := 1; id <= fieldsCnt; id++ {
+		f, err := ds.NewField(id, 6, time.Hour, 0, "09:00", to, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fields = append(fields, f)
+	}
+	divisions := []ds.Division{{ID: 1, Name: "2012", Format: 6}}
+	var coaches []ds.Coach
+	var teams []ds.Team
+	for id := 1; id <= teamsCnt; id++ {
+		coaches = append(coaches, ds.Coach{ID: id})
+		teams = append(teams, ds.Team{ID: id, Name: string(rune('А' + id - 1)), CoachID: id, DivisionID: 1})
+	}
+	cond, err := NewCondition("1", fields, nil, divisions, coaches, teams, nil, nil, nil, nil, DefaultParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cond
+}
+
+// treeSearcher поисковик с первыми нодами маленькой задачи
+func treeSearcher(t *testing.T) *Searcher {
+	t.Helper()
+	s := NewSearcher(Settings{})
+	s.Condition = smallCondition(t)
+	s.tree = newSearchTree()
+	firstNodes, err := s.genFirstNodes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(firstNodes) < 2 {
+		t.Fatalf("first nodes: %d", len(firstNodes))
+	}
+	s.tree.setFirstNodes(firstNodes)
+	s.nextNode = firstNodes[0]
+	return s
+}
+
+// walk спуститься от первой ноды root, на каждой глубине пропустив skip[i] вариантов
+func walk(t *testing.T, s *Searcher, root int, skip ...int) {
+	t.Helper()
+	cur := s.tree.firstNodes[root]
+	for i, k := range skip {
+		cur.next = s.genNodes(cur)
+		if k >= len(cur.next) {
+			t.Fatalf("depth %d: %d variants, skip %d", i+1, len(cur.next), k)
+		}
+		for j := 0; j < k; j++ {
+			cur.next[j] = nil
+		}
+		cur.nextIdx = k
+		cur = cur.next[k]
+	}
+}
+
+func TestRestoreCheckpoint(t *testing.T) {
+	src := treeSearcher(t)
+	walk(t, src, 0, 1, 0)
+	walk(t, src, 1, 2)
+	src.nextNodeIdx = 1
+	src.bestDepth = 3
+	src.attempts = 42
+	cp := src.takeCheckpoint()
+
+	tests := []struct {
+		name    string
+		change  func(cp *Checkpoint)
+		wantErr bool
+	}{
+		{name: "same problem", change: func(cp *Checkpoint) {}},
+		{name: "empty branches are skipped", change: func(cp *Checkpoint) { cp.Branches = append(cp.Branches, nil) }},
+		{name: "unknown first game", change: func(cp *Checkpoint) { cp.Branches[0][0].Team1 = 99 }, wantErr: true},
+		{name: "another game deeper", change: func(cp *Checkpoint) { cp.Branches[0][1].Slot = 99 }, wantErr: true},
+		{name: "cursor past the variants", change: func(cp *Checkpoint) { cp.Branches[0][0].Next = 999 }, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := copyCheckpoint(cp)
+			tc.change(c)
+
+			dst := treeSearcher(t)
+			err := dst.restoreCheckpoint(c)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("error %v, want error %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := dst.takeCheckpoint(); !reflect.DeepEqual(got, cp) {
+				t.Errorf("restored %+v, want %+v", got, cp)
+			}
+			if dst.nextNode != dst.tree.firstNodes[1] {
+				t.Error("next node is not restored")
+			}
+			if root := dst.tree.firstNodes[0]; root.next[0] != nil {
+				t.Error("passed variants must not be kept")
+			}
+		})
+	}
+}
+
+func TestShutdownAndResume(t *testing.T) {
+	s := NewSearcher(Settings{MaxMemoryMB: 1024, RotateInterval: time.Hour})
+	if _, _, err := s.Search(smallCondition(t)); err != nil {
+		t.Fatal(err)
+	}
+	s.Shutdown()
+
+	sols, attempts := s.GetSolutions()
+	cp := s.Checkpoint()
+	if cp == nil {
+		t.Fatal("no checkpoint after shutdown")
+	}
+	if attempts == 0 || cp.Attempts != attempts {
+		t.Fatalf("checkpoint attempts %d, search attempts %d", cp.Attempts, attempts)
+	}
+	if len(sols) == 0 {
+		t.Fatal("small problem must have solutions")
+	}
+
+	// продолжение начинает со счетчика попыток точки и не теряет найденные решения
+	resumed, resumedAttempts, err := s.Resume(smallCondition(t), cp, sols)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Stop()
+	if resumedAttempts <= cp.Attempts {
+		t.Errorf("resumed attempts %d, must go on from %d", resumedAttempts, cp.Attempts)
+	}
+	if len(resumed) < len(sols) {
+		t.Errorf("resumed search has %d solutions, had %d", len(resumed), len(sols))
+	}
+	if next := s.Checkpoint(); next == nil || next.Attempts < resumedAttempts {
+		t.Errorf("checkpoint after resume %+v", next)
+	}
+}
+
+func TestResultOfFinishedSearch(t *testing.T) {
+	s := NewSearcher(Settings{MaxMemoryMB: 1024, RotateInterval: time.Hour})
+	if _, _, err := s.Search(smallCondition(t)); err != nil {
+		t.Fatal(err)
+	}
+	firstID, result := s.Current()
+	s.Stop()
+
+	// итог первого поиска не подменяется следующим, даже если его прочитали после нового запуска
+	if _, _, err := s.Search(smallCondition(t)); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+	if s.SearchID() == firstID {
+		t.Fatal("new search has the same id")
+	}
+
+	res := <-result
+	if res.SearchID != firstID || res.Reason != StopReasonUser {
+		t.Errorf("result of search %d stopped with %q, want search %d stopped by user", res.SearchID, res.Reason, firstID)
+	}
+	if res.Checkpoint == nil || res.Checkpoint.Attempts != res.Attempts || res.Attempts == 0 {
+		t.Errorf("result attempts %d, checkpoint %+v", res.Attempts, res.Checkpoint)
+	}
+	if len(res.Solutions) == 0 {
+		t.Fatal("small problem must have solutions")
+	}
+	prefix := fmt.Sprintf("%d_", firstID)
+	for i, sl := range res.Solutions {
+		if !strings.HasPrefix(sl.HashStr, prefix) {
+			t.Errorf("solution %s is not from search %d", sl.HashStr, firstID)
+		}
+		if i > 0 && sl.Sum < res.Solutions[i-1].Sum {
+			t.Error("solutions are not sorted by sum")
+		}
+	}
+}
+
+func copyCheckpoint(cp *Checkpoint) *Checkpoint {
+	c := *cp
+	c.Branches = make([][]Step, 0, len(cp.Branches))
+	for _, b := range cp.Branches {
+		c.Branches = append(c.Branches, append([]Step(nil), b...))
+	}
+	return &c
+}
+
+func TestSearchFinishes(t *testing.T) {
+	s := NewSearcher(Settings{MaxMemoryMB: 1024, RotateInterval: time.Millisecond})
+	// 3 команды на одном поле: вариантов мало, перебор заканчивается за секунды
+	if _, _, err := s.Search(testCondition(t, 3, 1, "12:00")); err != nil {
+		t.Fatal(err)
+	}
+	_, result := s.Current()
+
+	select {
+	case res := <-result:
+		if res.Reason != StopReasonFinished {
+			t.Errorf("search stopped with %q, want %q", res.Reason, StopReasonFinished)
+		}
+		if res.Checkpoint != nil {
+			t.Errorf("finished search has a checkpoint %+v", res.Checkpoint)
+		}
+		if len(res.Solutions) == 0 {
+			t.Error("small problem must have solutions")
+		}
+	case <-time.After(10 * time.Second):
+		s.Stop()
+		t.Fatal("search of a small problem does not finish")
+	}
+}
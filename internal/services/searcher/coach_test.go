@@ -0,0 +1,60 @@
+package searcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sergrom/timetable/internal/ds"
+)
+
+func TestIsCoachRunOk(t *testing.T) {
+	// игры тренера по 50 минут, начало - в минутах от 9:00
+	base := clock(t, "09:00")
+	game := func(min int) tInterval {
+		from := base.Add(time.Duration(min) * time.Minute)
+		return tInterval{from: from, to: from.Add(50 * time.Minute)}
+	}
+
+	tests := []struct {
+		name  string
+		coach ds.Coach
+		prev  []int
+		next  int
+		want  bool
+	}{
+		{name: "no limit", coach: ds.Coach{ID: 1}, prev: []int{0, 60, 120}, next: 180, want: true},
+		{name: "first game", coach: ds.Coach{ID: 1, MaxConsecutive: 1}, next: 0, want: true},
+		{name: "back to back over the limit", coach: ds.Coach{ID: 1, MaxConsecutive: 2}, prev: []int{0, 50}, next: 100},
+		{name: "over the limit before the others", coach: ds.Coach{ID: 1, MaxConsecutive: 2}, prev: []int{50, 100}, next: 0},
+		{name: "game between two runs joins them", coach: ds.Coach{ID: 1, MaxConsecutive: 2}, prev: []int{0, 100}, next: 50},
+		{name: "any gap is a break by default", coach: ds.Coach{ID: 1, MaxConsecutive: 2}, prev: []int{0, 50}, next: 110, want: true},
+		{
+			name:  "short gap does not break the run",
+			coach: ds.Coach{ID: 1, MaxConsecutive: 2, BreakDur: 30 * time.Minute},
+			prev:  []int{0, 60},
+			next:  120,
+		},
+		{
+			name:  "long enough gap breaks the run",
+			coach: ds.Coach{ID: 1, MaxConsecutive: 2, BreakDur: 30 * time.Minute},
+			prev:  []int{0, 50},
+			next:  130,
+			want:  true,
+		},
+		{name: "unknown coach", coach: ds.Coach{ID: 2, MaxConsecutive: 1}, prev: []int{0}, next: 50, want: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Condition{coachMap: map[int]*ds.Coach{tc.coach.ID: &tc.coach}}
+			prev := make([]tInterval, 0, len(tc.prev))
+			for _, m := range tc.prev {
+				prev = append(prev, game(m))
+			}
+			next := game(tc.next)
+			if got := c.isCoachRunOk(1, prev, next.from, next.to); got != tc.want {
+				t.Errorf("ok = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
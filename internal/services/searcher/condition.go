@@ -2,50 +2,83 @@ package searcher
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/pkg"
 )
 
 type Condition struct {
-	TourName       string
-	Fields         []ds.Field
-	Divisions      []ds.Division
-	Coaches        []ds.Coach
-	Teams          []ds.Team
-	Wishes         []ds.Wish
-	Games          []ds.Game
-	TeamsPrettyMap map[int]string
-	DayStart       time.Time
-	DayEnd         time.Time
+	TourName          string
+	Fields            []ds.Field
+	Merges            []ds.FieldMerge
+	Divisions         []ds.Division
+	Coaches           []ds.Coach
+	Teams             []ds.Team
+	Wishes            []ds.Wish
+	Games             []ds.Game
+	Pairings          []ds.Game // заданные пары тура (из плана сезона), если пусто - пары подбираются
+	Referees          []ds.Referee
+	TeamsPrettyMap    map[int]string
+	RefereesPrettyMap map[int]string
+	DayStart          time.Time
+	DayEnd            time.Time
+	Params            Params
 
 	teamsByDivs        map[int][]*ds.Team
+	teamsByGroups      map[int][]*ds.Team // команды по группам дивизионов, которые могут играть между собой
+	divGroups          map[int]int        // группа дивизиона
 	teamsByIDs         map[int]*ds.Team
 	teamFormats        map[int]int
 	divMap             map[int]*ds.Division
 	coachMap           map[int]*ds.Coach
-	wishMap            map[int]*ds.Wish
-	teamSlots          map[int][]int
+	wishMap            map[int][]*ds.Wish
+	teamNodeSlots      map[int]map[*ds.FieldNode][]int // команда -> поле -> слоты, в которые команда может играть
 	teamPairsMap       map[int][]*ds.TeamPair
 	teamPairsByTeamMap map[int][]*ds.TeamPair
+	pairPenalty        map[*ds.TeamPair]int
+	slotPenalty        map[int]map[nodeSlot]int       // команда -> поле и слот -> штраф за пожелания
+	bannedNodes        map[int]map[*ds.FieldNode]bool // команда -> поля, на которых она не может играть
+	mustPairs          map[int][]*ds.TeamPair         // команда -> пары, которые обязательно должны быть сыграны
 	fieldNodes         []*ds.FieldNode
+	fieldBreaks        map[string][]ds.Break // поле -> перерывы, для вывода в решении
+	nodeStadiums       map[string]int        // поле -> стадион
+	nodeVenues         map[string]string     // поле -> стадион и дата, для вывода в решении
 	coachGameCnt       map[int]int
-	stadSlotsCnt       int
+	gamesNeed          map[int]int   // сколько игр должна сыграть команда
+	gamesCnt           int           // сколько всего игр в туре
+	unit               time.Duration // самая короткая игра, единица для подсчета штрафов
+	buffer             time.Duration // самая длинная пересменка, такой промежуток между играми - не отдых
+	clockStart         time.Time     // самое раннее начало игр без учета даты
 }
 
-func NewCondition(tourName string, fields []ds.Field, divisions []ds.Division, coaches []ds.Coach, teams []ds.Team, wishes []ds.Wish, games []ds.Game) *Condition {
+type nodeSlot struct {
+	fNode *ds.FieldNode
+	slot  int
+}
+
+func NewCondition(tourName string, fields []ds.Field, merges []ds.FieldMerge, divisions []ds.Division, coaches []ds.Coach, teams []ds.Team, wishes []ds.Wish, games []ds.Game, pairings []ds.Game, referees []ds.Referee, params Params) (*Condition, error) {
 	cond := &Condition{
 		TourName:  tourName,
 		Fields:    fields,
+		Merges:    merges,
 		Divisions: divisions,
 		Coaches:   coaches,
 		Teams:     teams,
 		Wishes:    wishes,
 		Games:     games,
+		Pairings:  pairings,
+		Referees:  referees,
+		Params:    params,
 	}
 
 	dayStart := fields[0].TimeFrom
 	dayEnd := fields[0].TimeTo
+	clockStart, clockEnd := pkg.Clock(dayStart), pkg.Clock(dayEnd) // границы дня без учета даты, для пожеланий
+	unit := fields[0].GameDur
+	buffer := time.Duration(0)
 	for i := range fields {
 		t1 := fields[i].TimeFrom
 		t2 := fields[i].TimeTo
@@ -55,9 +88,24 @@ func NewCondition(tourName string, fields []ds.Field, divisions []ds.Division, c
 		if t2.After(dayEnd) {
 			dayEnd = t2
 		}
+		if c1 := pkg.Clock(t1); c1.Before(clockStart) {
+			clockStart = c1
+		}
+		if c2 := pkg.Clock(t2); c2.After(clockEnd) {
+			clockEnd = c2
+		}
+		if fields[i].GameDur < unit {
+			unit = fields[i].GameDur
+		}
+		if fields[i].Buffer > buffer {
+			buffer = fields[i].Buffer
+		}
 	}
 	cond.DayStart = dayStart
 	cond.DayEnd = dayEnd
+	cond.unit = unit
+	cond.buffer = buffer
+	cond.clockStart = clockStart
 
 	divMap := make(map[int]*ds.Division, len(cond.Divisions))
 	for i := range cond.Divisions {
@@ -79,8 +127,17 @@ func NewCondition(tourName string, fields []ds.Field, divisions []ds.Division, c
 		teamsPrettyMap[tID] = fmt.Sprintf("%s (%s)", team.Name, div.Name)
 	}
 
+	refereesPrettyMap := make(map[int]string, len(referees))
+	for _, r := range referees {
+		refereesPrettyMap[r.ID] = r.Name
+	}
+
 	rematchRestricts := make(map[int]map[int]string)
 	for _, g := range games {
+		if g.CanRematch == 1 {
+			// переигровка разрешена, такая встреча только штрафуется (см. calcRematchPenalty)
+			continue
+		}
 		id1, id2 := g.TeamID1, g.TeamID2
 		if id1 > id2 {
 			id1, id2 = id2, id1
@@ -91,30 +148,133 @@ func NewCondition(tourName string, fields []ds.Field, divisions []ds.Division, c
 		rematchRestricts[id1][id2] = g.Tour
 	}
 
+	rematchPenalty := calcRematchPenalty(games, params)
+
+	divLinks, divGroups := groupDivisions(cond.Divisions)
+	teamsByGroups := make(map[int][]*ds.Team, len(teamsByDivs))
+	for i := range teams {
+		group := divGroups[teams[i].DivisionID]
+		teamsByGroups[group] = append(teamsByGroups[group], &teams[i])
+	}
+
+	// пожелания по соперникам
+	mustPairKeys := make(map[[2]int]bool)
+	notPairKeys := make(map[[2]int]bool)
+	for _, w := range cond.Wishes {
+		switch w.Kind {
+		case ds.WishOpponent:
+			mustPairKeys[pairKey(w.TeamID, w.Value)] = true
+		case ds.WishNotOpponent:
+			notPairKeys[pairKey(w.TeamID, w.Value)] = true
+		}
+	}
+
+	// пару, которую просили поставить, нельзя молча выбросить - лучше сразу сказать, почему ее не будет
+	if len(pairings) == 0 {
+		if err := checkMustPairs(mustPairKeys, notPairKeys, teamsByIDs, divMap, divGroups, rematchRestricts); err != nil {
+			return nil, err
+		}
+	}
+
+	// заданные пары тура: играются только они, первая команда - хозяин
+	planned := make(map[[2]int]int, len(pairings))
+	gamesNeed := make(map[int]int, len(teams))
+	for _, g := range pairings {
+		if _, ok := teamsByIDs[g.TeamID1]; !ok {
+			continue
+		}
+		if _, ok := teamsByIDs[g.TeamID2]; !ok {
+			continue
+		}
+		planned[pairKey(g.TeamID1, g.TeamID2)] = g.TeamID1
+		gamesNeed[g.TeamID1]++
+		gamesNeed[g.TeamID2]++
+	}
+	if len(pairings) == 0 {
+		for tID := range teamsByIDs {
+			gamesNeed[tID] = GamesPerTeam
+		}
+	}
+	gamesCnt := 0
+	for _, cnt := range gamesNeed {
+		gamesCnt += cnt
+	}
+	gamesCnt /= 2
+
 	// Собираем пары команд, которые могут между собой играть
-	teamPairsMap := make(map[int][]*ds.TeamPair, len(teamsByDivs)) // Пары команд
+	teamPairsMap := make(map[int][]*ds.TeamPair, len(teamsByGroups)) // Пары команд по группам дивизионов
 	teamPairsByTeamMap := make(map[int][]*ds.TeamPair)
+	pairPenalty := make(map[*ds.TeamPair]int)
+	mustPairs := make(map[int][]*ds.TeamPair)
 	coachGameCnt := make(map[int]int, len(coaches)) // Игры тренеров
-	for divID, tt := range teamsByDivs {
-		if _, ok := teamPairsMap[divID]; !ok {
-			teamPairsMap[divID] = make([]*ds.TeamPair, 0, len(tt)*len(tt)/2+1)
+	for group, tt := range teamsByGroups {
+		if _, ok := teamPairsMap[group]; !ok {
+			teamPairsMap[group] = make([]*ds.TeamPair, 0, len(tt)*len(tt)/2+1)
 		}
 
 		for i1 := 0; i1 < len(tt); i1++ {
 			for i2 := i1 + 1; i2 < len(tt); i2++ {
+				key := pairKey(tt[i1].ID, tt[i2].ID)
+				if len(pairings) > 0 {
+					home, ok := planned[key]
+					if !ok {
+						continue // пары нет в плане тура
+					}
+					tp := &ds.TeamPair{Team1: tt[i1], Team2: tt[i2]}
+					if home == tt[i2].ID {
+						tp = &ds.TeamPair{Team1: tt[i2], Team2: tt[i1]}
+					}
+					teamPairsMap[group] = append(teamPairsMap[group], tp)
+					teamPairsByTeamMap[tt[i1].ID] = append(teamPairsByTeamMap[tt[i1].ID], tp)
+					teamPairsByTeamMap[tt[i2].ID] = append(teamPairsByTeamMap[tt[i2].ID], tp)
+					coachGameCnt[tt[i1].CoachID]++
+					coachGameCnt[tt[i2].CoachID]++
+					continue
+				}
+
 				if tt[i1].CoachID == tt[i2].CoachID {
 					// команды одного тренера не могут играть между собой (уловие из тз)
 					continue
 				}
 
+				if notPairKeys[key] {
+					// команда просила не ставить ей этого соперника
+					continue
+				}
+				// пара, которую просили поставить, не ограничивается связями дивизионов и рейтингом
+				must := mustPairKeys[key]
+
+				crossDiv := tt[i1].DivisionID != tt[i2].DivisionID
+				if crossDiv && !must && !divLinks[pairKey(tt[i1].DivisionID, tt[i2].DivisionID)] {
+					// дивизионы в одной группе, но напрямую между собой не играют
+					continue
+				}
+
 				if !canRematch(tt[i1].ID, tt[i2].ID, rematchRestricts) {
-					// ранее была игра между этими командами и снова играть нельзя
+					// ранее была игра между этими командами и снова играть нельзя, даже если пару просили
+					continue
+				}
+
+				ratingDiff := calcRatingDiff(tt[i1], tt[i2])
+				if !must && params.RatingMaxDiff > 0 && ratingDiff > params.RatingMaxDiff {
+					// слишком разные по силе команды
 					continue
 				}
 
 				// все ок, добавляем пару команд
 				tp := &ds.TeamPair{Team1: tt[i1], Team2: tt[i2]}
-				teamPairsMap[divID] = append(teamPairsMap[divID], tp)
+				teamPairsMap[group] = append(teamPairsMap[group], tp)
+				if must {
+					mustPairs[tt[i1].ID] = append(mustPairs[tt[i1].ID], tp)
+					mustPairs[tt[i2].ID] = append(mustPairs[tt[i2].ID], tp)
+				}
+				penalty := rematchPenalty[key] + params.RatingWeight*ratingDiff/100
+				if crossDiv {
+					penalty += params.CrossDivWeight
+				}
+				if penalty > 0 {
+					pairPenalty[tp] = penalty
+				}
 
 				if _, ok := teamPairsByTeamMap[tt[i1].ID]; !ok {
 					teamPairsByTeamMap[tt[i1].ID] = make([]*ds.TeamPair, 0, 10)
@@ -131,35 +291,40 @@ func NewCondition(tourName string, fields []ds.Field, divisions []ds.Division, c
 		}
 	}
 
-	// Собираем возможные поля и пары полей
-	stadSlots := 0
-	fieldNodes := make([]*ds.FieldNode, 0, len(cond.Fields))
+	// Собираем возможные поля и объединения полей
+	fieldNodes := make([]*ds.FieldNode, 0, len(cond.Fields)+len(cond.Merges))
+	fieldsByIDs := make(map[int]*ds.Field, len(cond.Fields))
+	fieldBreaks := make(map[string][]ds.Break)
 	for i := range cond.Fields {
-		fieldNodes = append(fieldNodes, &ds.FieldNode{Field1: &cond.Fields[i]})
-		if cnt := int(cond.Fields[i].TimeTo.Sub(cond.Fields[i].TimeFrom).Minutes() / cond.Fields[i].GameDur.Minutes()); cnt > stadSlots {
-			stadSlots = cnt
+		fNode := ds.NewFieldNode(&cond.Fields[i])
+		fieldNodes = append(fieldNodes, fNode)
+		fieldsByIDs[cond.Fields[i].ID] = &cond.Fields[i]
+		if len(cond.Fields[i].Breaks) > 0 {
+			fieldBreaks[fNode.String()] = cond.Fields[i].Breaks
 		}
 	}
-	has7 := false
-	for i := range cond.Divisions {
-		if cond.Divisions[i].Format == 7 && len(teamsByDivs[cond.Divisions[i].ID]) > 0 {
-			has7 = true
-			break
+	for _, m := range cond.Merges {
+		if !hasFormat(cond.Divisions, teamsByDivs, m.Format) {
+			continue // объединенное поле никому не нужно
 		}
-	}
-	if has7 {
-		// если есть команды которые играют по формату 7, то добавляем пары полей форматов меньше 7
-		if len(cond.Fields) > 1 {
-			fieldNodes = append(fieldNodes, &ds.FieldNode{
-				Field1: &cond.Fields[0],
-				Field2: &cond.Fields[1],
-			})
+		ff := make([]*ds.Field, 0, len(m.FieldIDs))
+		for _, fID := range m.FieldIDs {
+			if f, ok := fieldsByIDs[fID]; ok {
+				ff = append(ff, f)
+			}
 		}
-		if len(cond.Fields) > 3 {
-			fieldNodes = append(fieldNodes, &ds.FieldNode{
-				Field1: &cond.Fields[2],
-				Field2: &cond.Fields[3],
-			})
+		if len(ff) < len(m.FieldIDs) {
+			continue // какого-то из полей нет в этом туре
+		}
+		fieldNodes = append(fieldNodes, ds.NewMergedFieldNode(ff, m.Format))
+	}
+
+	nodeStadiums := make(map[string]int, len(fieldNodes))
+	nodeVenues := make(map[string]string, len(fieldNodes))
+	for _, fNode := range fieldNodes {
+		nodeStadiums[fNode.String()] = fNode.StadiumID()
+		if venue := fNode.Fields()[0].Venue; venue != "" {
+			nodeVenues[fNode.String()] = venue
 		}
 	}
 
@@ -168,43 +333,99 @@ func NewCondition(tourName string, fields []ds.Field, divisions []ds.Division, c
 		coachMap[cond.Coaches[i].ID] = &cond.Coaches[i]
 	}
 
-	wishMap := make(map[int]*ds.Wish, len(cond.Wishes))
+	wishMap := make(map[int][]*ds.Wish, len(cond.Wishes))
 	for i := range cond.Wishes {
-		wishMap[cond.Wishes[i].TeamID] = &cond.Wishes[i]
+		wishMap[cond.Wishes[i].TeamID] = append(wishMap[cond.Wishes[i].TeamID], &cond.Wishes[i])
 	}
 
-	fieldStart, gameDur := cond.Fields[0].TimeFrom, cond.Fields[0].GameDur
-	teamSlots := make(map[int][]int)
-	for tID := range teamsByIDs {
-		slots := make([]int, 0, stadSlots)
-		w, ok := wishMap[tID]
-		for i := 0; i < stadSlots; i++ {
-			if !ok {
-				slots = append(slots, i)
+	bannedNodes := make(map[int]map[*ds.FieldNode]bool)
+	for tID, ww := range wishMap {
+		for _, fNode := range fieldNodes {
+			if isFieldNodeOk(fNode, ww) {
+				continue
+			}
+			if _, ok := bannedNodes[tID]; !ok {
+				bannedNodes[tID] = make(map[*ds.FieldNode]bool)
+			}
+			bannedNodes[tID][fNode] = true
+		}
+	}
+
+	// Слоты команд на каждом поле со своей сеткой времени
+	teamNodeSlots := make(map[int]map[*ds.FieldNode][]int, len(teamsByIDs))
+	slotPenalty := make(map[int]map[nodeSlot]int)
+	for tID, team := range teamsByIDs {
+		teamNodeSlots[tID] = make(map[*ds.FieldNode][]int, len(fieldNodes))
+		div := divMap[team.DivisionID]
+		groups := teamWishGroups(clockStart, clockEnd, wishMap[tID])
+		coach := coachMap[team.CoachID]
+		for _, fNode := range fieldNodes {
+			if !fNode.Fits(div.Format) {
+				continue
+			}
+			if bannedNodes[tID][fNode] {
 				continue
 			}
-			if w.IsSlotOk(fieldStart, gameDur, i) {
+
+			slots := make([]int, 0, fNode.SlotsCnt())
+			for i := 0; i < fNode.SlotsCnt(); i++ {
+				// время тренеров и пожеланий задано без даты, сравниваем со временем дня игры
+				from, to := fNode.GetFromTo(i)
+				from, to = pkg.Clock(from), pkg.Clock(to)
+				if coach != nil && !coach.IsAvailable(from, to) {
+					continue // тренера еще нет или он уже уехал
+				}
+				if !isInGroups(groups, from, to) {
+					continue
+				}
 				slots = append(slots, i)
+				if penalty := calcPreferPenalty(wishMap[tID], from, to); penalty > 0 {
+					if _, ok := slotPenalty[tID]; !ok {
+						slotPenalty[tID] = make(map[nodeSlot]int)
+					}
+					slotPenalty[tID][nodeSlot{fNode, i}] = penalty
+				}
 			}
+			teamNodeSlots[tID][fNode] = slots
 		}
-		teamSlots[tID] = slots
 	}
 
 	cond.teamsByDivs = teamsByDivs
+	cond.teamsByGroups = teamsByGroups
+	cond.divGroups = divGroups
 	cond.teamsByIDs = teamsByIDs
 	cond.teamFormats = teamFormats
 	cond.TeamsPrettyMap = teamsPrettyMap
+	cond.RefereesPrettyMap = refereesPrettyMap
 	cond.divMap = divMap
 	cond.coachMap = coachMap
 	cond.wishMap = wishMap
-	cond.teamSlots = teamSlots
+	cond.teamNodeSlots = teamNodeSlots
 	cond.teamPairsMap = teamPairsMap
 	cond.teamPairsByTeamMap = teamPairsByTeamMap
+	cond.pairPenalty = pairPenalty
+	cond.slotPenalty = slotPenalty
+	cond.bannedNodes = bannedNodes
+	cond.mustPairs = mustPairs
 	cond.fieldNodes = fieldNodes
+	cond.fieldBreaks = fieldBreaks
+	cond.nodeStadiums = nodeStadiums
+	cond.nodeVenues = nodeVenues
 	cond.coachGameCnt = coachGameCnt
-	cond.stadSlotsCnt = stadSlots
+	cond.gamesNeed = gamesNeed
+	cond.gamesCnt = gamesCnt
 
-	return cond
+	return cond, nil
+}
+
+// hasFormat есть ли в туре команды, которые играют по формату format
+func hasFormat(divisions []ds.Division, teamsByDivs map[int][]*ds.Team, format int) bool {
+	for i := range divisions {
+		if divisions[i].Format == format && len(teamsByDivs[divisions[i].ID]) > 0 {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *Condition) Dump() {
@@ -227,10 +448,21 @@ func (c *Condition) Dump() {
 	}
 
 	fmt.Println()
-	fmt.Println("Возможные пары команд по дивизионам:")
-	for divID, pairs := range c.teamPairsMap {
-		fmt.Printf("Дивизион: %s (формат: %d)\n", c.divMap[divID].Name, c.divMap[divID].Format)
+	fmt.Println("Возможные пары команд по группам дивизионов:")
+	for group, pairs := range c.teamPairsMap {
+		names := make([]string, 0, 1)
+		for divID, g := range c.divGroups {
+			if g == group {
+				names = append(names, c.divMap[divID].Name)
+			}
+		}
+		sort.Strings(names)
+		fmt.Printf("Дивизионы: %s (формат: %d)\n", strings.Join(names, ", "), c.divMap[group].Format)
 		for _, p := range pairs {
+			if penalty, ok := c.pairPenalty[p]; ok {
+				fmt.Printf("	%s (штраф: %d)\n", p, penalty)
+				continue
+			}
 			fmt.Printf("	%s\n", p)
 		}
 	}
@@ -250,6 +482,107 @@ func (c *Condition) getFieldNodes() []*ds.FieldNode {
 	return c.fieldNodes
 }
 
+// nodePenalty штраф за пару соперников и время игры
+func (c *Condition) nodePenalty(pair *ds.TeamPair, fNode *ds.FieldNode, slot int) int {
+	key := nodeSlot{fNode, slot}
+	return c.pairPenalty[pair] + c.slotPenalty[pair.Team1.ID][key] + c.slotPenalty[pair.Team2.ID][key]
+}
+
+// units сколько самых коротких игр помещается в промежуток d
+func (c *Condition) units(d time.Duration) int {
+	return int(d / c.unit)
+}
+
+// pos номер самой короткой игры от начала дня, с которой начинается t
+func (c *Condition) pos(t time.Time) int {
+	return c.units(pkg.Clock(t).Sub(c.clockStart))
+}
+
+// isTeamTimeOk игры команды не пересекаются, и новая игра не дальше чем через одну игру от предыдущей.
+// Если игры на разных стадионах, между ними должно хватать времени на переезд
+func (c *Condition) isTeamTimeOk(prev []tInterval, from, to time.Time, stadiumID int, maxGap time.Duration) bool {
+	if len(prev) == 0 {
+		return true
+	}
+	near := false
+	for _, iv := range prev {
+		if iv.overlaps(from, to) {
+			return false
+		}
+		if !iv.travelOk(from, to, stadiumID, c.Params.TravelDur) {
+			return false
+		}
+		gap := iv.gap(from, to)
+		if iv.stadiumID != stadiumID {
+			gap -= c.Params.TravelDur
+		}
+		if gap <= maxGap {
+			near = true
+		}
+	}
+	return near
+}
+
+// isCoachTimeOk тренер не занят в это время, успевает переехать между стадионами,
+// его игры не растягиваются на весь день и он не превышает лимит игр подряд
+func (c *Condition) isCoachTimeOk(coachID int, prev []tInterval, coachTeamsCnt int, from, to time.Time, stadiumID int) bool {
+	if len(prev) == 0 {
+		return true
+	}
+
+	minFrom, maxFrom := from, from
+	for _, iv := range prev {
+		if iv.overlaps(from, to) {
+			return false
+		}
+		if !iv.travelOk(from, to, stadiumID, c.Params.TravelDur) {
+			return false
+		}
+		if !iv.sameDay(from) {
+			continue // игры в другие дни не растягивают этот день
+		}
+		if iv.from.Before(minFrom) {
+			minFrom = iv.from
+		}
+		if iv.from.After(maxFrom) {
+			maxFrom = iv.from
+		}
+	}
+	if c.units(maxFrom.Sub(minFrom)) >= coachTeamsCnt*3 {
+		return false
+	}
+
+	return c.isCoachRunOk(coachID, prev, from, to)
+}
+
+// isCoachRunOk тренер не превышает лимит игр подряд.
+// Игры считаются подряд, если перерыв между ними меньше BreakDur тренера или это только пересменка
+func (c *Condition) isCoachRunOk(coachID int, prev []tInterval, from, to time.Time) bool {
+	coach := c.coachMap[coachID]
+	if coach == nil || coach.MaxConsecutive <= 0 {
+		return true
+	}
+
+	ivs := append(append(make([]tInterval, 0, len(prev)+1), prev...), tInterval{from: from, to: to})
+	sort.Slice(ivs, func(i, j int) bool {
+		return ivs[i].from.Before(ivs[j].from)
+	})
+
+	run := 1
+	for i := 1; i < len(ivs); i++ {
+		gap := ivs[i].from.Sub(ivs[i-1].to)
+		if gap > c.buffer && gap >= coach.BreakDur {
+			run = 1
+			continue
+		}
+		if run++; run > coach.MaxConsecutive {
+			return false
+		}
+	}
+
+	return true
+}
+
 func canRematch(teamID1, teamID2 int, rematchRestricts map[int]map[int]string) bool {
 	id1, id2 := teamID1, teamID2
 	if id1 > id2 {
@@ -261,3 +594,148 @@ func canRematch(teamID1, teamID2 int, rematchRestricts map[int]map[int]string) b
 	}
 	return true
 }
+
+// calcRematchPenalty штраф за повторную встречу пар, которым переигровка разрешена.
+// Чем свежее тур, тем больше штраф, учитываются только последние params.RematchTours туров.
+// Порядок туров - см. orderTours
+func calcRematchPenalty(games []ds.Game, params Params) map[[2]int]int {
+	penalty := make(map[[2]int]int)
+	if params.RematchTours <= 0 || params.RematchWeight <= 0 {
+		return penalty
+	}
+
+	tourIdx := orderTours(games)
+
+	for _, g := range games {
+		if g.CanRematch != 1 {
+			continue
+		}
+		ago := len(tourIdx) - tourIdx[g.Tour] // 1 - последний тур
+		if ago > params.RematchTours {
+			continue
+		}
+		penalty[pairKey(g.TeamID1, g.TeamID2)] += params.RematchWeight * (params.RematchTours - ago + 1) / params.RematchTours
+	}
+
+	return penalty
+}
+
+// orderTours номер каждого тура по порядку от самого раннего. Туры идут по дате игр (самой ранней в туре).
+// У старых записей даты может не быть: такие туры считаются более ранними, чем туры с датами,
+// и между собой упорядочены по первому появлению в списке игр. Без дат порядок - просто порядок строк
+func orderTours(games []ds.Game) map[string]int {
+	var names []string
+	dates := make(map[string]string)
+	for _, g := range games {
+		d, ok := dates[g.Tour]
+		if !ok {
+			names = append(names, g.Tour)
+		}
+		if g.Date != "" && (d == "" || g.Date < d) {
+			d = g.Date
+		}
+		dates[g.Tour] = d
+	}
+
+	// даты "2006-01-02" сравниваются как строки, пустая дата меньше любой
+	sort.SliceStable(names, func(i, j int) bool {
+		return dates[names[i]] < dates[names[j]]
+	})
+
+	tourIdx := make(map[string]int, len(names))
+	for i, name := range names {
+		tourIdx[name] = i
+	}
+	return tourIdx
+}
+
+// groupDivisions связи дивизионов, которые могут играть между собой (только одного формата),
+// и группы связанных дивизионов. Номер группы - минимальный ID дивизиона в ней.
+func groupDivisions(divisions []ds.Division) (map[[2]int]bool, map[int]int) {
+	formats := make(map[int]int, len(divisions))
+	groups := make(map[int]int, len(divisions))
+	for _, d := range divisions {
+		formats[d.ID] = d.Format
+		groups[d.ID] = d.ID
+	}
+
+	var find func(id int) int
+	find = func(id int) int {
+		if groups[id] != id {
+			groups[id] = find(groups[id])
+		}
+		return groups[id]
+	}
+
+	links := make(map[[2]int]bool)
+	for _, d := range divisions {
+		for _, id := range d.PlaysWith {
+			f, ok := formats[id]
+			if !ok || id == d.ID || f != d.Format {
+				continue
+			}
+			links[pairKey(d.ID, id)] = true
+			g1, g2 := find(d.ID), find(id)
+			if g1 > g2 {
+				g1, g2 = g2, g1
+			}
+			groups[g2] = g1
+		}
+	}
+
+	for id := range groups {
+		groups[id] = find(id)
+	}
+
+	return links, groups
+}
+
+// calcRatingDiff разница в рейтинге команд, если у одной из них рейтинг не задан - 0
+func calcRatingDiff(team1, team2 *ds.Team) int {
+	if team1.Rating == 0 || team2.Rating == 0 {
+		return 0
+	}
+	return abs(team1.Rating - team2.Rating)
+}
+
+// checkMustPairs ошибка, если пару из пожелания «соперник» составить нельзя: у команд один тренер,
+// есть пожелание не играть друг с другом, дивизионы не связаны или команды уже играли без права переигровки
+func checkMustPairs(must, not map[[2]int]bool, teams map[int]*ds.Team, divMap map[int]*ds.Division, divGroups map[int]int, rematchRestricts map[int]map[int]string) error {
+	keys := make([][2]int, 0, len(must))
+	for key := range must {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1]
+	})
+
+	for _, key := range keys {
+		t1, ok1 := teams[key[0]]
+		t2, ok2 := teams[key[1]]
+		if !ok1 || !ok2 {
+			continue // одна из команд не играет в этом туре
+		}
+		reason := ""
+		switch {
+		case t1.CoachID == t2.CoachID:
+			reason = "у команд один тренер"
+		case not[key]:
+			reason = "есть и пожелание не ставить их друг с другом"
+		case divGroups[t1.DivisionID] != divGroups[t2.DivisionID]:
+			reason = fmt.Sprintf("дивизионы %s и %s не играют между собой", divMap[t1.DivisionID].Name, divMap[t2.DivisionID].Name)
+		case !canRematch(key[0], key[1], rematchRestricts):
+			reason = fmt.Sprintf("команды уже играли в туре %s, а переигровка запрещена", rematchRestricts[key[0]][key[1]])
+		default:
+			continue
+		}
+		return fmt.Errorf("пожелание «соперник» %s - %s выполнить нельзя: %s", t1.Name, t2.Name, reason)
+	}
+	return nil
+}
+
+func pairKey(teamID1, teamID2 int) [2]int {
+	if teamID1 > teamID2 {
+		return [2]int{teamID2, teamID1}
+	}
+	return [2]int{teamID1, teamID2}
+}
@@ -0,0 +1,215 @@
+package searcher
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sergrom/timetable/internal/ds"
+)
+
+func TestCalcRematchPenalty(t *testing.T) {
+	params := Params{RematchTours: 3, RematchWeight: 6}
+
+	tests := []struct {
+		name   string
+		games  []ds.Game
+		params Params
+		want   map[[2]int]int
+	}{
+		{
+			name:   "no games",
+			params: params,
+			want:   map[[2]int]int{},
+		},
+		{
+			name: "penalty decays with tours ago",
+			games: []ds.Game{
+				{Tour: "1", TeamID1: 1, TeamID2: 2, CanRematch: 1},
+				{Tour: "2", TeamID1: 3, TeamID2: 4, CanRematch: 1},
+				{Tour: "3", TeamID1: 5, TeamID2: 6, CanRematch: 1},
+			},
+			params: params,
+			want:   map[[2]int]int{{1, 2}: 2, {3, 4}: 4, {5, 6}: 6},
+		},
+		{
+			name: "older tours are forgotten",
+			games: []ds.Game{
+				{Tour: "1", TeamID1: 1, TeamID2: 2, CanRematch: 1},
+				{Tour: "2", TeamID1: 1, TeamID2: 3, CanRematch: 1},
+				{Tour: "3", TeamID1: 1, TeamID2: 4, CanRematch: 1},
+				{Tour: "4", TeamID1: 1, TeamID2: 5, CanRematch: 1},
+			},
+			params: params,
+			want:   map[[2]int]int{{1, 3}: 2, {1, 4}: 4, {1, 5}: 6},
+		},
+		{
+			name: "pair order does not matter and repeats add up",
+			games: []ds.Game{
+				{Tour: "1", TeamID1: 2, TeamID2: 1, CanRematch: 1},
+				{Tour: "2", TeamID1: 1, TeamID2: 2, CanRematch: 1},
+			},
+			params: Params{RematchTours: 2, RematchWeight: 10},
+			want:   map[[2]int]int{{1, 2}: 15},
+		},
+		{
+			name: "games without rematch are forbidden, not penalized",
+			games: []ds.Game{
+				{Tour: "1", TeamID1: 1, TeamID2: 2},
+				{Tour: "1", TeamID1: 3, TeamID2: 4, CanRematch: 1},
+			},
+			params: params,
+			want:   map[[2]int]int{{3, 4}: 6},
+		},
+		{
+			name: "tours are ordered by date, not by rows",
+			games: []ds.Game{
+				{Tour: "3", TeamID1: 5, TeamID2: 6, CanRematch: 1, Date: "2026-05-15"},
+				{Tour: "1", TeamID1: 1, TeamID2: 2, CanRematch: 1, Date: "2026-05-01"},
+				{Tour: "2", TeamID1: 3, TeamID2: 4, CanRematch: 1, Date: "2026-05-08"},
+			},
+			params: params,
+			want:   map[[2]int]int{{1, 2}: 2, {3, 4}: 4, {5, 6}: 6},
+		},
+		{
+			name: "tours without dates are older, in row order",
+			games: []ds.Game{
+				{Tour: "3", TeamID1: 5, TeamID2: 6, CanRematch: 1, Date: "2026-05-15"},
+				{Tour: "1", TeamID1: 1, TeamID2: 2, CanRematch: 1},
+				{Tour: "2", TeamID1: 3, TeamID2: 4, CanRematch: 1},
+			},
+			params: params,
+			want:   map[[2]int]int{{1, 2}: 2, {3, 4}: 4, {5, 6}: 6},
+		},
+		{
+			name: "tour date is its earliest game",
+			games: []ds.Game{
+				{Tour: "2", TeamID1: 3, TeamID2: 4, CanRematch: 1, Date: "2026-05-08"},
+				{Tour: "1", TeamID1: 1, TeamID2: 2, CanRematch: 1, Date: "2026-05-09"},
+				{Tour: "1", TeamID1: 5, TeamID2: 6, CanRematch: 1, Date: "2026-05-01"},
+			},
+			params: Params{RematchTours: 2, RematchWeight: 10},
+			want:   map[[2]int]int{{1, 2}: 5, {5, 6}: 5, {3, 4}: 10},
+		},
+		{
+			name:   "switched off",
+			games:  []ds.Game{{Tour: "1", TeamID1: 1, TeamID2: 2, CanRematch: 1}},
+			params: Params{RematchTours: 0, RematchWeight: 6},
+			want:   map[[2]int]int{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := calcRematchPenalty(tc.games, tc.params)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("penalty %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGroupDivisions(t *testing.T) {
+	tests := []struct {
+		name       string
+		divisions  []ds.Division
+		wantLinks  map[[2]int]bool
+		wantGroups map[int]int
+	}{
+		{
+			name:       "no links",
+			divisions:  []ds.Division{{ID: 1, Format: 6}, {ID: 2, Format: 6}},
+			wantLinks:  map[[2]int]bool{},
+			wantGroups: map[int]int{1: 1, 2: 2},
+		},
+		{
+			name:       "link from one side is enough",
+			divisions:  []ds.Division{{ID: 1, Format: 6}, {ID: 2, Format: 6, PlaysWith: []int{1}}},
+			wantLinks:  map[[2]int]bool{{1, 2}: true},
+			wantGroups: map[int]int{1: 1, 2: 1},
+		},
+		{
+			name: "chain makes one group, links stay direct",
+			divisions: []ds.Division{
+				{ID: 3, Format: 6, PlaysWith: []int{2}},
+				{ID: 2, Format: 6, PlaysWith: []int{1}},
+				{ID: 1, Format: 6},
+				{ID: 4, Format: 6},
+			},
+			wantLinks:  map[[2]int]bool{{2, 3}: true, {1, 2}: true},
+			wantGroups: map[int]int{1: 1, 2: 1, 3: 1, 4: 4},
+		},
+		{
+			name:       "different formats, self and unknown divisions are ignored",
+			divisions:  []ds.Division{{ID: 1, Format: 6, PlaysWith: []int{2, 1, 9}}, {ID: 2, Format: 8}},
+			wantLinks:  map[[2]int]bool{},
+			wantGroups: map[int]int{1: 1, 2: 2},
+		},
+		{
+			name: "two groups merged by a later link",
+			divisions: []ds.Division{
+				{ID: 1, Format: 6, PlaysWith: []int{2}},
+				{ID: 3, Format: 6, PlaysWith: []int{4}},
+				{ID: 4, Format: 6, PlaysWith: []int{2}},
+				{ID: 2, Format: 6},
+				{ID: 5, Format: 6},
+			},
+			wantLinks:  map[[2]int]bool{{1, 2}: true, {3, 4}: true, {2, 4}: true},
+			wantGroups: map[int]int{1: 1, 2: 1, 3: 1, 4: 1, 5: 5},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			links, groups := groupDivisions(tc.divisions)
+			if !reflect.DeepEqual(links, tc.wantLinks) {
+				t.Errorf("links %v, want %v", links, tc.wantLinks)
+			}
+			if !reflect.DeepEqual(groups, tc.wantGroups) {
+				t.Errorf("groups %v, want %v", groups, tc.wantGroups)
+			}
+		})
+	}
+}
+
+func TestCheckMustPairs(t *testing.T) {
+	teams := map[int]*ds.Team{
+		1: {ID: 1, Name: "А", CoachID: 1, DivisionID: 1},
+		2: {ID: 2, Name: "Б", CoachID: 1, DivisionID: 1},
+		3: {ID: 3, Name: "В", CoachID: 2, DivisionID: 1},
+		4: {ID: 4, Name: "Г", CoachID: 3, DivisionID: 2},
+	}
+	divMap := map[int]*ds.Division{1: {ID: 1, Name: "2012"}, 2: {ID: 2, Name: "2014"}}
+	divGroups := map[int]int{1: 1, 2: 2}
+	rematchRestricts := map[int]map[int]string{1: {3: "Тур 2"}}
+
+	tests := []struct {
+		name    string
+		must    [][2]int
+		not     [][2]int
+		wantErr bool
+	}{
+		{name: "no wishes"},
+		{name: "possible pair", must: [][2]int{{2, 3}}},
+		{name: "played and rematch is banned", must: [][2]int{{1, 3}}, wantErr: true},
+		{name: "team not in tour", must: [][2]int{{1, 9}}},
+		{name: "same coach", must: [][2]int{{1, 2}}, wantErr: true},
+		{name: "wished and not wished", must: [][2]int{{2, 3}}, not: [][2]int{{2, 3}}, wantErr: true},
+		{name: "divisions not linked", must: [][2]int{{3, 4}}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			must, not := make(map[[2]int]bool), make(map[[2]int]bool)
+			for _, p := range tc.must {
+				must[pairKey(p[0], p[1])] = true
+			}
+			for _, p := range tc.not {
+				not[pairKey(p[0], p[1])] = true
+			}
+			err := checkMustPairs(must, not, teams, divMap, divGroups, rematchRestricts)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("error %v, want error %v", err, tc.wantErr)
+			}
+		})
+	}
+}
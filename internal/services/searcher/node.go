@@ -30,6 +30,7 @@ type node struct {
 	parent   *node   // предыдущая нода
 	next     []*node // следующие ноды
 	nextIdx  int
+	done     bool // у первой ноды перебраны все ветки
 	depth    int
 	priority int
 
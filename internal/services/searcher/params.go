@@ -0,0 +1,61 @@
+package searcher
+
+import "time"
+
+const (
+	DefaultRematchTours   = 3
+	DefaultRematchWeight  = 6
+	DefaultRatingWeight   = 2
+	DefaultCrossDivWeight = 10
+
+	GamesPerTeam = 2 // игр у команды за тур, если пары тура не заданы
+)
+
+// Params настройки поиска, которые не являются исходными данными тура
+type Params struct {
+	RematchTours   int           // сколько последних туров учитывать при штрафе за повторную встречу
+	RematchWeight  int           // штраф за встречу, сыгранную в самом последнем туре
+	RatingWeight   int           // штраф за каждые 100 пунктов разницы в рейтинге команд
+	RatingMaxDiff  int           // максимальная разница в рейтинге соперников, 0 - без ограничения
+	CrossDivWeight int           // штраф за игру команд разных дивизионов
+	TravelDur      time.Duration // переезд между стадионами для команд, тренеров и судей
+}
+
+// DefaultParams ...
+func DefaultParams() Params {
+	return Params{
+		RematchTours:   DefaultRematchTours,
+		RematchWeight:  DefaultRematchWeight,
+		RatingWeight:   DefaultRatingWeight,
+		CrossDivWeight: DefaultCrossDivWeight,
+	}
+}
+
+// Settings настройки работы поиска, общие для всех запусков сервера
+type Settings struct {
+	MaxMemoryMB    uint64        `yaml:"max_memory_mb"`   // поиск останавливается, если занял больше памяти
+	RotateInterval time.Duration `yaml:"rotate_interval"` // как часто переходить к следующей первой ноде
+	NodeLimits     []NodeLimit   `yaml:"node_limits"`     // сколько лучших вариантов продолжать на глубине
+}
+
+// NodeLimit на глубине от MinDepth, если вариантов не меньше MinNodes, продолжаются только Keep лучших
+type NodeLimit struct {
+	MinDepth int `yaml:"min_depth"`
+	MinNodes int `yaml:"min_nodes"`
+	Keep     int `yaml:"keep"`
+}
+
+// DefaultSettings ...
+func DefaultSettings() Settings {
+	return Settings{
+		MaxMemoryMB:    14 * 1024,
+		RotateInterval: 5 * time.Second,
+		NodeLimits: []NodeLimit{
+			{MinDepth: 31, MinNodes: 30, Keep: 2},
+			{MinDepth: 21, MinNodes: 20, Keep: 3},
+			{MinDepth: 11, MinNodes: 10, Keep: 4},
+			{MinDepth: 6, MinNodes: 5, Keep: 5},
+			{MinDepth: 0, MinNodes: 10, Keep: 10},
+		},
+	}
+}
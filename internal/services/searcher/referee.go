@@ -0,0 +1,153 @@
+package searcher
+
+import (
+	"sort"
+	"time"
+
+	"github.com/sergrom/timetable/internal/pkg"
+)
+
+// assignReferees назначить судей на игры решения так, чтобы у судьи не было наложений,
+// а простои между его играми были минимальны. Жадно: игры по времени начала, на игру
+// ставится уже работающий судья с наименьшим простоем, иначе - свободный.
+// Лимит игр и простои считаются за день: в новый день тура судья начинает заново.
+// Возвращает суммарный простой судей в минутах и false, если какую-то игру судить некому.
+func (c *Condition) assignReferees(games map[string][]SolutioGame) (int, bool) {
+	fields := make([]string, 0, len(games))
+	for fld := range games {
+		fields = append(fields, fld)
+	}
+	sort.Strings(fields)
+
+	all := make([]*SolutioGame, 0, len(c.Teams))
+	stadiums := make(map[*SolutioGame]int, len(c.Teams))
+	for _, fld := range fields {
+		for i := range games[fld] {
+			all = append(all, &games[fld][i])
+			stadiums[&games[fld][i]] = c.nodeStadiums[fld]
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Start.Before(all[j].Start)
+	})
+
+	lastEnd := make([]time.Time, len(c.Referees))
+	lastStadium := make([]int, len(c.Referees))
+	gamesCnt := make([]int, len(c.Referees)) // игр судьи в день его последней игры
+	idle := time.Duration(0)
+
+	for _, g := range all {
+		format := c.teamFormats[g.TeamID1]
+		best, bestGap := -1, time.Duration(0)
+		for i := range c.Referees {
+			ref := &c.Referees[i]
+			if gamesCnt[i] > 0 && !sameDate(lastEnd[i], g.Start) {
+				gamesCnt[i] = 0 // игры идут по времени, прошлый день судьи закончен
+			}
+			if !ref.CanJudge(format) || !ref.IsAvailable(pkg.Clock(g.Start), pkg.Clock(g.End)) {
+				continue
+			}
+			if ref.MaxGames > 0 && gamesCnt[i] >= ref.MaxGames {
+				continue
+			}
+			if gamesCnt[i] == 0 {
+				if best == -1 {
+					best, bestGap = i, -1
+				}
+				continue
+			}
+			if lastEnd[i].After(g.Start) {
+				continue // судья еще на другой игре
+			}
+			gap := g.Start.Sub(lastEnd[i])
+			if lastStadium[i] != stadiums[g] && gap < c.Params.TravelDur {
+				continue // судья не успевает переехать
+			}
+			if best == -1 || bestGap == -1 || gap < bestGap {
+				best, bestGap = i, gap
+			}
+		}
+		if best == -1 {
+			return 0, false
+		}
+
+		if bestGap > 0 {
+			idle += bestGap
+		}
+		g.RefereeID = c.Referees[best].ID
+		lastEnd[best] = g.End
+		lastStadium[best] = stadiums[g]
+		gamesCnt[best]++
+	}
+
+	return int(idle.Minutes()), true
+}
+
+// refGame игра ветки поиска, которой нужен судья
+type refGame struct {
+	from, to time.Time
+	format   int
+}
+
+// refereesFit хватит ли судей, если к играм ветки добавить игру g: в любой момент
+// одновременно идущие игры должны разойтись по разным подходящим судьям.
+// Переезды и лимит игр за день здесь не учитываются - их проверяет assignReferees
+func (c *Condition) refereesFit(branch []refGame, g refGame) bool {
+	if len(c.Referees) == 0 {
+		return true
+	}
+
+	// больше всего игр вместе с g идет в ее начало или в начало одной из игр внутри нее
+	moments := []time.Time{g.from}
+	for _, b := range branch {
+		if b.from.After(g.from) && b.from.Before(g.to) {
+			moments = append(moments, b.from)
+		}
+	}
+	for _, t := range moments {
+		games := []refGame{g}
+		for _, b := range branch {
+			if !b.from.After(t) && b.to.After(t) {
+				games = append(games, b)
+			}
+		}
+		if !c.matchReferees(games) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchReferees можно ли поставить на каждую игру своего судью (паросочетание игр и судей)
+func (c *Condition) matchReferees(games []refGame) bool {
+	if len(games) > len(c.Referees) {
+		return false
+	}
+	match := make([]int, len(c.Referees)) // игра, на которую поставлен судья, -1 - свободен
+	for i := range match {
+		match[i] = -1
+	}
+	for gi := range games {
+		if !c.augment(games, gi, match, make([]bool, len(c.Referees))) {
+			return false
+		}
+	}
+	return true
+}
+
+// augment найти судью для игры gi, пересаживая уже поставленных судей
+func (c *Condition) augment(games []refGame, gi int, match []int, seen []bool) bool {
+	g := games[gi]
+	for ri := range c.Referees {
+		ref := &c.Referees[ri]
+		if seen[ri] || !ref.CanJudge(g.format) || !ref.IsAvailable(pkg.Clock(g.from), pkg.Clock(g.to)) {
+			continue
+		}
+		seen[ri] = true
+		if match[ri] == -1 || c.augment(games, match[ri], match, seen) {
+			match[ri] = gi
+			return true
+		}
+	}
+	return false
+}
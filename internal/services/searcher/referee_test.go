@@ -0,0 +1,229 @@
+package searcher
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/pkg"
+)
+
+// clock время hh:mm сегодняшнего дня, как его разбирают справочники
+func clock(t *testing.T, hm string) time.Time {
+	t.Helper()
+	v, err := pkg.ParseHM(hm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestAssignReferees(t *testing.T) {
+	const dur = 50 * time.Minute
+	c := &Condition{
+		Params:       Params{TravelDur: 30 * time.Minute},
+		teamFormats:  map[int]int{1: 6, 2: 6, 3: 6, 4: 6, 5: 8, 6: 8},
+		nodeStadiums: map[string]int{"поле1": 1, "поле2": 1, "поле3": 2},
+	}
+	game := func(id1, id2 int, from string) SolutioGame {
+		start := clock(t, from)
+		return SolutioGame{TeamID1: id1, TeamID2: id2, Start: start, End: start.Add(dur)}
+	}
+	// nextDay та же игра на следующий день тура
+	nextDay := func(g SolutioGame) SolutioGame {
+		g.Start, g.End = g.Start.AddDate(0, 0, 1), g.End.AddDate(0, 0, 1)
+		return g
+	}
+
+	tests := []struct {
+		name     string
+		referees []ds.Referee
+		games    map[string][]SolutioGame
+		wantOk   bool
+		wantIdle int
+		wantRefs map[string][]int // судьи игр по полям
+	}{
+		{
+			name:     "one referee, games one after another",
+			referees: []ds.Referee{{ID: 7}},
+			games:    map[string][]SolutioGame{"поле1": {game(1, 2, "10:00"), game(3, 4, "11:00")}},
+			wantOk:   true,
+			wantIdle: 10,
+			wantRefs: map[string][]int{"поле1": {7, 7}},
+		},
+		{
+			name:     "concurrent games need two referees",
+			referees: []ds.Referee{{ID: 7}},
+			games:    map[string][]SolutioGame{"поле1": {game(1, 2, "10:00")}, "поле2": {game(3, 4, "10:30")}},
+		},
+		{
+			name:     "working referee is kept, free one waits",
+			referees: []ds.Referee{{ID: 7}, {ID: 8}},
+			games:    map[string][]SolutioGame{"поле1": {game(1, 2, "10:00"), game(3, 4, "11:00")}},
+			wantOk:   true,
+			wantIdle: 10,
+			wantRefs: map[string][]int{"поле1": {7, 7}},
+		},
+		{
+			name:     "working referee goes to another field before a free one",
+			referees: []ds.Referee{{ID: 7}, {ID: 8}},
+			games: map[string][]SolutioGame{
+				"поле1": {game(1, 2, "09:00")},
+				"поле2": {game(3, 4, "10:00"), game(5, 6, "11:00")},
+			},
+			wantOk:   true,
+			wantIdle: 20,
+			wantRefs: map[string][]int{"поле1": {7}, "поле2": {7, 7}},
+		},
+		{
+			name:     "format the referee can't judge",
+			referees: []ds.Referee{{ID: 7, Formats: []int{6}}},
+			games:    map[string][]SolutioGame{"поле1": {game(5, 6, "10:00")}},
+		},
+		{
+			name:     "referee per format",
+			referees: []ds.Referee{{ID: 7, Formats: []int{6}}, {ID: 8, Formats: []int{8}}},
+			games:    map[string][]SolutioGame{"поле1": {game(5, 6, "10:00")}, "поле2": {game(1, 2, "10:00")}},
+			wantOk:   true,
+			wantRefs: map[string][]int{"поле1": {8}, "поле2": {7}},
+		},
+		{
+			name:     "referee comes later",
+			referees: []ds.Referee{{ID: 7, TimeFrom: clock(t, "12:00")}},
+			games:    map[string][]SolutioGame{"поле1": {game(1, 2, "10:00")}},
+		},
+		{
+			name:     "referee leaves before the game ends",
+			referees: []ds.Referee{{ID: 7, TimeTo: clock(t, "10:30")}},
+			games:    map[string][]SolutioGame{"поле1": {game(1, 2, "10:00")}},
+		},
+		{
+			name:     "referee can't make it to another stadium",
+			referees: []ds.Referee{{ID: 7}},
+			games:    map[string][]SolutioGame{"поле1": {game(1, 2, "10:00")}, "поле3": {game(3, 4, "11:00")}},
+		},
+		{
+			name:     "enough time to travel",
+			referees: []ds.Referee{{ID: 7}},
+			games:    map[string][]SolutioGame{"поле1": {game(1, 2, "10:00")}, "поле3": {game(3, 4, "11:30")}},
+			wantOk:   true,
+			wantIdle: 40,
+			wantRefs: map[string][]int{"поле1": {7}, "поле3": {7}},
+		},
+		{
+			name:     "games limit is per day",
+			referees: []ds.Referee{{ID: 7, MaxGames: 1}},
+			games:    map[string][]SolutioGame{"поле1": {game(1, 2, "10:00")}, "поле2": {nextDay(game(3, 4, "09:00"))}},
+			wantOk:   true,
+			wantRefs: map[string][]int{"поле1": {7}, "поле2": {7}},
+		},
+		{
+			name:     "night between tour days is not idle",
+			referees: []ds.Referee{{ID: 7}},
+			games:    map[string][]SolutioGame{"поле1": {game(1, 2, "10:00"), nextDay(game(3, 4, "11:00"))}},
+			wantOk:   true,
+			wantRefs: map[string][]int{"поле1": {7, 7}},
+		},
+		{
+			name:     "games limit",
+			referees: []ds.Referee{{ID: 7, MaxGames: 1}},
+			games:    map[string][]SolutioGame{"поле1": {game(1, 2, "10:00"), game(3, 4, "11:00")}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c.Referees = tc.referees
+			idle, ok := c.assignReferees(tc.games)
+			if ok != tc.wantOk {
+				t.Fatalf("ok = %v, want %v", ok, tc.wantOk)
+			}
+			if !ok {
+				return
+			}
+			if idle != tc.wantIdle {
+				t.Errorf("idle = %d, want %d", idle, tc.wantIdle)
+			}
+			for fld, refs := range tc.wantRefs {
+				for i, id := range refs {
+					if got := tc.games[fld][i].RefereeID; got != id {
+						t.Errorf("%s, game %d: referee %d, want %d", fld, i, got, id)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRefereesFit(t *testing.T) {
+	// игры 50 минут, day - день тура, время - сегодняшнее, как у судей из справочника
+	game := func(day int, hm string, format int) refGame {
+		from := clock(t, hm).AddDate(0, 0, day)
+		return refGame{from: from, to: from.Add(50 * time.Minute), format: format}
+	}
+	two := []ds.Referee{{ID: 1}, {ID: 2}}
+
+	tests := []struct {
+		name     string
+		referees []ds.Referee
+		branch   []refGame
+		cand     refGame
+		want     bool
+	}{
+		{name: "no referees - no limit", cand: game(0, "10:00", 6), want: true},
+		{name: "free referee", referees: two, branch: []refGame{game(0, "10:00", 6)}, cand: game(0, "10:00", 6), want: true},
+		{
+			name:     "all referees busy",
+			referees: two,
+			branch:   []refGame{game(0, "10:00", 6), game(0, "10:00", 6)},
+			cand:     game(0, "10:00", 6),
+		},
+		{
+			name:     "game starting inside the candidate counts",
+			referees: two,
+			branch:   []refGame{game(0, "09:40", 6), game(0, "10:20", 6)},
+			cand:     game(0, "10:00", 6),
+		},
+		{
+			name:     "finished games don't count",
+			referees: two,
+			branch:   []refGame{game(0, "09:00", 6), game(0, "09:00", 6)},
+			cand:     game(0, "10:00", 6),
+			want:     true,
+		},
+		{
+			name:     "same time on another day",
+			referees: two,
+			branch:   []refGame{game(0, "10:00", 6), game(0, "10:00", 6)},
+			cand:     game(1, "10:00", 6),
+			want:     true,
+		},
+		{
+			name:     "referees swap to fit formats",
+			referees: []ds.Referee{{ID: 1}, {ID: 2, Formats: []int{6}}},
+			branch:   []refGame{game(0, "10:00", 6)},
+			cand:     game(0, "10:00", 8),
+			want:     true,
+		},
+		{
+			name:     "nobody judges the format",
+			referees: []ds.Referee{{ID: 1, Formats: []int{6}}, {ID: 2, Formats: []int{6}}},
+			cand:     game(0, "10:00", 8),
+		},
+		{
+			name:     "the only free referee has left",
+			referees: []ds.Referee{{ID: 1}, {ID: 2, TimeTo: clock(t, "10:30")}},
+			branch:   []refGame{game(0, "10:00", 6)},
+			cand:     game(0, "10:00", 6),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &Condition{Referees: tc.referees}
+			if got := c.refereesFit(tc.branch, tc.cand); got != tc.want {
+				t.Errorf("fit = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
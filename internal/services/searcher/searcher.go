@@ -17,6 +17,13 @@ const (
 	StatusStopped   = 3
 )
 
+const (
+	StopReasonUser     = "остановлен пользователем"
+	StopReasonFinished = "перебор завершен"
+	StopReasonMemory   = "превышен лимит памяти"
+	StopReasonShutdown = "остановлен при выключении сервера"
+)
+
 var (
 	SearchCounter int = 1
 )
@@ -30,25 +37,43 @@ type Searcher struct {
 	nextNode    *node
 	bestDepth		int
 
-	solutions []Solution
-	attempts  int
-	solHashes map[string]struct{}
-	stop      chan struct{}
-	now       time.Time
+	solutions   []Solution
+	attempts    int
+	refRejected int // решений, отброшенных из-за того, что судей не хватило
+	solHashes   map[string]struct{}
+	stop        chan struct{}
+	done        chan struct{} // закрывается, когда поиск завершился
+	reason      string        // почему поиск завершился
+	now         time.Time
+	settings    Settings
+
+	cpReq      chan chan *Checkpoint // запросы точки продолжения к горутине поиска
+	checkpoint *Checkpoint           // точка продолжения, снятая при остановке
+
+	searchID int         // номер текущего поиска, SearchCounter на момент запуска
+	result   chan Result // итог текущего поиска, отправляется один раз перед закрытием done
 }
 
-type tInterval struct {
-	from, to time.Time
+// Result итог поиска, снятый горутиной поиска при его завершении
+type Result struct {
+	SearchID   int
+	Solutions  []Solution // по возрастанию штрафа
+	Attempts   int
+	Reason     string
+	Checkpoint *Checkpoint // nil, если перебор завершен
 }
 
-func NewSearcher() *Searcher {
+func NewSearcher(settings Settings) *Searcher {
 	return &Searcher{
+		settings:  settings,
 		status:    StatusInit,
 		tree:      nil,
 		solutions: make([]Solution, 0, 2000),
 		solHashes: make(map[string]struct{}),
 		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
 		now:       time.Now(),
+		cpReq:     make(chan chan *Checkpoint),
 	}
 }
 
@@ -59,46 +84,94 @@ func (s *Searcher) Mem() uint64 {
 }
 
 func (s *Searcher) Stop() {
-	s.lock.Lock()
-	defer s.lock.Unlock()
+	s.stopWith(StopReasonUser)
+}
+
+// Shutdown остановить поиск перед выключением сервера, точка продолжения сохраняется
+func (s *Searcher) Shutdown() {
+	s.stopWith(StopReasonShutdown)
+}
 
-	if s.status == StatusStopped || s.status == StatusInit {
+// stopWith остановить поиск и дождаться, пока горутина поиска снимет точку продолжения
+func (s *Searcher) stopWith(reason string) {
+	s.lock.Lock()
+	if s.status != StatusInProcess {
+		s.lock.Unlock()
 		return
 	}
 
-	close(s.stop)
-	time.Sleep(time.Second)
-	s.status = StatusStopped
-	s.tree = nil
-	runtime.GC()
+	s.reason = reason
+	s.closeStop()
+	done := s.done
+	s.lock.Unlock()
+
+	<-done
+}
+
+// closeStop закрыть канал stop, если он еще открыт, вызывается под блокировкой
+func (s *Searcher) closeStop() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
 }
 
 func (s *Searcher) Search(cond *Condition) ([]Solution, int, error) {
+	return s.Resume(cond, nil, nil)
+}
+
+// Resume продолжить поиск с точки продолжения cp с уже найденными решениями sols.
+// Без точки продолжения поиск начинается сначала
+func (s *Searcher) Resume(cond *Condition, cp *Checkpoint, sols []Solution) ([]Solution, int, error) {
 	s.lock.Lock()
 	if s.status == StatusInProcess {
+		s.lock.Unlock()
 		return []Solution{}, 0, errors.New("Searcher::Search() error: searcher must be 'init' status")
 	}
 
 	SearchCounter++
 	s.stop = make(chan struct{})
+	s.done = make(chan struct{})
+	s.result = make(chan Result, 1)
+	s.searchID = SearchCounter
+	s.reason = ""
 	s.status = StatusInProcess
 	s.Condition = cond
 	s.solutions = make([]Solution, 0, 2000)
 	s.attempts = 0
+	s.refRejected = 0
+	s.checkpoint = nil
 	if s.tree != nil {
 		s.tree.empty()
 	}
 	s.tree = newSearchTree()
 	s.nextNode = nil
+	s.nextNodeIdx = 0
 	runtime.GC()
 
+	// хеш решения начинается с номера поиска, переносим найденные решения в текущий поиск
+	for _, sl := range sols {
+		sl.HashStr = fmt.Sprintf("%d_%s", SearchCounter, sl.Hash())
+		if _, ok := s.solHashes[sl.HashStr]; !ok {
+			s.solHashes[sl.HashStr] = struct{}{}
+			s.solutions = append(s.solutions, sl)
+		}
+	}
+
 	firstNodes, err := s.genFirstNodes()
 	if err != nil {
+		s.status = StatusStopped
+		close(s.done)
+		s.lock.Unlock()
 		return nil, 0, err
 	}
 	s.tree.setFirstNodes(firstNodes)
 
 	if len(s.tree.firstNodes) == 0 {
+		s.status = StatusStopped
+		close(s.done)
+		s.lock.Unlock()
 		return []Solution{}, 0, errors.New("couldn't generate first nodes")
 	}
 
@@ -106,19 +179,46 @@ func (s *Searcher) Search(cond *Condition) ([]Solution, int, error) {
 	s.nextNode = s.tree.firstNodes[0]
 	s.bestDepth = 0
 
-	go s.rotateNextNode()
+	if cp != nil {
+		if err := s.restoreCheckpoint(cp); err != nil {
+			s.status = StatusStopped
+			close(s.done)
+			s.lock.Unlock()
+			return nil, 0, err
+		}
+	}
+
+	go s.rotateNextNode(s.stop)
 
 	s.lock.Unlock()
 
+	done, result := s.done, s.result
 	go func() {
 		defer func() {
 			s.lock.Lock()
 			s.status = StatusStopped
+			if s.reason != StopReasonFinished {
+				s.checkpoint = s.takeCheckpoint()
+			}
+			s.closeStop()
 			if s.tree != nil {
 				s.tree.empty()
 			}
+			// итог снимается здесь: после close(done) может начаться следующий поиск
+			res := Result{
+				SearchID:   s.searchID,
+				Solutions:  make([]Solution, len(s.solutions)),
+				Attempts:   s.attempts,
+				Reason:     s.reason,
+				Checkpoint: s.checkpoint,
+			}
+			copy(res.Solutions, s.solutions)
 			runtime.GC()
 			s.lock.Unlock()
+
+			sortSolutions(res.Solutions)
+			result <- res
+			close(done)
 		}()
 
 		isSingleCPU := runtime.NumCPU() == 1
@@ -126,15 +226,21 @@ func (s *Searcher) Search(cond *Condition) ([]Solution, int, error) {
 			select {
 			case <-s.stop:
 				return
+			case reply := <-s.cpReq:
+				s.lock.RLock()
+				reply <- s.takeCheckpoint()
+				s.lock.RUnlock()
 			default:
-				if s.Mem() > 14*1024*1024*1024 {
+				if s.Mem() > s.settings.MaxMemoryMB*1024*1024 {
 					fmt.Println("break on max memory exceeded")
+					s.setReason(StopReasonMemory)
 					return
 				}
 
 				n := s.getNextNode()
 				if n == nil {
 					fmt.Println("search finished")
+					s.setReason(StopReasonFinished)
 					return
 				}
 
@@ -155,10 +261,10 @@ func (s *Searcher) Search(cond *Condition) ([]Solution, int, error) {
 }
 
 func (s *Searcher) drillNode(theNode *node) {
-	teamsCnt := len(s.Condition.Teams)
+	gamesCnt := s.Condition.gamesCnt
 
 	curNode := theNode
-	for curNode.depth <= teamsCnt {
+	for curNode.depth <= gamesCnt {
 		if curNode.next == nil {
 			curNode.next = s.genNodes(curNode)
 		}
@@ -168,7 +274,7 @@ func (s *Searcher) drillNode(theNode *node) {
 		curNode = curNode.next[curNode.nextIdx]
 	}
 
-	if curNode.depth >= teamsCnt {
+	if curNode.depth >= gamesCnt {
 		s.addSolution(curNode)
 	} else if curNode.depth > s.bestDepth {
 		s.lock.Lock()
@@ -181,6 +287,10 @@ func (s *Searcher) drillNode(theNode *node) {
 	s.attempts++
 	s.lock.Unlock()
 
+	if curNode.parent == nil {
+		// первая нода сама решение или тупик, продолжений у нее нет
+		curNode.done = true
+	}
 	curNode = curNode.parent
 	for curNode != nil {
 		curNode.next[curNode.nextIdx] = nil
@@ -188,6 +298,9 @@ func (s *Searcher) drillNode(theNode *node) {
 		if curNode.nextIdx < len(curNode.next) {
 			break
 		}
+		if curNode.parent == nil {
+			curNode.done = true
+		}
 		curNode = curNode.parent
 	}
 }
@@ -195,25 +308,7 @@ func (s *Searcher) drillNode(theNode *node) {
 // genFirstNodes генерировать первые ноды
 func (s *Searcher) genFirstNodes() ([]*node, error) {
 	// для каждой команды, для каждого поля содержит слоты, на которых возможна игра
-	places := make(map[int]map[*ds.FieldNode][]int, len(s.Condition.Teams))
-	for tID, team := range s.Condition.teamsByIDs {
-		div := s.Condition.divMap[team.DivisionID]
-		slots := s.Condition.teamSlots[tID]
-		places[tID] = make(map[*ds.FieldNode][]int, len(slots))
-		for _, fNode := range s.Condition.fieldNodes {
-			if div.Format < 7 && fNode.Format() == 7 {
-				continue
-			}
-			if div.Format == 7 && fNode.Format() != 7 {
-				continue
-			}
-			for _, slot := range slots {
-				if fNode.IsSlotOk(slot) {
-					places[tID][fNode] = append(places[tID][fNode], slot)
-				}
-			}
-		}
-	}
+	places := s.Condition.teamNodeSlots
 
 	// choose the most specific team
 	var theTeam *ds.Team
@@ -221,10 +316,13 @@ func (s *Searcher) genFirstNodes() ([]*node, error) {
 
 	curCnt := 0
 	for tID, fieldSlots := range places {
+		if s.Condition.gamesNeed[tID] == 0 {
+			continue // команде в этом туре играть не с кем
+		}
 		slotsCnt := 0
 		fnKeys := make(map[string]bool, len(s.Condition.fieldNodes))
 		for fNode, slots := range fieldSlots {
-			key := fNodeKey(fNode)
+			key := s.Condition.fNodeKey(fNode)
 			if _, ok := fnKeys[key]; !ok {
 				fnKeys[key] = true
 				slotsCnt += len(slots)
@@ -235,23 +333,19 @@ func (s *Searcher) genFirstNodes() ([]*node, error) {
 			div := s.Condition.divMap[team.DivisionID]
 			return nil, fmt.Errorf("Невозможно разместить команду %s %s", team.Name, div.Name)
 		}
-		if theTeam == nil || curCnt > slotsCnt {
+		// при равенстве берем команду с меньшим ID, чтобы первые ноды не зависели от обхода карты
+		if theTeam == nil || curCnt > slotsCnt || (curCnt == slotsCnt && tID < theTeam.ID) {
 			theTeam, curCnt = s.Condition.teamsByIDs[tID], slotsCnt
 		}
 	}
 
 	// Собираем все возможные пары с этой командой
-	teamPairs := make([]*ds.TeamPair, 0, len(s.Condition.teamsByIDs))
-	for _, pair := range s.Condition.teamPairsMap[theTeam.DivisionID] {
-		if theTeam.ID == pair.Team1.ID || theTeam.ID == pair.Team2.ID {
-			teamPairs = append(teamPairs, pair)
-		}
-	}
+	teamPairs := s.Condition.teamPairsByTeamMap[theTeam.ID]
 
 	fnKeys := make(map[string]bool, len(s.Condition.fieldNodes))
 	nodesLen := 0
 	for _, fNode := range s.Condition.fieldNodes {
-		key := fNodeKey(fNode)
+		key := s.Condition.fNodeKey(fNode)
 		if _, ok := fnKeys[key]; ok {
 			continue
 		}
@@ -271,12 +365,19 @@ func (s *Searcher) genFirstNodes() ([]*node, error) {
 		}
 	}
 
-	fieldStart, gameDur := s.Condition.Fields[0].TimeFrom, s.Condition.Fields[0].GameDur
+	// обходим поля и пары по порядку, а не по картам: точка продолжения поиска ссылается на порядок нод
 	firstNodes := make([]*node, 0, nodesLen)
-	for fNode, pairSlots := range theNodeTeamPairSlots {
-		for pair, slots := range pairSlots {
-			for _, slot := range slots {
-				from, to := GetFromTo(fieldStart, gameDur, slot)
+	for _, fNode := range s.Condition.fieldNodes {
+		pairSlots, ok := theNodeTeamPairSlots[fNode]
+		if !ok {
+			continue
+		}
+		for _, pair := range teamPairs {
+			for _, slot := range pairSlots[pair] {
+				from, to := fNode.GetFromTo(slot)
+				if !s.Condition.refereesFit(nil, refGame{from: from, to: to, format: s.Condition.teamFormats[pair.Team1.ID]}) {
+					continue // игру некому судить
+				}
 				firstNodes = append(firstNodes, &node{
 					field:    fNode,
 					teamPair: pair,
@@ -290,7 +391,7 @@ func (s *Searcher) genFirstNodes() ([]*node, error) {
 		}
 	}
 
-	sort.Slice(firstNodes, func(i, j int) bool {
+	sort.SliceStable(firstNodes, func(i, j int) bool {
 		return firstNodes[i].timeFrom.Before(firstNodes[j].timeFrom)
 	})
 
@@ -300,96 +401,45 @@ func (s *Searcher) genFirstNodes() ([]*node, error) {
 	return firstNodes, nil
 }
 
-func resolveTeamSlots(teamSlots []int, teamPrevSlots []int, coachPrevSlots []int, coachTeamsCnt int) []int {
-	if len(teamPrevSlots) > 0 {
-		slot := teamPrevSlots[0]
-		teamSlots = IntersectSlots(teamSlots, []int{slot - 2, slot - 1, slot + 1, slot + 2})
-	}
-
-	teamSlotsMap := make(map[int]bool, len(teamSlots)+4)
-	for _, slot := range teamSlots {
-		if slot >= 0 {
-			teamSlotsMap[slot] = true
-		}
-	}
-
-	if len(coachPrevSlots) > 0 {
-		coachSlotsCnt := coachTeamsCnt * 3
-		slotMin, slotMax := 100000, -100000
-		for _, slot := range coachPrevSlots {
-			if slot < slotMin {
-				slotMin = slot
-			}
-			if slot > slotMax {
-				slotMax = slot
-			}
-		}
-		diff := coachSlotsCnt - (slotMax - slotMin)
-		if diff < 0 {
-			return []int{}
-		}
-
-		for slot := range teamSlotsMap {
-			if slot <= slotMin-diff || slot >= slotMax+diff {
-				delete(teamSlotsMap, slot)
-			}
-		}
-		for _, slot := range coachPrevSlots {
-			delete(teamSlotsMap, slot)
-		}
-	}
-
-	out := make([]int, 0, len(teamSlotsMap))
-	for slot := range teamSlotsMap {
-		out = append(out, slot)
-	}
-
-	sort.Ints(out)
-
-	return out
-}
-
 func (s *Searcher) genNodes(theNode *node) []*node {
 	teamGames := make(map[int]map[int]bool)
 	teamGamesCnt := make(map[int]int)
-	teamPrevSlots := make(map[int][]int)
-	coachPrevSlots := make(map[int][]int)
+	teamPrev := make(map[int][]tInterval)
+	coachPrev := make(map[int][]tInterval)
 	coachTeamsCnt := make(map[int]int)
-	fieldsPrevSlots := make(map[int]map[int]bool)
-	fieldSlotsMap := make(map[int][]int)
-	prevPairsByDiv := make(map[int]map[*ds.TeamPair]bool)
+	fieldsPrev := make(map[int][]tInterval)
+	prevPairsByGroup := make(map[int]map[*ds.TeamPair]bool)
+	refGames := make([]refGame, 0) // игры ветки, которым нужны судьи
+	pairsPenalty := 0
 
 	curNode := theNode
 	for curNode != nil {
 		id1, id2 := curNode.teamPair.Team1.ID, curNode.teamPair.Team2.ID
 		team1, team2 := s.Condition.teamsByIDs[id1], s.Condition.teamsByIDs[id2]
+		pairsPenalty += s.Condition.nodePenalty(curNode.teamPair, curNode.field, curNode.slot)
 
+		iv := tInterval{from: curNode.timeFrom, to: curNode.timeTo, stadiumID: curNode.field.StadiumID()}
 		teamGames[id1] = map[int]bool{id2: true}
 		teamGamesCnt[id1]++
 		teamGamesCnt[id2]++
-		teamPrevSlots[id1] = append(teamPrevSlots[id1], curNode.slot)
-		teamPrevSlots[id2] = append(teamPrevSlots[id2], curNode.slot)
+		teamPrev[id1] = append(teamPrev[id1], iv)
+		teamPrev[id2] = append(teamPrev[id2], iv)
 
-		coachPrevSlots[team1.CoachID] = append(coachPrevSlots[team1.CoachID], curNode.slot)
-		coachPrevSlots[team2.CoachID] = append(coachPrevSlots[team2.CoachID], curNode.slot)
+		coachPrev[team1.CoachID] = append(coachPrev[team1.CoachID], iv)
+		coachPrev[team2.CoachID] = append(coachPrev[team2.CoachID], iv)
 
-		if fieldsPrevSlots[curNode.field.Field1.ID] == nil {
-			fieldsPrevSlots[curNode.field.Field1.ID] = make(map[int]bool)
+		for _, f := range curNode.field.Fields() {
+			fieldsPrev[f.ID] = append(fieldsPrev[f.ID], iv)
 		}
-		fieldsPrevSlots[curNode.field.Field1.ID][curNode.slot] = true
-
-		if curNode.field.Field2 != nil {
-			if fieldsPrevSlots[curNode.field.Field2.ID] == nil {
-				fieldsPrevSlots[curNode.field.Field2.ID] = make(map[int]bool)
-			}
-			fieldsPrevSlots[curNode.field.Field2.ID][curNode.slot] = true
+		if len(s.Condition.Referees) > 0 {
+			refGames = append(refGames, refGame{from: iv.from, to: iv.to, format: s.Condition.teamFormats[id1]})
 		}
 
-		divID := team1.DivisionID
-		if _, ok := prevPairsByDiv[divID]; !ok {
-			prevPairsByDiv[divID] = make(map[*ds.TeamPair]bool)
+		group := s.Condition.divGroups[team1.DivisionID]
+		if _, ok := prevPairsByGroup[group]; !ok {
+			prevPairsByGroup[group] = make(map[*ds.TeamPair]bool)
 		}
-		prevPairsByDiv[divID][curNode.teamPair] = true
+		prevPairsByGroup[group][curNode.teamPair] = true
 
 		curNode = curNode.parent
 	}
@@ -398,41 +448,47 @@ func (s *Searcher) genNodes(theNode *node) []*node {
 		coachTeamsCnt[team.CoachID]++
 	}
 
-	for i := range s.Condition.Fields {
-		field := s.Condition.Fields[i]
-		fID := field.ID
-		prevSlots := fieldsPrevSlots[fID]
-		for _, slot := range field.GetSlots() {
-			if !prevSlots[slot] {
-				fieldSlotsMap[fID] = append(fieldSlotsMap[fID], slot)
+	// свободные слоты полей
+	freeSlots := make(map[*ds.FieldNode][]bool, len(s.Condition.fieldNodes))
+	for _, fNode := range s.Condition.fieldNodes {
+		free := make([]bool, fNode.SlotsCnt())
+		for slot := range free {
+			from, to := fNode.GetFromTo(slot)
+			free[slot] = true
+			for _, f := range fNode.Fields() {
+				for _, iv := range fieldsPrev[f.ID] {
+					if iv.overlaps(from, to) {
+						free[slot] = false
+					}
+				}
 			}
 		}
-		fieldSlotsMap[fID] = UniqueSlots(fieldSlotsMap[fID])
+		freeSlots[fNode] = free
 	}
 
 	teamNodeSlotsMap := make(map[int]map[*ds.FieldNode][]int)
 	for tID, team := range s.Condition.teamsByIDs {
-		if teamGamesCnt[tID] == 2 {
-			continue // команда сыграла обе игры
+		if teamGamesCnt[tID] >= s.Condition.gamesNeed[tID] {
+			continue // команда сыграла все свои игры
 		}
 
-		div := s.Condition.divMap[team.DivisionID]
 		cID := team.CoachID
-
-		teamSlots := resolveTeamSlots(s.Condition.teamSlots[tID], teamPrevSlots[tID], coachPrevSlots[cID], coachTeamsCnt[cID])
-		for _, fNode := range s.Condition.fieldNodes {
-			if fNode.Format() == 7 && div.Format < 7 || div.Format > fNode.Format() {
-				continue
-			}
-
-			fieldSlots := fieldSlotsMap[fNode.Field1.ID]
-			if fNode.Field2 != nil {
-				fieldSlots = IntersectSlots(fieldSlots, fieldSlotsMap[fNode.Field2.ID])
-			}
-
-			availableSlots := IntersectSlots(teamSlots, fieldSlots)
-			if _, ok := teamNodeSlotsMap[tID]; !ok {
-				teamNodeSlotsMap[tID] = make(map[*ds.FieldNode][]int)
+		teamNodeSlotsMap[tID] = make(map[*ds.FieldNode][]int, len(s.Condition.teamNodeSlots[tID]))
+		for fNode, slots := range s.Condition.teamNodeSlots[tID] {
+			availableSlots := make([]int, 0, len(slots))
+			for _, slot := range slots {
+				if !freeSlots[fNode][slot] {
+					continue
+				}
+				from, to := fNode.GetFromTo(slot)
+				// между играми команды не больше одной игры с пересменками
+				if !s.Condition.isTeamTimeOk(teamPrev[tID], from, to, fNode.StadiumID(), fNode.GameDur()+2*fNode.Buffer()) {
+					continue
+				}
+				if !s.Condition.isCoachTimeOk(cID, coachPrev[cID], coachTeamsCnt[cID], from, to, fNode.StadiumID()) {
+					continue
+				}
+				availableSlots = append(availableSlots, slot)
 			}
 			teamNodeSlotsMap[tID][fNode] = availableSlots
 		}
@@ -454,7 +510,7 @@ func (s *Searcher) genNodes(theNode *node) []*node {
 	var theMostProblemTeam *ds.Team
 	minCnt := 1000000
 	for tID, cnt := range teamSlotsCnt {
-		if theMostProblemTeam == nil || minCnt > cnt {
+		if theMostProblemTeam == nil || minCnt > cnt || (minCnt == cnt && tID < theMostProblemTeam.ID) {
 			theMostProblemTeam = s.Condition.teamsByIDs[tID]
 			minCnt = cnt
 		}
@@ -465,47 +521,44 @@ func (s *Searcher) genNodes(theNode *node) []*node {
 	}
 
 	nodes := make([]*node, 0, len(s.Condition.teamPairsMap)*3)
-	fieldStart, gameDur := s.Condition.Fields[0].TimeFrom, s.Condition.Fields[0].GameDur
 
 	for _, pairs := range s.Condition.teamPairsMap {
 		for _, pair := range pairs {
 			if pair.Team1.ID != theMostProblemTeam.ID && pair.Team2.ID != theMostProblemTeam.ID {
 				continue
 			}
-			if teamGamesCnt[pair.Team1.ID] == 2 || teamGamesCnt[pair.Team2.ID] == 2 {
-				continue // оманда сыграла обе игры
+			if teamGamesCnt[pair.Team1.ID] >= s.Condition.gamesNeed[pair.Team1.ID] || teamGamesCnt[pair.Team2.ID] >= s.Condition.gamesNeed[pair.Team2.ID] {
+				continue // команда сыграла все свои игры
 			}
 			if teamGames[pair.Team1.ID][pair.Team2.ID] || teamGames[pair.Team2.ID][pair.Team1.ID] {
 				continue
 			}
-			if !s.checkRestDivTeams(prevPairsByDiv, pair) {
+			if !s.checkRestDivTeams(prevPairsByGroup, pair) {
 				continue
 			}
 
 			for _, fNode := range s.Condition.fieldNodes {
 				slots1 := teamNodeSlotsMap[pair.Team1.ID][fNode]
 				slots2 := teamNodeSlotsMap[pair.Team2.ID][fNode]
-				pairSlots := IntersectSlots(slots1, slots2)
-
-				fieldSlots := fieldSlotsMap[fNode.Field1.ID]
-				if fNode.Field2 != nil {
-					fieldSlots = IntersectSlots(fieldSlots, fieldSlotsMap[fNode.Field2.ID])
-				}
 
-				availableSlots := IntersectSlots(pairSlots, fieldSlots)
+				availableSlots := IntersectSlots(slots1, slots2)
 				if len(availableSlots) == 0 {
 					continue
 				}
 
 				for _, slot := range availableSlots {
-					// количество размещений по команде
+					from, to := fNode.GetFromTo(slot)
+					if !s.Condition.refereesFit(refGames, refGame{from: from, to: to, format: s.Condition.teamFormats[pair.Team1.ID]}) {
+						continue // на эту игру не останется свободного судьи
+					}
 
+					// количество размещений по команде
 					valueSum :=
-						calcTeamSumValue(teamPrevSlots, pair, slot) +
-							calcCoachSumValue(coachPrevSlots, pair, slot) +
-							calcFieldSumValue(fieldsPrevSlots, slot)
+						s.Condition.calcTeamSumValue(teamPrev, pair, from, to) +
+							s.Condition.calcCoachSumValue(coachPrev, pair, from) +
+							s.Condition.calcFieldSumValue(fieldsPrev) +
+							s.Condition.nodePenalty(pair, fNode, slot)
 
-					from, to := GetFromTo(fieldStart, gameDur, slot)
 					nodes = append(nodes, &node{
 						value:    0,
 						valueSum: valueSum,
@@ -529,35 +582,49 @@ func (s *Searcher) genNodes(theNode *node) []*node {
 		return nil
 	}
 
-	if theNode.depth >= len(s.Condition.Teams)-1 {
-		tPrevSum := calcTeamPrevSum(teamPrevSlots)
-		cMinMaxCnt := calcCoachMinMaxCnt(coachPrevSlots)
+	if theNode.depth >= s.Condition.gamesCnt-1 {
+		tPrevSum := s.Condition.calcTeamPrevSum(teamPrev)
+		cMinMaxCnt := s.Condition.calcCoachMinMaxCnt(coachPrev)
 		for _, node := range nodes {
-			node.score = tPrevSum + calcSumValue(node, teamPrevSlots, cMinMaxCnt)
+			node.score = tPrevSum + pairsPenalty + s.Condition.nodePenalty(node.teamPair, node.field, node.slot) + s.Condition.calcSumValue(node, teamPrev, cMinMaxCnt)
 		}
 	}
 
 	// sort nodes by priority
-	sort.Slice(nodes, func(i, j int) bool {
+	sort.SliceStable(nodes, func(i, j int) bool {
 		return nodes[i].valueSum < nodes[j].valueSum
 	})
 
-	return limitNodes(nodes, theNode.depth)
+	return s.limitNodes(nodes, theNode.depth)
 }
 
-func (s *Searcher) checkRestDivTeams(prevPairsByDiv map[int]map[*ds.TeamPair]bool, pair *ds.TeamPair) bool {
-	gamesRest := make(map[int]int, len(s.Condition.teamsByDivs[pair.Team1.DivisionID]))
-	for _, team := range s.Condition.teamsByDivs[pair.Team1.DivisionID] {
-		gamesRest[team.ID] = 2
+// checkRestDivTeams проверить, что после игры pair остальные команды группы дивизионов смогут сыграть свои игры
+func (s *Searcher) checkRestDivTeams(prevPairsByGroup map[int]map[*ds.TeamPair]bool, pair *ds.TeamPair) bool {
+	group := s.Condition.divGroups[pair.Team1.DivisionID]
+	gamesRest := make(map[int]int, len(s.Condition.teamsByGroups[group]))
+	for _, team := range s.Condition.teamsByGroups[group] {
+		gamesRest[team.ID] = s.Condition.gamesNeed[team.ID]
 	}
 
-	for p := range prevPairsByDiv[pair.Team1.DivisionID] {
+	for p := range prevPairsByGroup[group] {
 		gamesRest[p.Team1.ID]--
 		gamesRest[p.Team2.ID]--
 	}
 	gamesRest[pair.Team1.ID]--
 	gamesRest[pair.Team2.ID]--
 
+	// обязательные пары должны оставаться возможными
+	for tID := range gamesRest {
+		for _, p := range s.Condition.mustPairs[tID] {
+			if p == pair || prevPairsByGroup[group][p] {
+				continue
+			}
+			if gamesRest[p.Team1.ID] <= 0 || gamesRest[p.Team2.ID] <= 0 {
+				return false
+			}
+		}
+	}
+
 	for tID, cnt := range gamesRest {
 		cntRest := 0
 		for _, p := range s.Condition.teamPairsByTeamMap[tID] {
@@ -573,57 +640,60 @@ func (s *Searcher) checkRestDivTeams(prevPairsByDiv map[int]map[*ds.TeamPair]boo
 	return true
 }
 
-func limitNodes(nodes []*node, depth int) []*node {
+func (s *Searcher) limitNodes(nodes []*node, depth int) []*node {
 	cnt := len(nodes)
-	switch {
-	case depth > 30 && cnt >= 30:
-		return nodes[:2]
-	case depth > 20 && cnt >= 20:
-		return nodes[:3]
-	case depth > 10 && cnt >= 10:
-		return nodes[:4]
-	case depth > 5 && cnt >= 5:
-		return nodes[:5]
-	case cnt >= 10:
-		return nodes[:10]
+	for _, l := range s.settings.NodeLimits {
+		if depth >= l.MinDepth && cnt >= l.MinNodes {
+			if cnt > l.Keep {
+				return nodes[:l.Keep]
+			}
+			return nodes
+		}
 	}
 	return nodes
 }
 
-func calcTeamSumValue(teamPrevSlots map[int][]int, newPair *ds.TeamPair, newSlot int) int {
-	val := 0
+// idle простой между двумя играми в самых коротких играх
+func (c *Condition) idle(iv1, iv2 tInterval) int {
+	return c.units(iv1.gap(iv2.from, iv2.to))
+}
 
-	for tID, slots := range teamPrevSlots {
-		if len(slots) == 2 {
-			val += abs(slots[0]-slots[1]) - 1
+func (c *Condition) calcTeamSumValue(teamPrev map[int][]tInterval, newPair *ds.TeamPair, from, to time.Time) int {
+	val := 0
 
-		} else if len(slots) == 1 {
+	for tID, ivs := range teamPrev {
+		for i := 1; i < len(ivs); i++ {
+			val += c.idle(ivs[i-1], ivs[i])
+		}
+		if len(ivs) > 0 && len(ivs) < c.gamesNeed[tID] {
 			if newPair.Team1.ID == tID || newPair.Team2.ID == tID {
-				val += abs(slots[0]-newSlot) - 1
+				val += c.idle(ivs[len(ivs)-1], tInterval{from: from, to: to})
 			}
 		}
 	}
 	return val
 }
 
-func calcSumValue(curNode *node, teamPrevSlots map[int][]int, cMinMaxCnt map[int][3]int) int {
+func (c *Condition) calcSumValue(curNode *node, teamPrev map[int][]tInterval, cMinMaxCnt map[int][3]int) int {
 	sum := 0
+	cur := tInterval{from: curNode.timeFrom, to: curNode.timeTo}
 
-	if slots := teamPrevSlots[curNode.teamPair.Team1.ID]; len(slots) > 0 {
-		sum += abs(slots[0]-curNode.slot) - 1
+	if ivs := teamPrev[curNode.teamPair.Team1.ID]; len(ivs) > 0 {
+		sum += c.idle(ivs[0], cur)
 	}
-	if slots := teamPrevSlots[curNode.teamPair.Team2.ID]; len(slots) > 0 {
-		sum += abs(slots[0]-curNode.slot) - 1
+	if ivs := teamPrev[curNode.teamPair.Team2.ID]; len(ivs) > 0 {
+		sum += c.idle(ivs[0], cur)
 	}
 
+	pos := c.pos(curNode.timeFrom)
 	for _, cID := range []int{curNode.teamPair.Team1.CoachID, curNode.teamPair.Team2.CoachID} {
 		if mmc, ok := cMinMaxCnt[cID]; ok {
 			min, max, cnt := mmc[0], mmc[1], mmc[2]
-			if curNode.slot < min {
-				min = curNode.slot
+			if pos < min {
+				min = pos
 			}
-			if curNode.slot > max {
-				max = curNode.slot
+			if pos > max {
+				max = pos
 			}
 			cnt++
 			sum += cnt - (max - min + 1)
@@ -633,72 +703,75 @@ func calcSumValue(curNode *node, teamPrevSlots map[int][]int, cMinMaxCnt map[int
 	return sum
 }
 
-func calcTeamPrevSum(teamPrevSlots map[int][]int) int {
+func (c *Condition) calcTeamPrevSum(teamPrev map[int][]tInterval) int {
 	sum := 0
-	for _, slots := range teamPrevSlots {
-		if len(slots) == 2 {
-			sum += abs(slots[0]-slots[1]) - 1
+	for _, ivs := range teamPrev {
+		for i := 1; i < len(ivs); i++ {
+			sum += c.idle(ivs[i-1], ivs[i])
 		}
 	}
 	return sum
 }
 
-func calcCoachMinMaxCnt(coachPrevSlots map[int][]int) map[int][3]int {
+func (c *Condition) calcCoachMinMaxCnt(coachPrev map[int][]tInterval) map[int][3]int {
 	minMax := make(map[int][3]int, 30)
-	for cID, slots := range coachPrevSlots {
+	for cID, ivs := range coachPrev {
 		min, max := 10000, -10000
-		for _, slot := range slots {
-			if min > slot {
-				min = slot
+		for _, iv := range ivs {
+			pos := c.pos(iv.from)
+			if min > pos {
+				min = pos
 			}
-			if max < slot {
-				max = slot
+			if max < pos {
+				max = pos
 			}
 		}
-		minMax[cID] = [3]int{min, max, len(slots)}
+		minMax[cID] = [3]int{min, max, len(ivs)}
 	}
 	return minMax
 }
 
-func calcCoachSumValue(coachPrevSlots map[int][]int, newPair *ds.TeamPair, newSlot int) int {
+func (c *Condition) calcCoachSumValue(coachPrev map[int][]tInterval, newPair *ds.TeamPair, from time.Time) int {
 	val := 0
-	coachSlots := make(map[int][]int)
-	for cID, slots := range coachPrevSlots {
-		coachSlots[cID] = append(coachSlots[cID], slots...)
+	coachPos := make(map[int][]int)
+	for cID, ivs := range coachPrev {
+		for _, iv := range ivs {
+			coachPos[cID] = append(coachPos[cID], c.pos(iv.from))
+		}
 	}
 
 	c1, c2 := newPair.Team1.CoachID, newPair.Team2.CoachID
-	coachSlots[c1] = append(coachSlots[c1], newSlot)
-	coachSlots[c2] = append(coachSlots[c2], newSlot)
+	coachPos[c1] = append(coachPos[c1], c.pos(from))
+	coachPos[c2] = append(coachPos[c2], c.pos(from))
 
-	for _, slots := range coachSlots {
+	for _, positions := range coachPos {
 		min, max := 10000, -10000
-		for _, slot := range slots {
-			if max < slot {
-				max = slot
+		for _, pos := range positions {
+			if max < pos {
+				max = pos
 			}
-			if min > slot {
-				min = slot
+			if min > pos {
+				min = pos
 			}
 		}
-		val += len(slots) - (max - min)
+		val += len(positions) - (max - min)
 
 	}
 	return val
 }
 
-func calcFieldSumValue(fieldsPrevSlots map[int]map[int]bool, slot int) int {
+func (c *Condition) calcFieldSumValue(fieldsPrev map[int][]tInterval) int {
 	val := 0
-	for _, slots := range fieldsPrevSlots {
+	for _, ivs := range fieldsPrev {
 		max := -10000
-		for slot := range slots {
-			if max < slot {
-				max = slot
+		for _, iv := range ivs {
+			if pos := c.pos(iv.from); max < pos {
+				max = pos
 			}
 		}
 		val += max
 	}
-	return val / len(fieldsPrevSlots)
+	return val / len(fieldsPrev)
 }
 
 func abs(n int) int {
@@ -708,28 +781,81 @@ func abs(n int) int {
 	return n
 }
 
-func fNodeKey(fNode *ds.FieldNode) string {
-	return fmt.Sprintf("%d_%s_%s", fNode.Format(), fNode.GetTimeFrom().Format("15:04"), fNode.GetTimeTo().Format("15:04"))
+// fNodeKey одинаковые поля взаимозаменяемы, кроме тех, которые упоминаются в пожеланиях команд
+func (c *Condition) fNodeKey(fNode *ds.FieldNode) string {
+	key := fmt.Sprintf("%d_%d_%d_%s_%s", fNode.StadiumID(), fNode.Format(), len(fNode.Fields()), fNode.GetTimeFrom().Format("02.01 15:04"), fNode.GetTimeTo().Format("15:04"))
+	for _, f := range fNode.Fields() {
+		key += "_" + ds.BreaksString(f.Breaks)
+	}
+	for _, nodes := range c.bannedNodes {
+		if nodes[fNode] {
+			return key + "_" + fNode.String()
+		}
+	}
+	return key
 }
 
+// getNextNode первая нода, с которой перебирать дальше: текущая или, если ее ветки перебраны,
+// следующая по кругу. Когда перебраны все первые ноды, возвращает nil
 func (s *Searcher) getNextNode() *node {
-	// rotate nextNode pointer
-	// todo ...
+	s.lock.Lock()
+	defer s.lock.Unlock()
 
-	return s.nextNode
+	nLen := len(s.tree.firstNodes)
+	for i := 0; i < nLen; i++ {
+		idx := (s.nextNodeIdx + i) % nLen
+		if n := s.tree.firstNodes[idx]; !n.done {
+			s.nextNodeIdx, s.nextNode = idx, n
+			return n
+		}
+	}
+	return nil
 }
 
 func (s *Searcher) GetSolutions() ([]Solution, int) {
+	// сортируем копию: поиск дописывает решения в s.solutions, пока их читают
 	s.lock.RLock()
-	sol := s.solutions
+	sol := make([]Solution, len(s.solutions))
+	copy(sol, s.solutions)
 	att := s.attempts
 	s.lock.RUnlock()
 
+	sortSolutions(sol)
+
+	return sol, att
+}
+
+func sortSolutions(sol []Solution) {
 	sort.Slice(sol, func(i, j int) bool {
 		return sol[i].Sum < sol[j].Sum
 	})
+}
 
-	return sol, att
+// Current номер текущего поиска и канал, в который придет его итог
+func (s *Searcher) Current() (int, <-chan Result) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.searchID, s.result
+}
+
+// SearchID номер текущего поиска: по нему видно, что тот поиск уже сменился следующим
+func (s *Searcher) SearchID() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.searchID
+}
+
+// Done канал текущего поиска, закрывается после его завершения
+func (s *Searcher) Done() <-chan struct{} {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.done
+}
+
+func (s *Searcher) setReason(reason string) {
+	s.lock.Lock()
+	s.reason = reason
+	s.lock.Unlock()
 }
 
 func (s *Searcher) Status() int {
@@ -768,6 +894,14 @@ func (s *Searcher) AttemptsCnt() int {
 	return s.attempts
 }
 
+// RefereeRejected сколько решений отброшено, потому что на игры не хватило судей
+func (s *Searcher) RefereeRejected() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.refRejected
+}
+
 func (s *Searcher) addSolution(theNode *node) {
 	games := make(map[string][]SolutioGame, len(s.Condition.Fields))
 
@@ -787,9 +921,24 @@ func (s *Searcher) addSolution(theNode *node) {
 		})
 	}
 
+	sum := theNode.score
+	if len(s.Condition.Referees) > 0 {
+		idle, ok := s.Condition.assignReferees(games)
+		if !ok {
+			// у каждой игры есть свой судья, но расписать их с переездами и лимитом игр не вышло
+			s.lock.Lock()
+			s.refRejected++
+			s.lock.Unlock()
+			return
+		}
+		sum += idle / int(s.Condition.unit.Minutes())
+	}
+
 	sl := Solution{
-		Sum:   theNode.score,
-		Games: games,
+		Sum:    sum,
+		Games:  games,
+		Breaks: s.Condition.fieldBreaks,
+		Venues: s.Condition.nodeVenues,
 	}
 
 	sl.HashStr = fmt.Sprintf("%d_%s", SearchCounter, sl.Hash())
@@ -802,20 +951,25 @@ func (s *Searcher) addSolution(theNode *node) {
 	}
 }
 
-func (s *Searcher) rotateNextNode() {
-	ticker := time.NewTicker(5 * time.Second)
+// rotateNextNode переключать первую ноду, пока не закрыт stop текущего поиска
+func (s *Searcher) rotateNextNode(stop <-chan struct{}) {
+	ticker := time.NewTicker(s.settings.RotateInterval)
 	defer ticker.Stop()
 	for {
 		select {
-		case <-s.stop:
+		case <-stop:
 			return
 		default:
 		}
 		select {
-		case <-s.stop:
+		case <-stop:
 			return
 		case <-ticker.C:
 			s.lock.Lock()
+			if s.tree == nil || len(s.tree.firstNodes) == 0 {
+				s.lock.Unlock()
+				return // поиск уже завершился
+			}
 			// fmt.Println("switch node", s.nextNodeIdx)
 			s.nextNodeIdx++
 			idx, nLen := s.nextNodeIdx, len(s.tree.firstNodes)
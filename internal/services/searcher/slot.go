@@ -37,6 +37,37 @@ func UniqueSlots(slots []int) []int {
 	return out
 }
 
-func GetFromTo(start time.Time, dur time.Duration, slot int) (time.Time, time.Time) {
-	return start.Add(dur * time.Duration(slot)), start.Add(dur * time.Duration(slot+1))
+type tInterval struct {
+	from, to  time.Time
+	stadiumID int
+}
+
+// overlaps промежуток пересекается с игрой from-to
+func (iv tInterval) overlaps(from, to time.Time) bool {
+	return from.Before(iv.to) && iv.from.Before(to)
+}
+
+// travelOk между промежутком и игрой на другом стадионе хватает времени на переезд
+func (iv tInterval) travelOk(from, to time.Time, stadiumID int, travel time.Duration) bool {
+	return iv.stadiumID == stadiumID || iv.gap(from, to) >= travel
+}
+
+// sameDay промежуток в тот же день, что и t
+func (iv tInterval) sameDay(t time.Time) bool {
+	return sameDate(iv.from, t)
+}
+
+// sameDate моменты в один календарный день
+func sameDate(a, b time.Time) bool {
+	y1, m1, d1 := a.Date()
+	y2, m2, d2 := b.Date()
+	return y1 == y2 && m1 == m2 && d1 == d2
+}
+
+// gap время между промежутком и игрой from-to, которые не пересекаются
+func (iv tInterval) gap(from, to time.Time) time.Duration {
+	if !iv.from.Before(to) {
+		return iv.from.Sub(to)
+	}
+	return from.Sub(iv.to)
 }
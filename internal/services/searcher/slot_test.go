@@ -0,0 +1,39 @@
+package searcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsTeamTimeOk(t *testing.T) {
+	c := &Condition{Params: Params{TravelDur: 30 * time.Minute}}
+	at := func(day, h, m int) time.Time {
+		return time.Date(2024, 5, day, h, m, 0, 0, time.Local)
+	}
+	// предыдущая игра команды 18 мая 10:00-11:00 на стадионе 1
+	prev := []tInterval{{from: at(18, 10, 0), to: at(18, 11, 0), stadiumID: 1}}
+
+	tests := []struct {
+		name      string
+		from      time.Time
+		stadiumID int
+		want      bool
+	}{
+		{name: "overlap", from: at(18, 10, 30), stadiumID: 1},
+		{name: "right after on the same stadium", from: at(18, 11, 0), stadiumID: 1, want: true},
+		{name: "no time to travel", from: at(18, 11, 10), stadiumID: 2},
+		{name: "travel before the previous game", from: at(18, 8, 50), stadiumID: 2},
+		{name: "enough time to travel", from: at(18, 11, 30), stadiumID: 2, want: true},
+		{name: "travel time is not a wait", from: at(18, 13, 30), stadiumID: 2, want: true},
+		{name: "too long a wait", from: at(18, 13, 10), stadiumID: 1},
+		{name: "same time on another day", from: at(19, 10, 0), stadiumID: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := c.isTeamTimeOk(prev, tc.from, tc.from.Add(time.Hour), tc.stadiumID, 2*time.Hour); got != tc.want {
+				t.Errorf("ok = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
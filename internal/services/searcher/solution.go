@@ -7,11 +7,15 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"github.com/sergrom/timetable/internal/ds"
 )
 
 type Solution struct {
 	Sum     int                      `json:"sum"`
 	Games   map[string][]SolutioGame `json:"games"`
+	Breaks  map[string][]ds.Break    `json:"breaks"` // перерывы на полях
+	Venues  map[string]string        `json:"venues"` // стадион и дата полей
 	HashStr string                   `json:"hash"`
 }
 
@@ -20,7 +24,9 @@ type SolutioGame struct {
 	TeamID2   int       `json:"team_id_2"`
 	Start     time.Time `json:"start"`
 	End       time.Time `json:"end"`
+	RefereeID int       `json:"referee_id"`
 	ExtraInfo string
+	IsBreak   bool
 	FixRowIdx int
 }
 
@@ -0,0 +1,87 @@
+package searcher
+
+import (
+	"time"
+
+	"github.com/sergrom/timetable/internal/ds"
+	"github.com/sergrom/timetable/internal/pkg"
+)
+
+// teamWishGroups промежутки, в которые команда может играть.
+// Несколько пожеланий "только" объединяются, пожелания "не может" вычитаются из каждого
+func teamWishGroups(dayFrom, dayTo time.Time, wishes []*ds.Wish) []*pkg.IvGroup {
+	groups := make([]*pkg.IvGroup, 0, 1)
+	for _, w := range wishes {
+		if w.Kind != ds.WishWithin {
+			continue
+		}
+		ig := pkg.NewIvGroup(dayFrom, dayTo)
+		ig.ApplyWish(w.TimeFrom, w.TimeTo)
+		groups = append(groups, ig)
+	}
+	if len(groups) == 0 {
+		groups = append(groups, pkg.NewIvGroup(dayFrom, dayTo))
+	}
+
+	for _, w := range wishes {
+		if w.Kind != ds.WishNotDuring {
+			continue
+		}
+		from, to := w.TimeFrom, w.TimeTo
+		if from.IsZero() {
+			from = dayFrom
+		}
+		if to.IsZero() {
+			to = dayTo
+		}
+		for _, ig := range groups {
+			ig.Exclude(from, to)
+		}
+	}
+
+	return groups
+}
+
+func isInGroups(groups []*pkg.IvGroup, from, to time.Time) bool {
+	for _, ig := range groups {
+		if ig.IsOk(from, to) {
+			return true
+		}
+	}
+	return false
+}
+
+// calcPreferPenalty штраф за удаленность игры от желаемого времени
+func calcPreferPenalty(wishes []*ds.Wish, from, to time.Time) int {
+	dur := to.Sub(from)
+	penalty := 0
+	for _, w := range wishes {
+		if w.Kind != ds.WishPrefer {
+			continue
+		}
+		_, dist := pkg.NewIvGroup(from, to).PlaceDurationNear(dur, w.Point())
+		penalty += w.Weight * dist / int(dur.Minutes())
+	}
+	return penalty
+}
+
+// isFieldNodeOk поле (или объединенные поля) подходит под пожелания команды по полям.
+// Несколько пожеланий "только поле" или "только формат" объединяются
+func isFieldNodeOk(fNode *ds.FieldNode, wishes []*ds.Wish) bool {
+	hasField, fieldOk, hasFormat, formatOk := false, false, false, false
+	for _, w := range wishes {
+		switch w.Kind {
+		case ds.WishField:
+			hasField = true
+			fieldOk = fieldOk || fNode.HasField(w.Value)
+		case ds.WishNotField:
+			if fNode.HasField(w.Value) {
+				return false
+			}
+		case ds.WishFieldFormat:
+			hasFormat = true
+			formatOk = formatOk || fNode.Format() == w.Value
+		}
+	}
+	return (!hasField || fieldOk) && (!hasFormat || formatOk)
+}
@@ -0,0 +1,194 @@
+package season
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/sergrom/timetable/internal/ds"
+)
+
+// Tour тур сезона
+type Tour struct {
+	Name string
+	Date string
+}
+
+// Plan составить план сезона для дивизионов: в каждом дивизионе каждая команда встречается с каждой один раз.
+// Круги берутся по круговой системе (метод многоугольника) и поровну раскладываются по турам, в тур - не больше
+// gamesPerTour кругов, иначе ошибка. Команды одного тренера между собой не играют, такие пары пропускаются,
+// их число возвращается вторым значением. Хозяин каждой игры выбирается так, чтобы домашних и гостевых игр
+// у команды было поровну.
+func Plan(divisions []ds.Division, teams []ds.Team, tours []Tour, gamesPerTour int) ([]ds.SeasonGame, int, error) {
+	if len(tours) == 0 {
+		return nil, 0, errors.New("не заданы туры сезона")
+	}
+
+	teamsByDivs := make(map[int][]ds.Team)
+	for _, t := range teams {
+		teamsByDivs[t.DivisionID] = append(teamsByDivs[t.DivisionID], t)
+	}
+
+	games := make([]ds.SeasonGame, 0, len(teams)*len(teams)/2)
+	skipped := 0
+	for _, div := range divisions {
+		tt := teamsByDivs[div.ID]
+		if len(tt) < 2 {
+			continue
+		}
+		sort.Slice(tt, func(i, j int) bool { return tt[i].ID < tt[j].ID })
+
+		rounds := roundRobin(len(tt))
+		if len(rounds) > len(tours)*gamesPerTour {
+			return nil, 0, fmt.Errorf("дивизиону %s нужно кругов: %d, туров: %d, а в тур помещается кругов не больше %d - добавьте даты",
+				div.Name, len(rounds), len(tours), gamesPerTour)
+		}
+
+		home := newHomeBalance(len(tt))
+		divGames := make([][2]int, 0, len(tt)*(len(tt)-1)/2)
+		divTours := make([]Tour, 0, cap(divGames))
+		for r, pairs := range rounds {
+			// круги поровну по турам: в туре не больше ceil(кругов/туров) кругов,
+			// если туров больше, чем кругов - в некоторых турах дивизион не играет
+			tour := tours[r*len(tours)/len(rounds)]
+			for _, p := range pairs {
+				if tt[p[0]].CoachID == tt[p[1]].CoachID {
+					skipped++
+					continue
+				}
+				h, a := home.pick(p[0], p[1])
+				divGames = append(divGames, [2]int{h, a})
+				divTours = append(divTours, tour)
+			}
+		}
+		home.repair(divGames)
+
+		for i, g := range divGames {
+			games = append(games, ds.SeasonGame{
+				Tour:    divTours[i].Name,
+				Date:    divTours[i].Date,
+				TeamID1: tt[g[0]].ID,
+				TeamID2: tt[g[1]].ID,
+			})
+		}
+	}
+
+	for i := range games {
+		games[i].ID = i + 1
+	}
+
+	return games, skipped, nil
+}
+
+// roundRobin круги круговой системы для n команд, при нечетном n в каждом круге одна команда отдыхает
+func roundRobin(n int) [][][2]int {
+	size := n
+	if size%2 == 1 {
+		size++ // фиктивная команда, игра с ней - выходной
+	}
+
+	ring := make([]int, size)
+	for i := range ring {
+		ring[i] = i
+	}
+
+	rounds := make([][][2]int, 0, size-1)
+	for r := 0; r < size-1; r++ {
+		pairs := make([][2]int, 0, size/2)
+		for i := 0; i < size/2; i++ {
+			t1, t2 := ring[i], ring[size-1-i]
+			if t1 >= n || t2 >= n {
+				continue
+			}
+			pairs = append(pairs, [2]int{t1, t2})
+		}
+		rounds = append(rounds, pairs)
+
+		// первая команда стоит на месте, остальные сдвигаются по кругу
+		last := ring[size-1]
+		copy(ring[2:], ring[1:size-1])
+		ring[1] = last
+	}
+
+	return rounds
+}
+
+// homeBalance счетчики домашних и гостевых игр команд
+type homeBalance struct {
+	diff     []int  // домашние минус гостевые
+	lastHome []bool // последняя игра была дома
+}
+
+func newHomeBalance(n int) *homeBalance {
+	return &homeBalance{
+		diff:     make([]int, n),
+		lastHome: make([]bool, n),
+	}
+}
+
+// pick выбрать хозяина игры: у кого меньше домашних игр, при равенстве - кто прошлую играл в гостях
+func (hb *homeBalance) pick(t1, t2 int) (int, int) {
+	h, a := t1, t2
+	switch {
+	case hb.diff[t2] < hb.diff[t1]:
+		h, a = t2, t1
+	case hb.diff[t2] == hb.diff[t1] && hb.lastHome[t1] && !hb.lastHome[t2]:
+		h, a = t2, t1
+	}
+
+	hb.diff[h]++
+	hb.diff[a]--
+	hb.lastHome[h], hb.lastHome[a] = true, false
+
+	return h, a
+}
+
+// repair поменять хозяев так, чтобы перекос домашних и гостевых игр у команд был не больше одной:
+// от команды с перекосом ищется цепочка игр хозяин -> гость до команды с недостатком домашних игр
+// и во всей цепочке хозяева меняются местами, у промежуточных команд баланс не меняется
+func (hb *homeBalance) repair(games [][2]int) {
+	for {
+		x := -1
+		for t, d := range hb.diff {
+			if d >= 2 && (x == -1 || d > hb.diff[x]) {
+				x = t
+			}
+		}
+		if x == -1 {
+			return
+		}
+
+		prevGame := map[int]int{x: -1}
+		queue := []int{x}
+		z := -1
+		for len(queue) > 0 && z == -1 {
+			u := queue[0]
+			queue = queue[1:]
+			for i, g := range games {
+				if g[0] != u {
+					continue
+				}
+				if _, ok := prevGame[g[1]]; ok {
+					continue
+				}
+				prevGame[g[1]] = i
+				if hb.diff[x]-hb.diff[g[1]] >= 3 {
+					z = g[1]
+					break
+				}
+				queue = append(queue, g[1])
+			}
+		}
+		if z == -1 {
+			return
+		}
+
+		for t := z; t != x; {
+			i := prevGame[t]
+			games[i] = [2]int{games[i][1], games[i][0]}
+			t = games[i][1]
+		}
+		hb.diff[x] -= 2
+		hb.diff[z] += 2
+	}
+}
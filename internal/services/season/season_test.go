@@ -0,0 +1,211 @@
+package season
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sergrom/timetable/internal/ds"
+)
+
+func TestRoundRobin(t *testing.T) {
+	tests := []struct {
+		n          int
+		wantRounds int
+	}{
+		{n: 2, wantRounds: 1},
+		{n: 3, wantRounds: 3},
+		{n: 4, wantRounds: 3},
+		{n: 5, wantRounds: 5},
+		{n: 6, wantRounds: 5},
+		{n: 9, wantRounds: 9},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("n=%d", tc.n), func(t *testing.T) {
+			rounds := roundRobin(tc.n)
+			if len(rounds) != tc.wantRounds {
+				t.Fatalf("rounds = %d, want %d", len(rounds), tc.wantRounds)
+			}
+
+			met := make(map[[2]int]int)
+			byes := make([]int, tc.n)
+			for r, pairs := range rounds {
+				played := make([]bool, tc.n)
+				for _, p := range pairs {
+					for _, team := range p {
+						if team < 0 || team >= tc.n {
+							t.Fatalf("round %d: team %d out of range", r, team)
+						}
+						if played[team] {
+							t.Fatalf("round %d: team %d plays twice", r, team)
+						}
+						played[team] = true
+					}
+					met[pairKey(p[0], p[1])]++
+				}
+				for team, ok := range played {
+					if !ok {
+						byes[team]++
+					}
+				}
+			}
+
+			if want := tc.n * (tc.n - 1) / 2; len(met) != want {
+				t.Errorf("pairs = %d, want %d", len(met), want)
+			}
+			for key, cnt := range met {
+				if cnt != 1 {
+					t.Errorf("pair %v met %d times", key, cnt)
+				}
+			}
+			wantByes := tc.n % 2 // при нечетном числе команд каждая отдыхает один раз
+			for team, cnt := range byes {
+				if cnt != wantByes {
+					t.Errorf("team %d byes = %d, want %d", team, cnt, wantByes)
+				}
+			}
+		})
+	}
+}
+
+func TestHomeBalanceRepair(t *testing.T) {
+	tests := []struct {
+		name  string
+		n     int
+		games [][2]int
+	}{
+		{
+			name:  "chain",
+			n:     3,
+			games: [][2]int{{0, 1}, {1, 2}, {0, 2}},
+		},
+		{
+			name:  "one team always home",
+			n:     4,
+			games: [][2]int{{0, 1}, {0, 2}, {0, 3}, {1, 2}, {2, 3}, {3, 1}},
+		},
+		{
+			name:  "first team of every round robin pair is home",
+			n:     7,
+			games: flatten(roundRobin(7)),
+		},
+		{
+			name:  "already balanced",
+			n:     3,
+			games: [][2]int{{0, 1}, {1, 2}, {2, 0}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			games := append([][2]int(nil), tc.games...)
+			hb := newHomeBalance(tc.n)
+			for _, g := range games {
+				hb.diff[g[0]]++
+				hb.diff[g[1]]--
+			}
+
+			hb.repair(games)
+
+			diff := make([]int, tc.n)
+			for i, g := range games {
+				if pairKey(g[0], g[1]) != pairKey(tc.games[i][0], tc.games[i][1]) {
+					t.Fatalf("game %d: %v became %v", i, tc.games[i], g)
+				}
+				diff[g[0]]++
+				diff[g[1]]--
+			}
+			for team, d := range diff {
+				if d != hb.diff[team] {
+					t.Errorf("team %d: counter %d, games %d", team, hb.diff[team], d)
+				}
+				if d > 1 || d < -1 {
+					t.Errorf("team %d: home minus away = %d", team, d)
+				}
+			}
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	div := ds.Division{ID: 1, Name: "2012"}
+	teams := func(coaches ...int) []ds.Team {
+		tt := make([]ds.Team, 0, len(coaches))
+		for i, c := range coaches {
+			tt = append(tt, ds.Team{ID: i + 1, Name: fmt.Sprint("team", i+1), DivisionID: div.ID, CoachID: c})
+		}
+		return tt
+	}
+	tours := func(n int) []Tour {
+		tt := make([]Tour, 0, n)
+		for i := 0; i < n; i++ {
+			tt = append(tt, Tour{Name: fmt.Sprint("Тур ", i+1), Date: fmt.Sprintf("2024-10-%02d", i+1)})
+		}
+		return tt
+	}
+
+	tests := []struct {
+		name        string
+		teams       []ds.Team
+		tours       []Tour
+		wantGames   int
+		wantSkipped int
+		wantErr     bool
+	}{
+		{name: "one round per tour", teams: teams(1, 2, 3, 4), tours: tours(3), wantGames: 6},
+		{name: "two rounds per tour", teams: teams(1, 2, 3, 4, 5, 6), tours: tours(3), wantGames: 15},
+		{name: "more tours than rounds", teams: teams(1, 2, 3), tours: tours(5), wantGames: 3},
+		{name: "too few tours", teams: teams(1, 2, 3, 4, 5, 6), tours: tours(2), wantErr: true},
+		{name: "same coach pairs skipped", teams: teams(1, 1, 2, 3), tours: tours(3), wantGames: 5, wantSkipped: 1},
+		{name: "no tours", teams: teams(1, 2), tours: nil, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			games, skipped, err := Plan([]ds.Division{div}, tc.teams, tc.tours, 2)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(games) != tc.wantGames || skipped != tc.wantSkipped {
+				t.Fatalf("games = %d, skipped = %d, want %d, %d", len(games), skipped, tc.wantGames, tc.wantSkipped)
+			}
+
+			perTour := make(map[string]map[int]int)
+			for _, g := range games {
+				if perTour[g.Tour] == nil {
+					perTour[g.Tour] = make(map[int]int)
+				}
+				perTour[g.Tour][g.TeamID1]++
+				perTour[g.Tour][g.TeamID2]++
+			}
+			for tour, cnt := range perTour {
+				for team, n := range cnt {
+					if n > 2 {
+						t.Errorf("%s: team %d plays %d games", tour, team, n)
+					}
+				}
+			}
+		})
+	}
+}
+
+func flatten(rounds [][][2]int) [][2]int {
+	games := make([][2]int, 0)
+	for _, pairs := range rounds {
+		games = append(games, pairs...)
+	}
+	return games
+}
+
+func pairKey(t1, t2 int) [2]int {
+	if t1 > t2 {
+		return [2]int{t2, t1}
+	}
+	return [2]int{t1, t2}
+}
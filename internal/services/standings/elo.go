@@ -0,0 +1,64 @@
+package standings
+
+import (
+	"math"
+	"sort"
+
+	"github.com/sergrom/timetable/internal/ds"
+)
+
+const (
+	EloK     = 32  // насколько сильно одна игра меняет рейтинг Эло
+	EloScale = 400 // разница рейтингов, при которой сильный выигрывает в 10 раз чаще
+)
+
+// Elo рейтинг Эло команд по внесенным результатам, только для команд, у которых есть результаты.
+// Начальный рейтинг - рейтинг команды, если он задан, иначе BaseRating.
+// Игры учитываются по дате, игры без даты - раньше остальных в порядке таблицы игр
+func Elo(teams []ds.Team, games []ds.Game) map[int]int {
+	rating := make(map[int]float64, len(teams))
+	for _, t := range teams {
+		r := t.Rating
+		if r == 0 {
+			r = BaseRating
+		}
+		rating[t.ID] = float64(r)
+	}
+
+	played := make([]ds.Game, 0, len(games))
+	for _, g := range games {
+		_, ok1 := rating[g.TeamID1]
+		_, ok2 := rating[g.TeamID2]
+		if ok1 && ok2 && g.HasResult() {
+			played = append(played, g)
+		}
+	}
+	sort.SliceStable(played, func(i, j int) bool {
+		return played[i].Date < played[j].Date
+	})
+
+	ratings := make(map[int]int)
+	for _, g := range played {
+		r1, r2 := rating[g.TeamID1], rating[g.TeamID2]
+		expected := 1 / (1 + math.Pow(10, (r2-r1)/EloScale))
+		delta := EloK * (eloScore(g.Score1, g.Score2) - expected)
+		rating[g.TeamID1] += delta
+		rating[g.TeamID2] -= delta
+		ratings[g.TeamID1], ratings[g.TeamID2] = 0, 0
+	}
+	for id := range ratings {
+		ratings[id] = int(math.Round(rating[id]))
+	}
+	return ratings
+}
+
+// eloScore результат игры для Эло: победа - 1, ничья - 0.5, поражение - 0
+func eloScore(goalsFor, goalsAgainst int) float64 {
+	switch {
+	case goalsFor > goalsAgainst:
+		return 1
+	case goalsFor == goalsAgainst:
+		return 0.5
+	}
+	return 0
+}
@@ -0,0 +1,77 @@
+package standings
+
+import (
+	"testing"
+
+	"github.com/sergrom/timetable/internal/ds"
+)
+
+func TestElo(t *testing.T) {
+	teams := []ds.Team{
+		{ID: 1, Name: "А"},
+		{ID: 2, Name: "Б"},
+		{ID: 3, Name: "В", Rating: 1400},
+	}
+	played := func(id1, id2, s1, s2 int, date string) ds.Game {
+		return ds.Game{TeamID1: id1, TeamID2: id2, Score1: s1, Score2: s2, Status: ds.GamePlayed, Date: date}
+	}
+
+	tests := []struct {
+		name  string
+		games []ds.Game
+		want  map[int]int
+	}{
+		{
+			name:  "no results",
+			games: []ds.Game{{TeamID1: 1, TeamID2: 2, Status: ds.GameScheduled}},
+			want:  map[int]int{},
+		},
+		{
+			name:  "equal teams, win",
+			games: []ds.Game{played(1, 2, 2, 0, "")},
+			want:  map[int]int{1: BaseRating + EloK/2, 2: BaseRating - EloK/2},
+		},
+		{
+			name:  "equal teams, draw",
+			games: []ds.Game{played(1, 2, 1, 1, "")},
+			want:  map[int]int{1: BaseRating, 2: BaseRating},
+		},
+		{
+			name:  "manual rating is the start, favourite gains little",
+			games: []ds.Game{played(3, 1, 1, 0, "")},
+			want:  map[int]int{3: 1403, 1: 997},
+		},
+		{
+			name:  "upset costs the favourite more",
+			games: []ds.Game{played(3, 1, 0, 1, "")},
+			want:  map[int]int{3: 1371, 1: 1029},
+		},
+		{
+			name: "games go by date",
+			games: []ds.Game{
+				played(1, 2, 0, 1, "2024-10-02"),
+				played(1, 2, 1, 0, "2024-10-01"),
+			},
+			want: map[int]int{1: 999, 2: 1001},
+		},
+		{
+			name:  "unknown team",
+			games: []ds.Game{played(1, 9, 3, 0, "")},
+			want:  map[int]int{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := Elo(teams, tc.games)
+			if len(got) != len(tc.want) {
+				t.Fatalf("ratings %v, want %v", got, tc.want)
+			}
+			for id, r := range tc.want {
+				if got[id] != r {
+					t.Errorf("team %d: rating %d, want %d", id, got[id], r)
+				}
+			}
+		})
+	}
+}
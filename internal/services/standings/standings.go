@@ -0,0 +1,119 @@
+package standings
+
+import (
+	"sort"
+
+	"github.com/sergrom/timetable/internal/ds"
+)
+
+const (
+	PointsWin  = 3
+	PointsDraw = 1
+
+	BaseRating   = 1000 // рейтинг команды без очков
+	RatingPerPPG = 300  // прибавка к рейтингу за каждое очко в среднем за игру
+)
+
+// Row строка турнирной таблицы
+type Row struct {
+	Team         ds.Team
+	Played       int
+	Won          int
+	Drawn        int
+	Lost         int
+	GoalsFor     int
+	GoalsAgainst int
+	Points       int
+}
+
+// GoalDiff разница мячей
+func (r Row) GoalDiff() int {
+	return r.GoalsFor - r.GoalsAgainst
+}
+
+// Rating сила команды по таблице в шкале рейтинга команд
+func (r Row) Rating() int {
+	if r.Played == 0 {
+		return 0
+	}
+	return BaseRating + RatingPerPPG*r.Points/r.Played
+}
+
+// Calc турнирная таблица дивизиона по играм команд дивизиона между собой.
+// Места: очки, очки в личных встречах равных по очкам команд, разница мячей, забитые мячи.
+func Calc(div ds.Division, teams []ds.Team, games []ds.Game) []Row {
+	rows := make([]Row, 0)
+	idx := make(map[int]int)
+	for _, t := range teams {
+		if t.DivisionID != div.ID {
+			continue
+		}
+		idx[t.ID] = len(rows)
+		rows = append(rows, Row{Team: t})
+	}
+
+	divGames := make([]ds.Game, 0, len(games))
+	for _, g := range games {
+		_, ok1 := idx[g.TeamID1]
+		_, ok2 := idx[g.TeamID2]
+		if !ok1 || !ok2 || !g.HasResult() {
+			continue
+		}
+		divGames = append(divGames, g)
+		addResult(&rows[idx[g.TeamID1]], g.Score1, g.Score2)
+		addResult(&rows[idx[g.TeamID2]], g.Score2, g.Score1)
+	}
+
+	// очки в личных встречах среди команд с одинаковыми очками
+	h2h := make(map[int]int)
+	for _, g := range divGames {
+		r1, r2 := rows[idx[g.TeamID1]], rows[idx[g.TeamID2]]
+		if r1.Points != r2.Points {
+			continue
+		}
+		h2h[g.TeamID1] += points(g.Score1, g.Score2)
+		h2h[g.TeamID2] += points(g.Score2, g.Score1)
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		switch {
+		case a.Points != b.Points:
+			return a.Points > b.Points
+		case h2h[a.Team.ID] != h2h[b.Team.ID]:
+			return h2h[a.Team.ID] > h2h[b.Team.ID]
+		case a.GoalDiff() != b.GoalDiff():
+			return a.GoalDiff() > b.GoalDiff()
+		case a.GoalsFor != b.GoalsFor:
+			return a.GoalsFor > b.GoalsFor
+		}
+		return a.Team.Name < b.Team.Name
+	})
+
+	return rows
+}
+
+func addResult(r *Row, goalsFor, goalsAgainst int) {
+	r.Played++
+	r.GoalsFor += goalsFor
+	r.GoalsAgainst += goalsAgainst
+	r.Points += points(goalsFor, goalsAgainst)
+	switch {
+	case goalsFor > goalsAgainst:
+		r.Won++
+	case goalsFor == goalsAgainst:
+		r.Drawn++
+	default:
+		r.Lost++
+	}
+}
+
+func points(goalsFor, goalsAgainst int) int {
+	switch {
+	case goalsFor > goalsAgainst:
+		return PointsWin
+	case goalsFor == goalsAgainst:
+		return PointsDraw
+	}
+	return 0
+}
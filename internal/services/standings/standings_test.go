@@ -0,0 +1,120 @@
+package standings
+
+import (
+	"testing"
+
+	"github.com/sergrom/timetable/internal/ds"
+)
+
+func TestCalc(t *testing.T) {
+	div := ds.Division{ID: 1, Name: "2012"}
+	teams := []ds.Team{
+		{ID: 1, Name: "А", DivisionID: 1},
+		{ID: 2, Name: "Б", DivisionID: 1},
+		{ID: 3, Name: "В", DivisionID: 1},
+		{ID: 4, Name: "Чужой", DivisionID: 2},
+	}
+	played := func(id1, id2, s1, s2 int) ds.Game {
+		return ds.Game{TeamID1: id1, TeamID2: id2, Score1: s1, Score2: s2, Status: ds.GamePlayed}
+	}
+
+	tests := []struct {
+		name      string
+		games     []ds.Game
+		wantOrder []int
+		wantRows  map[int]Row // только проверяемые поля: Played, Won, Drawn, Lost, Points, голы
+	}{
+		{
+			name:      "no games - by name",
+			games:     nil,
+			wantOrder: []int{1, 2, 3},
+			wantRows:  map[int]Row{1: {}, 2: {}, 3: {}},
+		},
+		{
+			name:      "points",
+			games:     []ds.Game{played(1, 2, 0, 1), played(2, 3, 2, 2), played(3, 1, 3, 0)},
+			wantOrder: []int{3, 2, 1}, // у В и Б поровну очков и ничья между собой, у В лучше разница
+			wantRows: map[int]Row{
+				1: {Played: 2, Lost: 2, GoalsFor: 0, GoalsAgainst: 4},
+				2: {Played: 2, Won: 1, Drawn: 1, GoalsFor: 3, GoalsAgainst: 2, Points: 4},
+				3: {Played: 2, Won: 1, Drawn: 1, GoalsFor: 5, GoalsAgainst: 2, Points: 4},
+			},
+		},
+		{
+			name: "head to head before goal difference",
+			games: []ds.Game{
+				played(1, 2, 1, 0), // А обыграл Б, у Б лучше разница мячей
+				played(2, 3, 9, 0),
+				played(1, 3, 0, 0),
+				played(2, 3, 0, 0),
+			},
+			wantOrder: []int{1, 2, 3},
+		},
+		{
+			name: "goals for after equal goal difference",
+			games: []ds.Game{
+				played(1, 2, 2, 2),
+				played(1, 3, 3, 0),
+				played(2, 3, 4, 1),
+			},
+			wantOrder: []int{2, 1, 3},
+		},
+		{
+			name: "only results of the division count",
+			games: []ds.Game{
+				{TeamID1: 1, TeamID2: 2, Score1: 5, Score2: 0, Status: ds.GameScheduled},
+				{TeamID1: 1, TeamID2: 2, Score1: 5, Score2: 0, Status: ds.GameCancelled},
+				played(2, 4, 0, 7),
+				{TeamID1: 3, TeamID2: 1, Score1: 0, Score2: 3, Status: ds.GameForfeit},
+			},
+			wantOrder: []int{1, 2, 3},
+			wantRows: map[int]Row{
+				1: {Played: 1, Won: 1, GoalsFor: 3, Points: PointsWin},
+				2: {},
+				3: {Played: 1, Lost: 1, GoalsAgainst: 3},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rows := Calc(div, teams, tc.games)
+			if len(rows) != len(tc.wantOrder) {
+				t.Fatalf("rows = %d, want %d", len(rows), len(tc.wantOrder))
+			}
+			for i, id := range tc.wantOrder {
+				if rows[i].Team.ID != id {
+					t.Fatalf("place %d: team %d, want %d", i+1, rows[i].Team.ID, id)
+				}
+			}
+			for _, r := range rows {
+				want, ok := tc.wantRows[r.Team.ID]
+				if !ok {
+					continue
+				}
+				want.Team = r.Team
+				if r != want {
+					t.Errorf("team %d: %+v, want %+v", r.Team.ID, r, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRowRating(t *testing.T) {
+	tests := []struct {
+		row  Row
+		want int
+	}{
+		{row: Row{}, want: 0},
+		{row: Row{Played: 2, Points: 0}, want: BaseRating},
+		{row: Row{Played: 2, Points: 6}, want: BaseRating + 3*RatingPerPPG},
+		{row: Row{Played: 3, Points: 4}, want: BaseRating + RatingPerPPG*4/3},
+	}
+
+	for _, tc := range tests {
+		if got := tc.row.Rating(); got != tc.want {
+			t.Errorf("%+v: rating %d, want %d", tc.row, got, tc.want)
+		}
+	}
+}